@@ -0,0 +1,223 @@
+package cart
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/cart"
+	"github.com/funmi4194/ecommerce/primer"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// caller returns the signed in user, if any, and the cart token sent by the client
+func caller(r *http.Request) (string, string) {
+	userId := ""
+	if u, ok := r.Context().Value(types.AuthCtxKey{}).(*user.User); ok && u != nil {
+		userId = u.ID
+	}
+	return userId, r.Header.Get(primer.CartHeader)
+}
+
+// respond sends the cart back and hands the client its cart token
+func respond(w http.ResponseWriter, status int, message, userId string, view *cart.View) {
+
+	if view.Token != "" {
+		w.Header().Set(primer.CartHeader, view.Token)
+	}
+
+	data := types.M{
+		"cart": view,
+	}
+	if userId != "" {
+		data["token"] = helper.RefreshToken(userId)
+	}
+
+	barf.Response(w).Status(status).JSON(barf.Res{
+		Status:  true,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// Cart is the controller function to retrieve the cart of a user or guest
+func Cart(w http.ResponseWriter, r *http.Request) {
+
+	userId, token := caller(r)
+
+	view, err := cart.Cart(userId, token)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.Cart] [cart.Cart(userId, token)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	respond(w, http.StatusOK, "Cart retrieved sucessfully", userId, view)
+}
+
+// AddItem is the controller function to put a product in the cart of a user or guest
+func AddItem(w http.ResponseWriter, r *http.Request) {
+
+	userId, token := caller(r)
+
+	var data types.CartItem
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[cart.AddItem] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	view, err := cart.AddItem(userId, token, data)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.AddItem] [cart.AddItem(userId, token, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	respond(w, http.StatusOK, "Item added to cart sucessfully", userId, view)
+}
+
+// UpdateItem is the controller function to change the quantity of a product in the cart of a user or guest
+func UpdateItem(w http.ResponseWriter, r *http.Request) {
+
+	userId, token := caller(r)
+
+	var data types.CartItem
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[cart.UpdateItem] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	view, err := cart.UpdateItem(userId, token, data)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.UpdateItem] [cart.UpdateItem(userId, token, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	respond(w, http.StatusOK, "Cart updated sucessfully", userId, view)
+}
+
+// RemoveItem is the controller function to take a product out of the cart of a user or guest
+func RemoveItem(w http.ResponseWriter, r *http.Request) {
+
+	userId, token := caller(r)
+
+	var data types.RemoveCartItem
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[cart.RemoveItem] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	view, err := cart.RemoveItem(userId, token, data)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.RemoveItem] [cart.RemoveItem(userId, token, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	respond(w, http.StatusOK, "Item removed from cart sucessfully", userId, view)
+}
+
+// MergeCart is the controller function to move a guest cart into the signed in user's cart
+func MergeCart(w http.ResponseWriter, r *http.Request) {
+
+	userId, token := caller(r)
+
+	if err := cart.MergeCart(userId, token); err != nil {
+		barf.Logger().Errorf(`[cart.MergeCart] [cart.MergeCart(userId, token)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	view, err := cart.Cart(userId, "")
+	if err != nil {
+		barf.Logger().Errorf(`[cart.MergeCart] [cart.Cart(userId, "")] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	respond(w, http.StatusOK, "Cart merged sucessfully", userId, view)
+}
+
+// Checkout is the controller function to place an order for the items in the signed in user's cart
+func Checkout(w http.ResponseWriter, r *http.Request) {
+
+	userId, token := caller(r)
+
+	var data types.CartCheckout
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[cart.Checkout] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	order, err := cart.Checkout(userId, token, data)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.Checkout] [cart.Checkout(userId, token, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Order initiated sucessfully",
+		Data: types.M{
+			"order": order,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
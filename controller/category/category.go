@@ -0,0 +1,154 @@
+package category
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/category"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// CreateCategory is the controller function to add a category
+func CreateCategory(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.CreateCategory
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[category.CreateCategory] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	category, err := category.CreateCategory(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[category.CreateCategory] [category.CreateCategory(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Category created sucessfully",
+		Data: types.M{
+			"category": category,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
+
+// UpdateCategory is the controller function to update a category
+func UpdateCategory(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.UpdateCategory
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[category.UpdateCategory] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	category, err := category.UpdateCategory(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[category.UpdateCategory] [category.UpdateCategory(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Category updated sucessfully",
+		Data: types.M{
+			"category": category,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
+
+// DeleteCategory is the controller function to delete a category
+func DeleteCategory(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.DeleteCategory
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := category.DeleteCategory(userId, data); err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [category.DeleteCategory(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Category deleted sucessfully",
+		Data: types.M{
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Categories is the controller function to list all categories
+func Categories(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	categories, err := category.Categories()
+	if err != nil {
+		barf.Logger().Errorf(`[category.Categories] [category.Categories()] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Categories retrieved sucessfully",
+		Data: types.M{
+			"categories": categories,
+			"token":      helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,129 @@
+package coupon
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/coupon"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// CreateCoupon is the controller function to create a coupon
+func CreateCoupon(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.CreateCoupon
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[coupon.CreateCoupon] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	coupon, err := coupon.CreateCoupon(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[coupon.CreateCoupon] [coupon.CreateCoupon(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Coupon created sucessfully",
+		Data: types.M{
+			"coupon": coupon,
+			"token":  helper.RefreshToken(userId),
+		},
+	})
+}
+
+// UpdateCoupon is the controller function to update a coupon
+func UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.UpdateCoupon
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[coupon.UpdateCoupon] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	coupon, err := coupon.UpdateCoupon(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[coupon.UpdateCoupon] [coupon.UpdateCoupon(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Coupon updated sucessfully",
+		Data: types.M{
+			"coupon": coupon,
+			"token":  helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Coupons is the controller function to list coupons
+func Coupons(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.CouponFilter
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[coupon.Coupons] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	coupons, pagination, err := coupon.Coupons(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[coupon.Coupons] [coupon.Coupons(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Coupons retrieved sucessfully",
+		Data: types.M{
+			"coupons":    coupons,
+			"pagination": pagination,
+			"token":      helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,129 @@
+package inventory
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/inventory"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// Adjust is the controller function for an admin to add units to or take units from stock
+func Adjust(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.AdjustStock
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[inventory.Adjust] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	movement, err := inventory.Adjust(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[inventory.Adjust] [inventory.Adjust(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Stock adjusted sucessfully",
+		Data: types.M{
+			"movement": movement,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Movements is the controller function for an admin to list the stock history of a product
+func Movements(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.MovementFilter
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[inventory.Movements] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	movements, pagination, err := inventory.Movements(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[inventory.Movements] [inventory.Movements(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Stock movement(s) retrieved sucessfully",
+		Data: types.M{
+			"movements":  movements,
+			"pagination": pagination,
+			"token":      helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Reconcile is the controller function for an admin to check the stock of a product against its recorded movements
+func Reconcile(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ReconcileStock
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[inventory.Reconcile] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	reconciliations, err := inventory.Reconcile(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[inventory.Reconcile] [inventory.Reconcile(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Stock reconciled sucessfully",
+		Data: types.M{
+			"reconciliations": reconciliations,
+			"token":           helper.RefreshToken(userId),
+		},
+	})
+}
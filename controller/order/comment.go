@@ -0,0 +1,89 @@
+package order
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// AddComment is the controller function to add a comment to the thread of an order
+func AddComment(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.AddComment
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.AddComment] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	comment, err := order.AddComment(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.AddComment] [order.AddComment(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Comment added sucessfully",
+		Data: types.M{
+			"comment": comment,
+			"token":   helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Comments is the controller function to read the comment thread of an order
+func Comments(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ListComments
+	if err := barf.Request(r).Query().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.Comments] [barf.Request(r).Query().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	comments, err := order.Comments(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Comments] [order.Comments(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Comments retrieved sucessfully",
+		Data: types.M{
+			"comments": comments,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
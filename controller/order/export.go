@@ -0,0 +1,82 @@
+package order
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/opensaucerer/barf/server"
+)
+
+// ExportOrders is the controller function for an admin to download every order matching a filter as CSV or NDJSON
+func ExportOrders(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ExportOrders
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.ExportOrders] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	// the export is written straight to the connection as barf would otherwise hold all of it in memory
+	stream, ok := r.Context().Value(types.StreamCtxKey{}).(http.ResponseWriter)
+	if !ok {
+		barf.Logger().Error(`[order.ExportOrders] [r.Context().Value(types.StreamCtxKey{})] the stream middleware is not installed`)
+		barf.Response(w).Status(http.StatusInternalServerError).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+	controller := http.NewResponseController(stream)
+
+	opened, err := order.ExportOrders(r.Context(), userId, data, order.ExportSink{
+		Open: func(contentType, filename string) io.Writer {
+			// large exports take longer than the server's write timeout
+			if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+				barf.Logger().Errorf(`[order.ExportOrders] [controller.SetWriteDeadline(time.Time{})] %s`, err.Error())
+			}
+
+			// barf must not write anything else once the export has started
+			if rw, ok := w.(*server.ResponseWriter); ok {
+				rw.Written = true
+			}
+
+			stream.Header().Set("Content-Type", contentType)
+			stream.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+			stream.Header().Set("Cache-Control", "no-store")
+			stream.WriteHeader(http.StatusOK)
+			return stream
+		},
+		Flush: func() {
+			controller.Flush()
+		},
+	})
+	if err != nil {
+		if opened {
+			// the client sees a truncated file as the status has already been sent
+			barf.Logger().Errorf(`[order.ExportOrders] [order.ExportOrders(r.Context(), userId, data, order.ExportSink{] export aborted: %s`, err.Error())
+			return
+		}
+		barf.Logger().Errorf(`[order.ExportOrders] [order.ExportOrders(r.Context(), userId, data, order.ExportSink{] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package order
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// UpdateOrderItem is the controller function for an admin to move a line of an order to another status
+func UpdateOrderItem(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.UpdateOrderItem
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	item, err := order.UpdateOrderItem(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [order.UpdateOrderItem(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Order item updated sucessfully",
+		Data: types.M{
+			"item":  item,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// OrderItems is the controller function to list the lines of orders
+func OrderItems(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.OrderItemFilter
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.OrderItems] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	items, pagination, err := order.OrderItems(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OrderItems] [order.OrderItems(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Order item(s) retrieved sucessfully",
+		Data: types.M{
+			"items":      items,
+			"pagination": pagination,
+			"token":      helper.RefreshToken(userId),
+		},
+	})
+}
@@ -69,7 +69,7 @@ func CancelOrder(w http.ResponseWriter, r *http.Request) {
 	order, err := order.CancelOrder(userId, data)
 	if err != nil {
 		barf.Logger().Errorf(`[order.CancelOrder] [order.CancelOrder(userId, data)] %s`, err.Error())
-		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
 			Status:  false,
 			Message: err.Error(),
 			Data:    nil,
@@ -108,7 +108,7 @@ func UpdateOrder(w http.ResponseWriter, r *http.Request) {
 	order, err := order.UpdateOrder(userId, data)
 	if err != nil {
 		barf.Logger().Errorf(`[order.UpdateOrder] [order.UpdateOrder(userId, data)] %s`, err.Error())
-		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
 			Status:  false,
 			Message: err.Error(),
 			Data:    nil,
@@ -166,3 +166,123 @@ func Orders(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// Pay is the controller function to start the payment of an order
+func Pay(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.InitializePayment
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.Pay] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	authorization, err := order.InitializePayment(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Pay] [order.InitializePayment(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Payment initialized sucessfully",
+		Data: types.M{
+			"payment": authorization,
+			"token":   helper.RefreshToken(userId),
+		},
+	})
+}
+
+// PriceOrder is the controller function to price an order without creating it
+func PriceOrder(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.InitiateOrder
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.PriceOrder] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	quote, err := order.PriceOrder(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.PriceOrder] [order.PriceOrder(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Order priced sucessfully",
+		Data: types.M{
+			"quote": quote,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Reorder is the controller function to place one of the user's previous orders again
+func Reorder(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.Reorder
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.Reorder] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	reordered, err := order.Reorder(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Reorder] [order.Reorder(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Order placed again sucessfully",
+		Data: types.M{
+			"order":    reordered.Order,
+			"dropped":  reordered.Dropped,
+			"reduced":  reordered.Reduced,
+			"repriced": reordered.Repriced,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,68 @@
+package order
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/opensaucerer/barf/server"
+)
+
+// Receipt is the controller function to download the receipt of an order as a PDF or view it as a web page
+func Receipt(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.Receipt
+	if err := barf.Request(r).Query().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.Receipt] [barf.Request(r).Query().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	document, format, err := order.Receipt(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Receipt] [order.Receipt(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	var content []byte
+	contentType, disposition := "text/html; charset=utf-8", "inline"
+	if format == enum.PDF {
+		content, err = document.PDF()
+		contentType, disposition = "application/pdf", "attachment"
+	} else {
+		content, err = document.HTML()
+	}
+	if err != nil {
+		barf.Logger().Errorf(`[order.Receipt] [document.%s()] %s`, format, err.Error())
+		barf.Response(w).Status(http.StatusInternalServerError).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not prepare the receipt at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	// send the rendered receipt (barf only writes JSON so the response is written directly)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, document.Filename(format)))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+	server.Write(w)
+}
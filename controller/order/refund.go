@@ -0,0 +1,51 @@
+package order
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// RefundOrder is the controller function to refund all or part of a paid order
+func RefundOrder(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.RefundOrder
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.RefundOrder] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	order, refund, err := order.RefundOrder(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.RefundOrder] [order.RefundOrder(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Order refunded sucessfully",
+		Data: types.M{
+			"order":  order,
+			"refund": refund,
+			"token":  helper.RefreshToken(userId),
+		},
+	})
+}
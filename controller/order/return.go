@@ -0,0 +1,168 @@
+package order
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// OpenReturn is the controller function for a user to ask to send back items of a completed order
+func OpenReturn(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.OpenReturn
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	rma, err := order.OpenReturn(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [order.OpenReturn(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Return requested sucessfully",
+		Data: types.M{
+			"return": rma,
+			"token":  helper.RefreshToken(userId),
+		},
+	})
+}
+
+// ReviewReturn is the controller function for an admin to approve or reject a requested return
+func ReviewReturn(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ReviewReturn
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	rma, err := order.ReviewReturn(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [order.ReviewReturn(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Return reviewed sucessfully",
+		Data: types.M{
+			"return": rma,
+			"token":  helper.RefreshToken(userId),
+		},
+	})
+}
+
+// ReceiveReturn is the controller function for an admin to record the items of an approved return arriving back
+func ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ReceiveReturn
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	rma, refund, err := order.ReceiveReturn(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [order.ReceiveReturn(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Return received sucessfully",
+		Data: types.M{
+			"return": rma,
+			"refund": refund,
+			"token":  helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Returns is the controller function to list the returns of orders
+func Returns(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ListReturns
+	if err := barf.Request(r).Query().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.Returns] [barf.Request(r).Query().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	returns, err := order.Returns(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Returns] [order.Returns(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Returns retrieved sucessfully",
+		Data: types.M{
+			"returns": returns,
+			"token":   helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,129 @@
+package order
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// CreateShipment is the controller function to record items of an order leaving the warehouse
+func CreateShipment(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.CreateShipment
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	shipment, err := order.CreateShipment(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [order.CreateShipment(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Shipment created sucessfully",
+		Data: types.M{
+			"shipment": shipment,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
+
+// DeliverShipment is the controller function to record a shipment reaching the customer
+func DeliverShipment(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.DeliverShipment
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	shipment, err := order.DeliverShipment(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [order.DeliverShipment(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(statusCode(err)).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Shipment delivered sucessfully",
+		Data: types.M{
+			"shipment": shipment,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Shipments is the controller function to view the shipment progress of an order
+func Shipments(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.ListShipments
+	if err := barf.Request(r).Query().Format(&data); err != nil {
+		barf.Logger().Errorf(`[order.Shipments] [barf.Request(r).Query().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	shipments, progress, err := order.Shipments(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Shipments] [order.Shipments(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Shipments retrieved sucessfully",
+		Data: types.M{
+			"shipments": shipments,
+			"progress":  progress,
+			"token":     helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,16 @@
+package order
+
+import (
+	"errors"
+	"net/http"
+
+	orderLogic "github.com/funmi4194/ecommerce/logic/order"
+)
+
+// statusCode maps an error returned by the order logic to the http status code it should be reported with
+func statusCode(err error) int {
+	if errors.Is(err, orderLogic.ErrIllegalTransition) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
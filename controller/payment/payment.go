@@ -0,0 +1,37 @@
+package payment
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/payment"
+	"github.com/opensaucerer/barf"
+)
+
+// Webhook is the controller function to receive payment notifications from the gateway
+func Webhook(w http.ResponseWriter, r *http.Request) {
+
+	body := barf.Request(r).Body()
+
+	err := order.PaymentWebhook(body, r.Header.Get(payment.PaymentGateway.SignatureHeader()))
+	if err != nil {
+		barf.Logger().Errorf(`[payment.Webhook] [order.PaymentWebhook(body, signature)] %s`, err.Error())
+		status := http.StatusBadRequest
+		if err == payment.ErrInvalidSignature {
+			status = http.StatusUnauthorized
+		}
+		barf.Response(w).Status(status).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Notification received",
+		Data:    nil,
+	})
+}
@@ -0,0 +1,117 @@
+package pricing
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/pricing"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// CreateRule is the controller function to create a tax or fee rule
+func CreateRule(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.CreatePricingRule
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[pricing.CreateRule] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	rule, err := pricing.CreateRule(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[pricing.CreateRule] [pricing.CreateRule(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Rule created sucessfully",
+		Data: types.M{
+			"rule":  rule,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// UpdateRule is the controller function to update a tax or fee rule
+func UpdateRule(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.UpdatePricingRule
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[pricing.UpdateRule] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	rule, err := pricing.UpdateRule(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[pricing.UpdateRule] [pricing.UpdateRule(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Rule updated sucessfully",
+		Data: types.M{
+			"rule":  rule,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Rules is the controller function to list tax and fee rules
+func Rules(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	rules, err := pricing.Rules(userId)
+	if err != nil {
+		barf.Logger().Errorf(`[pricing.Rules] [pricing.Rules(userId)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Rules retrieved sucessfully",
+		Data: types.M{
+			"rules": rules,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package pricing
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/pricing"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// SetRate is the controller function to set the exchange rate between two currencies
+func SetRate(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.SetExchangeRate
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[pricing.SetRate] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	rate, err := pricing.SetRate(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[pricing.SetRate] [pricing.SetRate(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Rate set sucessfully",
+		Data: types.M{
+			"rate":  rate,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// DeleteRate is the controller function to remove an exchange rate
+func DeleteRate(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.DeleteExchangeRate
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[pricing.DeleteRate] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := pricing.DeleteRate(userId, data); err != nil {
+		barf.Logger().Errorf(`[pricing.DeleteRate] [pricing.DeleteRate(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Rate deleted sucessfully",
+		Data: types.M{
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
+
+// Rates is the controller function to list exchange rates
+func Rates(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	rates, err := pricing.Rates(userId)
+	if err != nil {
+		barf.Logger().Errorf(`[pricing.Rates] [pricing.Rates(userId)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Rates retrieved sucessfully",
+		Data: types.M{
+			"rates": rates,
+			"token": helper.RefreshToken(userId),
+		},
+	})
+}
@@ -0,0 +1,115 @@
+package product
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/category"
+	"github.com/funmi4194/ecommerce/logic/product"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// signedIn adds a refreshed token to the response data when the request was made by a signed in user
+func signedIn(r *http.Request, data types.M) types.M {
+	if u, ok := r.Context().Value(types.AuthCtxKey{}).(*user.User); ok && u != nil {
+		data["token"] = helper.RefreshToken(u.ID)
+	}
+	return data
+}
+
+// Catalogue is the controller function to browse the published products without signing in
+func Catalogue(w http.ResponseWriter, r *http.Request) {
+
+	var data types.CatalogueFilter
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[product.Catalogue] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	products, pagination, err := product.Catalogue(data)
+	if err != nil {
+		barf.Logger().Errorf(`[product.Catalogue] [product.Catalogue(data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Product(s) retreived sucessfully",
+		Data: signedIn(r, types.M{
+			"products":   products,
+			"pagination": pagination,
+		}),
+	})
+}
+
+// CatalogueProduct is the controller function to view a published product by its Id without signing in
+func CatalogueProduct(w http.ResponseWriter, r *http.Request) {
+
+	var data types.ProductFilter
+	if err := barf.Request(r).Query().Format(&data); err != nil {
+		barf.Logger().Errorf(`[product.CatalogueProduct] [barf.Request(r).Query().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	listing, err := product.CatalogueProduct(data)
+	if err != nil {
+		barf.Logger().Errorf(`[product.CatalogueProduct] [product.CatalogueProduct(data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Product retreived sucessfully",
+		Data: signedIn(r, types.M{
+			"product": listing,
+		}),
+	})
+}
+
+// CatalogueCategories is the controller function to browse the product taxonomy without signing in
+func CatalogueCategories(w http.ResponseWriter, r *http.Request) {
+
+	categories, err := category.Catalogue()
+	if err != nil {
+		barf.Logger().Errorf(`[product.CatalogueCategories] [category.Catalogue()] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Categories retrieved sucessfully",
+		Data: signedIn(r, types.M{
+			"categories": categories,
+		}),
+	})
+}
@@ -203,3 +203,42 @@ func DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// RestoreProducts is the controller function to restore a deleted product or products
+func RestoreProducts(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	userId := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.Restore
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[product.RestoreProducts] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	products, err := product.RestoreProducts(userId, data)
+	if err != nil {
+		barf.Logger().Errorf(`[product.RestoreProducts] [product.RestoreProducts(userId, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Product(s) restored sucessfully",
+		Data: types.M{
+			"products": products,
+			"token":    helper.RefreshToken(userId),
+		},
+	})
+}
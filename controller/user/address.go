@@ -0,0 +1,154 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/helper"
+	userLogic "github.com/funmi4194/ecommerce/logic/user"
+	"github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// CreateAddress is the controller function to add an address to the user's address book
+func CreateAddress(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	id := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.Address
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[user.CreateAddress] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	address, err := userLogic.CreateAddress(id, data)
+	if err != nil {
+		barf.Logger().Errorf(`[user.CreateAddress] [userLogic.CreateAddress(id, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusCreated).JSON(barf.Res{
+		Status:  true,
+		Message: "Address added successfully",
+		Data: types.M{
+			"address": address,
+			"token":   helper.RefreshToken(id),
+		},
+	})
+}
+
+// Addresses is the controller function to list the user's address book
+func Addresses(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	id := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	addresses, err := userLogic.Addresses(id)
+	if err != nil {
+		barf.Logger().Errorf(`[user.Addresses] [userLogic.Addresses(id)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Addresses retrieved successfully",
+		Data: types.M{
+			"addresses": addresses,
+			"token":     helper.RefreshToken(id),
+		},
+	})
+}
+
+// UpdateAddress is the controller function to update an address in the user's address book
+func UpdateAddress(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	id := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.UpdateAddress
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[user.UpdateAddress] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	address, err := userLogic.UpdateAddress(id, data)
+	if err != nil {
+		barf.Logger().Errorf(`[user.UpdateAddress] [userLogic.UpdateAddress(id, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Address updated successfully",
+		Data: types.M{
+			"address": address,
+			"token":   helper.RefreshToken(id),
+		},
+	})
+}
+
+// DeleteAddress is the controller function to remove an address from the user's address book
+func DeleteAddress(w http.ResponseWriter, r *http.Request) {
+
+	// get user from context
+	id := r.Context().Value(types.AuthCtxKey{}).(*user.User).ID
+
+	var data types.DeleteAddress
+	if err := barf.Request(r).Body().Format(&data); err != nil {
+		barf.Logger().Errorf(`[user.DeleteAddress] [barf.Request(r).Body().Format(&data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: "We could not process your request at this time. Please try again later.",
+			Data:    nil,
+		})
+		return
+	}
+
+	if err := userLogic.DeleteAddress(id, data); err != nil {
+		barf.Logger().Errorf(`[user.DeleteAddress] [userLogic.DeleteAddress(id, data)] %s`, err.Error())
+		barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+			Status:  false,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	// send response
+	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
+		Status:  true,
+		Message: "Address deleted successfully",
+		Data: types.M{
+			"token": helper.RefreshToken(id),
+		},
+	})
+}
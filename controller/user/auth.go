@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	"github.com/funmi4194/ecommerce/helper"
+	cartLogic "github.com/funmi4194/ecommerce/logic/cart"
 	userLogic "github.com/funmi4194/ecommerce/logic/user"
+	"github.com/funmi4194/ecommerce/primer"
 	"github.com/funmi4194/ecommerce/types"
 	"github.com/opensaucerer/barf"
 )
@@ -67,6 +69,11 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// bring along the cart the user filled before logging in (the login stands even if this fails)
+	if err := cartLogic.MergeCart(user.ID, r.Header.Get(primer.CartHeader)); err != nil {
+		barf.Logger().Errorf(`[user.Login] [cartLogic.MergeCart(user.ID, r.Header.Get(primer.CartHeader))] %s`, err.Error())
+	}
+
 	// send response
 	barf.Response(w).Status(http.StatusOK).JSON(barf.Res{
 		Status:  true,
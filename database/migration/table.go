@@ -2,11 +2,26 @@ package migration
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/funmi4194/ecommerce/database"
+	addressRepository "github.com/funmi4194/ecommerce/repository/address"
+	cartRepository "github.com/funmi4194/ecommerce/repository/cart"
+	categoryRepository "github.com/funmi4194/ecommerce/repository/category"
+	commentRepository "github.com/funmi4194/ecommerce/repository/comment"
+	couponRepository "github.com/funmi4194/ecommerce/repository/coupon"
+	idempotencyRepository "github.com/funmi4194/ecommerce/repository/idempotency"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
 	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	orderItemRepository "github.com/funmi4194/ecommerce/repository/orderitem"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
 	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	refundRepository "github.com/funmi4194/ecommerce/repository/refund"
+	returnRepository "github.com/funmi4194/ecommerce/repository/returns"
+	shipmentRepository "github.com/funmi4194/ecommerce/repository/shipment"
 	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
 	"github.com/opensaucerer/barf"
 )
 
@@ -14,6 +29,22 @@ var Table = []interface{}{
 	&userRepository.User{},
 	&orderRepository.Order{},
 	&productRepository.Product{},
+	&idempotencyRepository.Key{},
+	&refundRepository.Refund{},
+	&addressRepository.Address{},
+	&shipmentRepository.Shipment{},
+	&couponRepository.Coupon{},
+	&couponRepository.Redemption{},
+	&pricingRepository.Rule{},
+	&pricingRepository.Rate{},
+	&cartRepository.Cart{},
+	&returnRepository.Return{},
+	&commentRepository.Comment{},
+	&orderItemRepository.Item{},
+	&categoryRepository.Category{},
+	&categoryRepository.Link{},
+	&variantRepository.Variant{},
+	&inventoryRepository.Movement{},
 }
 
 // CreateTables creates tables that do not already exist. Although we have connections to other DBs configure.Save should only handle migration for configure.Save DB.
@@ -30,7 +61,161 @@ func CreateTables() error {
 	return nil
 }
 
+// Alterations are applied in order to bring tables created by earlier versions up to date, the fields of the columns they add are
+// declared last in their structs so that tables created from the structs have the same column order as migrated ones
+var Alterations = []string{
+	// orders placed before stock was reserved never held any, so every order there is when the column is added is marked as released,
+	// and orders that were cancelled, rejected or failed have already given their units back
+	`DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'stock_released') THEN
+		ALTER TABLE orders ADD COLUMN stock_released BOOLEAN NOT NULL DEFAULT FALSE;
+		UPDATE orders SET stock_released = TRUE;
+	END IF;
+END $$`,
+	`UPDATE orders SET stock_released = TRUE WHERE NOT stock_released AND (status IN ('CANCELLED', 'REJECTED') OR failed)`,
+	`ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address JSONB`,
+	`ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_code VARCHAR NOT NULL DEFAULT ''`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS carts_user_id_key ON carts (user_id) WHERE user_id <> ''`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS currency VARCHAR NOT NULL DEFAULT 'NGN'`,
+	`ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency VARCHAR NOT NULL DEFAULT 'NGN'`,
+	`ALTER TABLE coupons ADD COLUMN IF NOT EXISTS currency VARCHAR NOT NULL DEFAULT 'NGN'`,
+	`ALTER TABLE pricing_rules ADD COLUMN IF NOT EXISTS currency VARCHAR NOT NULL DEFAULT 'NGN'`,
+	`ALTER TABLE pricing_rules ADD COLUMN IF NOT EXISTS category_id VARCHAR NOT NULL DEFAULT ''`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+
+	// money moved from major units in doubles to the smallest unit of the currency in integers
+	toMinorUnits("orders", "amount",
+		`UPDATE orders SET invoice = `+minorItems("invoice")+` WHERE jsonb_typeof(invoice) = 'array'`,
+		`UPDATE shipments SET items = `+minorItems("items")+` WHERE jsonb_typeof(items) = 'array'`,
+		`UPDATE carts SET items = (SELECT COALESCE(jsonb_agg(jsonb_set(i, '{price}', to_jsonb(ROUND((i->>'price')::numeric * 100)::bigint)) ORDER BY n), '[]'::jsonb) FROM jsonb_array_elements(items) WITH ORDINALITY AS t(i, n)) WHERE jsonb_typeof(items) = 'array'`,
+		`UPDATE pricing_rules SET rate = ROUND(rate * 100) WHERE basis = 'FIXED'`,
+		`ALTER TABLE orders ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)`,
+	),
+	toMinorUnits("products", "price",
+		`ALTER TABLE products ALTER COLUMN price TYPE BIGINT USING ROUND(price * 100)`,
+	),
+	toMinorUnits("refunds", "amount",
+		`UPDATE refunds SET items = `+minorItems("items")+` WHERE jsonb_typeof(items) = 'array'`,
+		`ALTER TABLE refunds ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)`,
+	),
+	toMinorUnits("coupons", "min_spend",
+		`UPDATE coupons SET value = ROUND(value * 100) WHERE type = 'FIXED_AMOUNT'`,
+		`ALTER TABLE coupons ALTER COLUMN min_spend TYPE BIGINT USING ROUND(min_spend * 100)`,
+	),
+	toMinorUnits("coupon_redemptions", "amount",
+		`ALTER TABLE coupon_redemptions ALTER COLUMN amount TYPE BIGINT USING ROUND(amount * 100)`,
+	),
+
+	`CREATE INDEX IF NOT EXISTS order_comments_order_id_idx ON order_comments (order_id, created_at)`,
+
+	// the single double value of a coupon was split by type so that amounts and unit counts are kept as integers
+	`DO $$ BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'coupons' AND column_name = 'value') THEN
+		ALTER TABLE coupons ADD COLUMN IF NOT EXISTS percent DOUBLE PRECISION NOT NULL DEFAULT 0;
+		ALTER TABLE coupons ADD COLUMN IF NOT EXISTS amount BIGINT NOT NULL DEFAULT 0;
+		ALTER TABLE coupons ADD COLUMN IF NOT EXISTS units INTEGER NOT NULL DEFAULT 0;
+		UPDATE coupons SET percent = value WHERE type = 'PERCENTAGE';
+		UPDATE coupons SET amount = ROUND(value) WHERE type = 'FIXED_AMOUNT';
+		UPDATE coupons SET units = ROUND(value) WHERE type = 'FREE_ITEM';
+		ALTER TABLE coupons DROP COLUMN value;
+	END IF;
+END $$`,
+
+	// an order can hold several variants of a product, so its lines are told apart by product and variant
+	`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS variant_id VARCHAR NOT NULL DEFAULT ''`,
+	`DROP INDEX IF EXISTS order_items_order_id_product_id_key`,
+
+	// the product lines of orders placed before order items were kept (ids are derived from the order and product so reruns add nothing)
+	`CREATE UNIQUE INDEX IF NOT EXISTS order_items_order_id_product_id_variant_id_key ON order_items (order_id, product_id, variant_id)`,
+	`CREATE INDEX IF NOT EXISTS order_items_product_id_idx ON order_items (product_id)`,
+	`DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM order_items) THEN
+		INSERT INTO order_items (id, order_id, product_id, name, price, quantity, currency, status, created_at, updated_at)
+		SELECT md5(orders.id || ':' || (line->>'key'))::uuid::text, orders.id, line->>'key', line->>'name', (line->>'amount')::bigint, (line->>'quantity')::integer, orders.currency,
+			CASE WHEN orders.status IN ('CANCELLED', 'REJECTED') THEN 'CANCELLED' ELSE 'OPEN' END, orders.created_at, orders.created_at
+		FROM orders CROSS JOIN LATERAL jsonb_array_elements(orders.invoice) AS line
+		WHERE jsonb_typeof(orders.invoice) = 'array'
+			AND CASE WHEN COALESCE(line->>'metadata', '') = '' THEN 'PRODUCT' ELSE COALESCE((line->>'metadata')::jsonb->>'type', 'PRODUCT') END = 'PRODUCT'
+		ON CONFLICT (order_id, product_id, variant_id) DO NOTHING;
+	END IF;
+END $$`,
+
+	`CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id)`,
+	`CREATE INDEX IF NOT EXISTS product_categories_category_id_idx ON product_categories (category_id)`,
+	`CREATE INDEX IF NOT EXISTS product_variants_product_id_idx ON product_variants (product_id)`,
+
+	// stock held before movements were recorded is opened with an ADJUSTMENT so that the movements of every product and variant add up to
+	// its stock (only products and variants without movements get one, so reruns add nothing)
+	`CREATE INDEX IF NOT EXISTS inventory_movements_product_id_idx ON inventory_movements (product_id, variant_id, created_at)`,
+	`INSERT INTO inventory_movements (id, product_id, variant_id, kind, quantity, balance, order_id, actor_id, reason, created_at)
+	SELECT md5('opening:' || products.id)::uuid::text, products.id, '', 'ADJUSTMENT', products.stock, products.stock, '', '', 'Opening balance', now()
+	FROM products
+	WHERE NOT EXISTS (SELECT 1 FROM inventory_movements WHERE inventory_movements.product_id = products.id AND inventory_movements.variant_id = '')
+	ON CONFLICT (id) DO NOTHING`,
+	`INSERT INTO inventory_movements (id, product_id, variant_id, kind, quantity, balance, order_id, actor_id, reason, created_at)
+	SELECT md5('opening:' || product_variants.id)::uuid::text, product_variants.product_id, product_variants.id, 'ADJUSTMENT', product_variants.stock, product_variants.stock, '', '', 'Opening balance', now()
+	FROM product_variants
+	WHERE NOT EXISTS (SELECT 1 FROM inventory_movements WHERE inventory_movements.variant_id = product_variants.id)
+	ON CONFLICT (id) DO NOTHING`,
+
+	// a fixed rule charges a whole amount, so it was moved out of the double rate that percentage rules keep
+	`DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'pricing_rules' AND column_name = 'amount') THEN
+		ALTER TABLE pricing_rules ADD COLUMN amount BIGINT NOT NULL DEFAULT 0;
+		UPDATE pricing_rules SET amount = ROUND(rate), rate = 0 WHERE basis = 'FIXED';
+	END IF;
+END $$`,
+
+	// orders remember being rejected the way they remember being cancelled, the ones rejected before are found through their status and history
+	`DO $$ BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'rejected') THEN
+		ALTER TABLE orders ADD COLUMN rejected BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE orders ADD COLUMN rejected_at TIMESTAMPTZ;
+		UPDATE orders SET rejected = TRUE, rejected_at = updated_at
+		WHERE status = 'REJECTED' OR EXISTS (SELECT 1 FROM jsonb_array_elements(orders.history) AS h WHERE h->>'act' LIKE 'Moved order from % to REJECTED%');
+	END IF;
+END $$`,
+
+	// refunds recorded before they kept the status of their order cannot be undone automatically when the provider declines them
+	`ALTER TABLE refunds ADD COLUMN IF NOT EXISTS order_status VARCHAR NOT NULL DEFAULT ''`,
+}
+
+/*
+toMinorUnits wraps the statements that move a table's money from major units to the smallest unit of the currency (every
+currency we sell in has two decimal places) so that they only run while the guard column is still a double
+*/
+func toMinorUnits(table, guard string, statements ...string) string {
+	return fmt.Sprintf(`DO $$ BEGIN
+	IF (SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = '%s' AND column_name = '%s') = 'double precision' THEN
+		%s;
+	END IF;
+END $$`, table, guard, strings.Join(statements, ";\n\t\t"))
+}
+
+/*
+minorItems returns the SQL expression converting the invoice items in the jsonb column to the smallest unit of the currency,
+including the amounts recorded in the metadata of discount, tax, fee and converted product lines
+*/
+func minorItems(column string) string {
+	return `(SELECT COALESCE(jsonb_agg(
+		CASE WHEN LEFT(i->>'metadata', 1) = '{' THEN jsonb_set(item, '{metadata}', to_jsonb((
+			SELECT (m
+				|| CASE WHEN m->'base' IS NOT NULL THEN jsonb_build_object('base', ROUND((m->>'base')::numeric * 100)::bigint) ELSE '{}'::jsonb END
+				|| CASE WHEN m->'price' IS NOT NULL THEN jsonb_build_object('price', ROUND((m->>'price')::numeric * 100)::bigint) ELSE '{}'::jsonb END
+				|| CASE WHEN m->>'basis' = 'FIXED' AND m->'rate' IS NOT NULL THEN jsonb_build_object('rate', ROUND((m->>'rate')::numeric * 100)) ELSE '{}'::jsonb END
+			)::text FROM (SELECT (i->>'metadata')::jsonb AS m) AS x
+		))) ELSE item END ORDER BY n), '[]'::jsonb)
+	FROM jsonb_array_elements(` + column + `) WITH ORDINALITY AS t(i, n),
+		LATERAL (SELECT jsonb_set(i, '{amount}', to_jsonb(ROUND((i->>'amount')::numeric * 100)::bigint)) AS item) AS a)`
+}
+
 // migrate effects any database schema migration
 func Migrate() error {
+	for _, q := range Alterations {
+		if _, err := database.PostgreSQLDB.ExecContext(context.TODO(), q); err != nil {
+			barf.Logger().Warnf("failed to apply migration %s", q)
+			return err
+		}
+	}
 	return nil
 }
@@ -29,6 +29,7 @@ func MapToQuery(m types.SQLMap, noargs ...bool) (string, []interface{}) {
 		// if value is of type enum.SQLAlmostRaw, we need to handle it differently
 		if v, ok := v.(enum.SQLAlmostRaw); ok {
 			query += fmt.Sprintf(" %s %s %s %s ", k, v.Operator, v.Value, m.JoinOperator)
+			args = append(args, v.Args...)
 			continue
 		}
 
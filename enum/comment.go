@@ -0,0 +1,21 @@
+package enum
+
+type CommentVisibility string
+
+func (c CommentVisibility) String() string {
+	return string(c)
+}
+
+// Comment Visibilities
+const (
+	// Internal denotes a comment only admins can see
+	Internal CommentVisibility = "INTERNAL"
+
+	// Customer denotes a comment the customer who placed the order can see
+	Customer CommentVisibility = "CUSTOMER"
+)
+
+// IsValid reports whether the visibility is one comments can have
+func (c CommentVisibility) IsValid() bool {
+	return c == Internal || c == Customer
+}
@@ -0,0 +1,24 @@
+package enum
+
+type CouponType string
+
+func (c CouponType) String() string {
+	return string(c)
+}
+
+// Coupon Types
+const (
+	// Percentage takes a percentage of the order subtotal off
+	Percentage CouponType = "PERCENTAGE"
+
+	// FixedAmount takes a fixed amount off the order subtotal
+	FixedAmount CouponType = "FIXED_AMOUNT"
+
+	// FreeItem makes units of a product on the order free
+	FreeItem CouponType = "FREE_ITEM"
+)
+
+// IsValid reports whether the coupon type is one of the known types
+func (c CouponType) IsValid() bool {
+	return c == Percentage || c == FixedAmount || c == FreeItem
+}
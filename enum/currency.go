@@ -0,0 +1,56 @@
+package enum
+
+import (
+	"fmt"
+	"math"
+)
+
+type Currency string
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+// Currencies (ISO 4217 codes)
+const (
+	// NGN is the Nigerian naira
+	NGN Currency = "NGN"
+
+	// USD is the United States dollar
+	USD Currency = "USD"
+)
+
+// minorUnits is the number of decimal places each currency is charged in
+var minorUnits = map[Currency]int{
+	NGN: 2,
+	USD: 2,
+}
+
+// IsValid reports whether the currency is one of the currencies we sell in
+func (c Currency) IsValid() bool {
+	_, ok := minorUnits[c]
+	return ok
+}
+
+// MinorUnits returns the number of decimal places the currency is charged in (2 for kobo and cents)
+func (c Currency) MinorUnits() int {
+	if units, ok := minorUnits[c]; ok {
+		return units
+	}
+	return 2
+}
+
+// Minor converts an amount in major units (naira, dollars) to the smallest unit of the currency, rounding half away from zero
+func (c Currency) Minor(amount float64) int64 {
+	return int64(math.Round(amount * math.Pow10(c.MinorUnits())))
+}
+
+// Major converts an amount in the smallest unit of the currency (kobo, cents) to major units
+func (c Currency) Major(amount int64) float64 {
+	return float64(amount) / math.Pow10(c.MinorUnits())
+}
+
+// Format renders an amount in the smallest unit of the currency for people to read e.g NGN 1500.00
+func (c Currency) Format(amount int64) string {
+	return fmt.Sprintf("%s %.*f", c, c.MinorUnits(), c.Major(amount))
+}
@@ -0,0 +1,47 @@
+package enum
+
+import "testing"
+
+func TestCurrencyFormat(t *testing.T) {
+
+	tests := []struct {
+		currency Currency
+		amount   int64
+		want     string
+	}{
+		{NGN, 150000, "NGN 1500.00"},
+		{NGN, 4999, "NGN 49.99"},
+		{USD, 5, "USD 0.05"},
+		{USD, 0, "USD 0.00"},
+		{USD, -1250, "USD -12.50"},
+		{Currency("XYZ"), 100, "XYZ 1.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.currency.Format(tt.amount); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCurrencyMinorMajor(t *testing.T) {
+
+	tests := []struct {
+		major float64
+		minor int64
+	}{
+		{15, 1500},
+		{0.1, 10},
+		{49.99, 4999},
+		{0.005, 1},
+		{-0.005, -1},
+	}
+
+	for _, tt := range tests {
+		if got := NGN.Minor(tt.major); got != tt.minor {
+			t.Fatalf("expected %v naira to be %d kobo, got %d", tt.major, tt.minor, got)
+		}
+	}
+}
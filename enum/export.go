@@ -0,0 +1,41 @@
+package enum
+
+type ExportFormat string
+
+func (e ExportFormat) String() string {
+	return string(e)
+}
+
+// Export Formats
+const (
+	// CSV denotes comma separated values with a header row
+	CSV ExportFormat = "CSV"
+
+	// NDJSON denotes one JSON document per line
+	NDJSON ExportFormat = "NDJSON"
+)
+
+// IsValid reports whether the format is one exports can be written in
+func (e ExportFormat) IsValid() bool {
+	return e == CSV || e == NDJSON
+}
+
+type ExportRow string
+
+func (e ExportRow) String() string {
+	return string(e)
+}
+
+// Export Rows
+const (
+	// OrderRow denotes an export with a row for every order
+	OrderRow ExportRow = "ORDER"
+
+	// LineRow denotes an export with a row for every line of every order's invoice
+	LineRow ExportRow = "LINE"
+)
+
+// IsValid reports whether the export can be broken into rows this way
+func (e ExportRow) IsValid() bool {
+	return e == OrderRow || e == LineRow
+}
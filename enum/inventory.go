@@ -0,0 +1,30 @@
+package enum
+
+type MovementKind string
+
+func (m MovementKind) String() string {
+	return string(m)
+}
+
+// Movement Kinds
+const (
+	// Restock denotes units added to stock e.g when a product or variant is created or a delivery is received
+	Restock MovementKind = "RESTOCK"
+
+	// Sale denotes units taken from stock by an order
+	Sale MovementKind = "SALE"
+
+	// Cancellation denotes units an order took from stock being put back because the order was cancelled, rejected or not paid for
+	Cancellation MovementKind = "CANCELLATION"
+
+	// Adjustment denotes a manual correction of stock e.g after a stock count
+	Adjustment MovementKind = "ADJUSTMENT"
+
+	// Return denotes units put back in stock after a customer sent them back
+	Return MovementKind = "RETURN"
+)
+
+// IsValid reports whether the kind is one stock movements can have
+func (m MovementKind) IsValid() bool {
+	return m == Restock || m == Sale || m == Cancellation || m == Adjustment || m == Return
+}
@@ -0,0 +1,22 @@
+package enum
+
+type InvoiceLine string
+
+func (i InvoiceLine) String() string {
+	return string(i)
+}
+
+// Invoice Line Types
+const (
+	// ProductLine denotes units of a product bought (lines without metadata are product lines)
+	ProductLine InvoiceLine = "PRODUCT"
+
+	// DiscountLine denotes a discount taken off the order by a coupon
+	DiscountLine InvoiceLine = "DISCOUNT"
+
+	// TaxLine denotes tax charged on the order by a pricing rule
+	TaxLine InvoiceLine = "TAX"
+
+	// FeeLine denotes a fee charged on the order by a pricing rule
+	FeeLine InvoiceLine = "FEE"
+)
@@ -0,0 +1,27 @@
+package enum
+
+type ItemStatus string
+
+func (i ItemStatus) String() string {
+	return string(i)
+}
+
+// Order Item Statuses
+const (
+	// ItemOpen denotes a line of an order waiting to be fulfilled
+	ItemOpen ItemStatus = "OPEN"
+
+	// ItemBackordered denotes a line of an order waiting on stock to arrive
+	ItemBackordered ItemStatus = "BACKORDERED"
+
+	// ItemFulfilled denotes a line of an order that has been sent out in full
+	ItemFulfilled ItemStatus = "FULFILLED"
+
+	// ItemCancelled denotes a line of an order that will not be fulfilled
+	ItemCancelled ItemStatus = "CANCELLED"
+)
+
+// IsValid reports whether the status is one an order item can be in
+func (i ItemStatus) IsValid() bool {
+	return i == ItemOpen || i == ItemBackordered || i == ItemFulfilled || i == ItemCancelled
+}
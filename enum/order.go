@@ -21,4 +21,31 @@ const (
 
 	// Approved denotes an order approved by an admin
 	Approved OrderStatus = "APPROVED"
+
+	// PartiallyRefunded denotes a paid order part of whose amount has been refunded by an admin
+	PartiallyRefunded OrderStatus = "PARTIALLY_REFUNDED"
+
+	// Refunded denotes a paid order whose whole amount has been refunded by an admin
+	Refunded OrderStatus = "REFUNDED"
 )
+
+// OrderTransitions lists the statuses an order may move to from each status
+var OrderTransitions = map[OrderStatus][]OrderStatus{
+	Pending:           {Approved, Rejected, Cancelled, PartiallyRefunded, Refunded},
+	Approved:          {Completed, Cancelled, PartiallyRefunded, Refunded},
+	Completed:         {PartiallyRefunded, Refunded},
+	Rejected:          {PartiallyRefunded, Refunded},
+	Cancelled:         {PartiallyRefunded, Refunded},
+	PartiallyRefunded: {Completed, PartiallyRefunded, Refunded},
+	Refunded:          {},
+}
+
+// CanTransitionTo reports whether an order may move from o to next
+func (o OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, s := range OrderTransitions[o] {
+		if s == next {
+			return true
+		}
+	}
+	return false
+}
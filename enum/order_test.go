@@ -0,0 +1,50 @@
+package enum
+
+import "testing"
+
+func TestCanTransitionTo(t *testing.T) {
+
+	tests := []struct {
+		from OrderStatus
+		next OrderStatus
+		want bool
+	}{
+		{Pending, Approved, true},
+		{Pending, Rejected, true},
+		{Pending, Cancelled, true},
+		{Pending, Completed, false},
+		{Approved, Completed, true},
+		{Approved, Cancelled, true},
+		{Approved, Rejected, false},
+		{Approved, Pending, false},
+		{Completed, Refunded, true},
+		{Completed, Cancelled, false},
+		{Completed, Approved, false},
+		{Rejected, Approved, false},
+		{Cancelled, Pending, false},
+		{PartiallyRefunded, PartiallyRefunded, true},
+		{PartiallyRefunded, Completed, true},
+		{PartiallyRefunded, Cancelled, false},
+		{Refunded, PartiallyRefunded, false},
+		{Refunded, Completed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.next), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.next); got != tt.want {
+				t.Fatalf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderTransitionsLeadToKnownStatuses(t *testing.T) {
+
+	for from, next := range OrderTransitions {
+		for _, status := range next {
+			if _, ok := OrderTransitions[status]; !ok {
+				t.Fatalf("%s leads to %s which has no transitions of its own", from, status)
+			}
+		}
+	}
+}
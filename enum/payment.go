@@ -0,0 +1,37 @@
+package enum
+
+type PaymentStatus string
+
+func (p PaymentStatus) String() string {
+	return string(p)
+}
+
+// Payment Statuses
+const (
+	// PaymentPending denotes a charge awaiting action from the customer
+	PaymentPending PaymentStatus = "PENDING"
+
+	// PaymentSuccess denotes a charge confirmed by the provider
+	PaymentSuccess PaymentStatus = "SUCCESS"
+
+	// PaymentFailed denotes a charge declined or abandoned at the provider
+	PaymentFailed PaymentStatus = "FAILED"
+
+	// PaymentReversed denotes a charge whose money has been returned to the customer
+	PaymentReversed PaymentStatus = "REVERSED"
+)
+
+type PaymentProvider string
+
+func (p PaymentProvider) String() string {
+	return string(p)
+}
+
+// Payment Providers
+const (
+	// Paystack denotes the paystack payment provider
+	Paystack PaymentProvider = "paystack"
+
+	// FakeProvider denotes the in-process gateway used by tests (it cannot be configured as the provider of a running server)
+	FakeProvider PaymentProvider = "fake"
+)
@@ -0,0 +1,41 @@
+package enum
+
+type PricingRule string
+
+func (p PricingRule) String() string {
+	return string(p)
+}
+
+// Pricing Rule Kinds
+const (
+	// Tax denotes a rule that charges tax on an order
+	Tax PricingRule = "TAX"
+
+	// Fee denotes a rule that charges a fee on an order
+	Fee PricingRule = "FEE"
+)
+
+// IsValid reports whether the rule kind is one of the known kinds
+func (p PricingRule) IsValid() bool {
+	return p == Tax || p == Fee
+}
+
+type RuleBasis string
+
+func (r RuleBasis) String() string {
+	return string(r)
+}
+
+// Pricing Rule Bases
+const (
+	// Rate charges a percentage of the order subtotal after discounts
+	Rate RuleBasis = "PERCENTAGE"
+
+	// Flat charges a fixed amount per order
+	Flat RuleBasis = "FIXED"
+)
+
+// IsValid reports whether the rule basis is one of the known bases
+func (r RuleBasis) IsValid() bool {
+	return r == Rate || r == Flat
+}
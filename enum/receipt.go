@@ -0,0 +1,21 @@
+package enum
+
+type ReceiptFormat string
+
+func (r ReceiptFormat) String() string {
+	return string(r)
+}
+
+// Receipt Formats
+const (
+	// PDF denotes a receipt rendered as a downloadable PDF document
+	PDF ReceiptFormat = "PDF"
+
+	// HTML denotes a receipt rendered as a web page
+	HTML ReceiptFormat = "HTML"
+)
+
+// IsValid reports whether the format is one receipts can be rendered in
+func (r ReceiptFormat) IsValid() bool {
+	return r == PDF || r == HTML
+}
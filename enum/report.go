@@ -0,0 +1,36 @@
+package enum
+
+type ReportPeriod string
+
+func (r ReportPeriod) String() string {
+	return string(r)
+}
+
+// Report Periods
+const (
+	// Day groups a report by calendar day
+	Day ReportPeriod = "DAY"
+
+	// Week groups a report by week (weeks start on Monday)
+	Week ReportPeriod = "WEEK"
+
+	// Month groups a report by calendar month
+	Month ReportPeriod = "MONTH"
+)
+
+// IsValid reports whether a report can be grouped by the period
+func (r ReportPeriod) IsValid() bool {
+	return r == Day || r == Week || r == Month
+}
+
+// Trunc returns the field postgres' date_trunc cuts timestamps down to for the period
+func (r ReportPeriod) Trunc() string {
+	switch r {
+	case Week:
+		return "week"
+	case Month:
+		return "month"
+	default:
+		return "day"
+	}
+}
@@ -0,0 +1,22 @@
+package enum
+
+type ReturnStatus string
+
+func (r ReturnStatus) String() string {
+	return string(r)
+}
+
+// Return Statuses
+const (
+	// ReturnRequested denotes a return opened by a customer and waiting for an admin
+	ReturnRequested ReturnStatus = "REQUESTED"
+
+	// ReturnApproved denotes a return an admin has agreed to take back
+	ReturnApproved ReturnStatus = "APPROVED"
+
+	// ReturnRejected denotes a return an admin has refused
+	ReturnRejected ReturnStatus = "REJECTED"
+
+	// ReturnReceived denotes an approved return whose items have arrived and been put back in stock
+	ReturnReceived ReturnStatus = "RECEIVED"
+)
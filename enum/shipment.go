@@ -0,0 +1,16 @@
+package enum
+
+type ShipmentStatus string
+
+func (s ShipmentStatus) String() string {
+	return string(s)
+}
+
+// Shipment Statuses
+const (
+	// Shipped denotes a shipment handed over to the carrier
+	Shipped ShipmentStatus = "SHIPPED"
+
+	// Delivered denotes a shipment received by the customer
+	Delivered ShipmentStatus = "DELIVERED"
+)
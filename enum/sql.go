@@ -23,9 +23,11 @@ type SQLValueMerge struct {
 
 // SQLAlmostRaw is a struct that holds a raw SQL value and an operator
 // the value is assigned as is to the column it is mapped against
+// Args are bound to the placeholders in the value
 type SQLAlmostRaw struct {
 	Value    interface{}
 	Operator SQLOperator
+	Args     []interface{}
 }
 
 // SQLRaw is a struct that holds a raw SQL value
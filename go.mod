@@ -8,6 +8,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/opensaucerer/barf v1.1.1
 	github.com/opensaucerer/bifrost v0.0.8
+	github.com/opensaucerer/imgconv v0.0.0-20230518033447-48b43a85aa95
 	github.com/uptrace/bun v1.2.5
 	github.com/uptrace/bun/dialect/pgdialect v1.2.5
 	github.com/uptrace/bun/driver/pgdriver v1.2.5
@@ -54,7 +55,6 @@ require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.14 // indirect
-	github.com/opensaucerer/imgconv v0.0.0-20230518033447-48b43a85aa95 // indirect
 	github.com/pdfcpu/pdfcpu v0.4.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/puzpuzpuz/xsync/v3 v3.4.0 // indirect
@@ -0,0 +1,706 @@
+package cart
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/order"
+	"github.com/funmi4194/ecommerce/primer"
+	cartRepository "github.com/funmi4194/ecommerce/repository/cart"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// Line is an item in a cart checked against the current state of its product
+type Line struct {
+	ProductID  string `json:"product_id"`
+	VariantID  string `json:"variant_id,omitempty"`
+	Name       string `json:"name"`
+	ProductUrl string `json:"product_url"`
+	Quantity   int    `json:"quantity"`
+
+	// the current price of the product and, when it has changed since the customer last saw it, the old price (in the smallest unit of the currency)
+	Price         int64         `json:"price"`
+	PreviousPrice int64         `json:"previous_price,omitempty"`
+	Currency      enum.Currency `json:"currency"`
+
+	Total int64 `json:"total"`
+
+	// false when the item cannot be ordered as it stands, with the reason why
+	Available bool   `json:"available"`
+	Issue     string `json:"issue,omitempty"`
+}
+
+// View is a cart as the customer sees it
+type View struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+	Items []Line `json:"items"`
+
+	// the sum of the available items in each currency they are priced in
+	Subtotals map[enum.Currency]int64 `json:"subtotals"`
+}
+
+// where returns the filter matching the given columns
+func where(m map[string]interface{}) types.SQLMaps {
+	return types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                m,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}
+}
+
+// owner returns the filter matching the cart of the user or, for a guest, the cart holding the token
+func owner(userId, token string) map[string]interface{} {
+	if userId != "" {
+		return map[string]interface{}{
+			"user_id": userId,
+		}
+	}
+	return map[string]interface{}{
+		"token":   token,
+		"user_id": "",
+	}
+}
+
+/*
+cartFor finds and locks the cart of the user or, for a guest, the cart holding the token using the provided transaction
+
+When "create" is true a new cart is started if none is found, guests always get a freshly generated token rather than the one they sent
+
+It returns sql.ErrNoRows if there is no cart and none was to be created
+*/
+func cartFor(tx *bun.Tx, userId, token string, create bool) (*cartRepository.Cart, error) {
+
+	var cart cartRepository.Cart
+
+	if userId != "" || token != "" {
+		err := cart.FUByMap(tx, where(owner(userId, token)))
+		if err == nil {
+			return &cart, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	if !create {
+		return nil, sql.ErrNoRows
+	}
+
+	cart = cartRepository.Cart{
+		ID:     helper.GenerateUUID(),
+		UserID: userId,
+		Token:  helper.GenerateUUID(),
+		Items:  []cartRepository.Item{},
+	}
+	cart.Date()
+
+	// create cart
+	created, err := cart.CreateTx(tx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         cart.ID,
+					"user_id":    cart.UserID,
+					"token":      cart.Token,
+					"items":      cart.Items,
+					"created_at": cart.CreatedAt,
+					"updated_at": cart.UpdatedAt,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// another request started the user's cart first
+	if !created {
+		if err := cart.FUByMap(tx, where(owner(userId, ""))); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cart, nil
+}
+
+// save writes the items of the cart using the provided transaction
+func save(tx *bun.Tx, cart *cartRepository.Cart) error {
+	return cart.UByMapTx(tx, types.SQLMaps{
+		WMaps: where(map[string]interface{}{
+			"id": cart.ID,
+		}).WMaps,
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"items":      cart.Items,
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	})
+}
+
+/*
+view checks every item in the cart against the current state of its product
+
+Prices on the cart are moved to the current prices, so it reports whether the cart changed and needs saving
+*/
+func view(cart *cartRepository.Cart) (*View, bool, error) {
+
+	v := View{
+		ID:        cart.ID,
+		Token:     cart.Token,
+		Items:     []Line{},
+		Subtotals: map[enum.Currency]int64{},
+	}
+
+	if len(cart.Items) == 0 {
+		return &v, false, nil
+	}
+
+	ids := []interface{}{}
+	for _, item := range cart.Items {
+		ids = append(ids, item.ProductID)
+	}
+
+	products := make(productRepository.Products, 0)
+
+	// find the products in the cart
+	if err := products.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": ids,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, len(ids), 0, enum.DESC.String(), true, true); err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	variants := make(variantRepository.Variants, 0)
+
+	// find the variants of the products in the cart
+	if err := variants.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": ids,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	found := map[string]productRepository.Product{}
+	for _, product := range products {
+		found[product.ID] = product
+	}
+
+	changed := false
+	for i, item := range cart.Items {
+
+		line := Line{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+			Available: true,
+		}
+
+		product, ok := found[item.ProductID]
+		var variant *variantRepository.Variant
+		var err error
+		if ok {
+			variant, err = order.VariantOf(product, variants, item.VariantID)
+		}
+
+		stock := product.Stock
+		if variant != nil {
+			stock = variant.Stock
+		}
+
+		switch {
+		case !ok || product.Status != enum.Published:
+			line.Available = false
+			line.Issue = "product is no longer available"
+		case err != nil && item.VariantID != "":
+			line.Available = false
+			line.Issue = "variant is no longer available"
+		case err != nil:
+			line.Available = false
+			line.Issue = "product is now sold in variants. please choose one"
+		case stock <= 0:
+			line.Available = false
+			line.Issue = "product is out of stock"
+		case int64(item.Quantity) > stock:
+			line.Available = false
+			line.Issue = fmt.Sprintf("only %d unit(s) are left in stock", stock)
+		}
+
+		if ok && err == nil {
+			line.Name = product.Name
+			line.ProductUrl = product.ProductUrl
+			line.Price = product.Price
+			line.Currency = product.Currency
+
+			if variant != nil {
+				line.Name = fmt.Sprintf("%s (%s)", product.Name, variant.Options.Label())
+				line.ProductUrl = variant.ImageUrl
+				line.Price = variant.Price
+			}
+
+			if line.Price != item.Price {
+				line.PreviousPrice = item.Price
+				cart.Items[i].Price = line.Price
+				changed = true
+			}
+		}
+
+		line.Total = line.Price * int64(line.Quantity)
+		if line.Available {
+			v.Subtotals[line.Currency] += line.Total
+		}
+
+		v.Items = append(v.Items, line)
+	}
+
+	return &v, changed, nil
+}
+
+// Cart is the logic function to retrieve the cart of a user or guest with every item checked against its product
+func Cart(userId, token string) (*View, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	cart, err := cartFor(btx, userId, token, false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &View{Items: []Line{}, Subtotals: map[enum.Currency]int64{}}, nil
+		}
+		barf.Logger().Errorf(`[cart.Cart] [cartFor(btx, userId, token, false)] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	v, changed, err := view(cart)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.Cart] [view(cart)] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	// price changes are only reported once
+	if changed {
+		if err := save(btx, cart); err != nil {
+			barf.Logger().Errorf(`[cart.Cart] [save(btx, cart)] %s`, err.Error())
+			return nil, errors.New("we're having issues retrieving your cart. please try again later")
+		}
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[cart.Cart] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	return v, nil
+}
+
+/*
+orderable finds the product (or the variant of it for a product sold in variants) and ensures the given quantity of it can
+be ordered
+
+It returns the current unit price in the smallest unit of the product's currency
+*/
+func orderable(productId, variantId string, quantity int) (int64, error) {
+
+	var product productRepository.Product
+
+	// find product
+	if err := product.FByKeyVal("id", productId, true); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New("product not found")
+		}
+		barf.Logger().Errorf(`[cart.orderable] [product.FByKeyVal("id", productId, true)] %s`, err.Error())
+		return 0, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	if product.Status != enum.Published {
+		return 0, fmt.Errorf("product '%s' is not available", product.Name)
+	}
+
+	variants := make(variantRepository.Variants, 0)
+
+	// find the variants of the product
+	if err := variants.FByMap(where(map[string]interface{}{
+		"product_id": product.ID,
+	})); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[cart.orderable] [variants.FByMap(where(map[string]interface{}{] %s`, err.Error())
+		return 0, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	variant, err := order.VariantOf(product, variants, variantId)
+	if err != nil {
+		return 0, err
+	}
+
+	name, price, stock := product.Name, product.Price, product.Stock
+	if variant != nil {
+		name, price, stock = fmt.Sprintf("%s (%s)", product.Name, variant.Options.Label()), variant.Price, variant.Stock
+	}
+
+	if int64(quantity) > stock {
+		if stock <= 0 {
+			return 0, fmt.Errorf("product '%s' is out of stock", name)
+		}
+		return 0, fmt.Errorf("only %d unit(s) of product '%s' are left in stock", stock, name)
+	}
+
+	return price, nil
+}
+
+/*
+AddItem is the logic function to put units of a product in the cart of a user or guest, starting a cart if there is none
+
+Adding a product already in the cart increases its quantity
+*/
+func AddItem(userId, token string, payload types.CartItem) (*View, error) {
+
+	if payload.ProductId == "" {
+		return nil, errors.New("product id is required")
+	}
+
+	if payload.Quantity <= 0 {
+		return nil, errors.New("item quantity must be greater than zero")
+	}
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	cart, err := cartFor(btx, userId, token, true)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.AddItem] [cartFor(btx, userId, token, true)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	index := -1
+	quantity := payload.Quantity
+	for i, item := range cart.Items {
+		if item.ProductID == payload.ProductId && item.VariantID == payload.VariantId {
+			index = i
+			quantity += item.Quantity
+			break
+		}
+	}
+
+	if index < 0 && len(cart.Items) >= primer.MaxCartItems {
+		return nil, fmt.Errorf("your cart can only hold up to %d products", primer.MaxCartItems)
+	}
+
+	price, err := orderable(payload.ProductId, payload.VariantId, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 {
+		cart.Items = append(cart.Items, cartRepository.Item{
+			ProductID: payload.ProductId,
+			VariantID: payload.VariantId,
+			Quantity:  quantity,
+			Price:     price,
+		})
+	} else {
+		cart.Items[index].Quantity = quantity
+		cart.Items[index].Price = price
+	}
+
+	if err := save(btx, cart); err != nil {
+		barf.Logger().Errorf(`[cart.AddItem] [save(btx, cart)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	v, _, err := view(cart)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.AddItem] [view(cart)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[cart.AddItem] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	return v, nil
+}
+
+// UpdateItem is the logic function to set the quantity of a product in the cart of a user or guest, a quantity of zero removes it
+func UpdateItem(userId, token string, payload types.CartItem) (*View, error) {
+
+	if payload.ProductId == "" {
+		return nil, errors.New("product id is required")
+	}
+
+	if payload.Quantity < 0 {
+		return nil, errors.New("item quantity cannot be negative")
+	}
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	cart, err := cartFor(btx, userId, token, false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("item is not in your cart")
+		}
+		barf.Logger().Errorf(`[cart.UpdateItem] [cartFor(btx, userId, token, false)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	index := -1
+	for i, item := range cart.Items {
+		if item.ProductID == payload.ProductId && item.VariantID == payload.VariantId {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		return nil, errors.New("item is not in your cart")
+	}
+
+	if payload.Quantity == 0 {
+		cart.Items = append(cart.Items[:index], cart.Items[index+1:]...)
+	} else {
+		price, err := orderable(payload.ProductId, payload.VariantId, payload.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		cart.Items[index].Quantity = payload.Quantity
+		cart.Items[index].Price = price
+	}
+
+	if err := save(btx, cart); err != nil {
+		barf.Logger().Errorf(`[cart.UpdateItem] [save(btx, cart)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	v, _, err := view(cart)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.UpdateItem] [view(cart)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[cart.UpdateItem] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your cart. please try again later")
+	}
+
+	return v, nil
+}
+
+// RemoveItem is the logic function to take a product out of the cart of a user or guest
+func RemoveItem(userId, token string, payload types.RemoveCartItem) (*View, error) {
+	return UpdateItem(userId, token, types.CartItem{
+		ProductId: payload.ProductId,
+		VariantId: payload.VariantId,
+		Quantity:  0,
+	})
+}
+
+/*
+MergeCart is the logic function to move the items of a guest cart into the cart of the user who just signed in
+
+Quantities of products in both carts are added up and the guest cart is removed, nothing happens when the token does not belong to a guest cart
+*/
+func MergeCart(userId, token string) error {
+
+	if token == "" {
+		return nil
+	}
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	// the guest cart is always locked before the user's cart
+	guest, err := cartFor(btx, "", token, false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		barf.Logger().Errorf(`[cart.MergeCart] [cartFor(btx, "", token, false)] %s`, err.Error())
+		return errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	cart, err := cartFor(btx, userId, "", true)
+	if err != nil {
+		barf.Logger().Errorf(`[cart.MergeCart] [cartFor(btx, userId, "", true)] %s`, err.Error())
+		return errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	for _, item := range guest.Items {
+		merged := false
+		for i := range cart.Items {
+			if cart.Items[i].ProductID == item.ProductID && cart.Items[i].VariantID == item.VariantID {
+				cart.Items[i].Quantity += item.Quantity
+				cart.Items[i].Price = item.Price
+				merged = true
+				break
+			}
+		}
+		if !merged && len(cart.Items) < primer.MaxCartItems {
+			cart.Items = append(cart.Items, item)
+		}
+	}
+
+	if err := save(btx, cart); err != nil {
+		barf.Logger().Errorf(`[cart.MergeCart] [save(btx, cart)] %s`, err.Error())
+		return errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	// remove the guest cart
+	if err := guest.DByMapTx(btx, where(map[string]interface{}{
+		"id": guest.ID,
+	})); err != nil {
+		barf.Logger().Errorf(`[cart.MergeCart] [guest.DByMapTx(btx, where(map[string]interface{}{] %s`, err.Error())
+		return errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[cart.MergeCart] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	return nil
+}
+
+/*
+Checkout is the logic function to turn the user's cart into an order through order.InitiateOrder
+
+A guest cart named by the token is merged in first, and the units ordered are taken out of the cart once the order is created
+*/
+func Checkout(userId, token string, payload types.CartCheckout) (*orderRepository.Order, error) {
+
+	if err := MergeCart(userId, token); err != nil {
+		return nil, err
+	}
+
+	var cart cartRepository.Cart
+
+	// find cart
+	if err := cart.FByMap(where(owner(userId, ""))); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[cart.Checkout] [cart.FByMap(where(owner(userId, "")))] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving your cart. please try again later")
+	}
+
+	if len(cart.Items) == 0 {
+		return nil, errors.New("your cart is empty")
+	}
+
+	items := []types.Item{}
+	for _, item := range cart.Items {
+		items = append(items, types.Item{
+			ProductId: item.ProductID,
+			VariantId: item.VariantID,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	o, err := order.InitiateOrder(userId, types.InitiateOrder{
+		Items:      items,
+		AddressId:  payload.AddressId,
+		Address:    payload.Address,
+		CouponCode: payload.CouponCode,
+		Currency:   payload.Currency,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the order stands even if the cart cannot be emptied
+	if err := empty(userId, items); err != nil {
+		barf.Logger().Errorf(`[cart.Checkout] [empty(userId, items)] order %s was created but the cart was not emptied: %s`, o.ID, err.Error())
+	}
+
+	return o, nil
+}
+
+// empty takes the ordered units out of the user's cart, keeping anything added while the order was being created
+func empty(userId string, ordered []types.Item) error {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	cart, err := cartFor(btx, userId, "", false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	quantities := map[string]int{}
+	for _, item := range ordered {
+		quantities[item.ProductId+":"+item.VariantId] += item.Quantity
+	}
+
+	items := []cartRepository.Item{}
+	for _, item := range cart.Items {
+		item.Quantity -= quantities[item.ProductID+":"+item.VariantID]
+		if item.Quantity > 0 {
+			items = append(items, item)
+		}
+	}
+	cart.Items = items
+
+	if err := save(btx, cart); err != nil {
+		return err
+	}
+
+	return btx.Commit()
+}
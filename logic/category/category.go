@@ -0,0 +1,512 @@
+package category
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	categoryRepository "github.com/funmi4194/ecommerce/repository/category"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+var (
+	slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	notSlug     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// admin ensures the user exists and is an admin
+func admin(userId string) error {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[category.admin] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("looks like your account no longer exists. please contact support")
+		}
+		return errors.New("we're having issues retrieving your account. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return errors.New("you do not have the permission to access this feature")
+	}
+
+	return nil
+}
+
+// slugify turns a category name into a slug e.g "Men's Shoes" becomes "men-s-shoes"
+func slugify(name string) string {
+	return strings.Trim(notSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// taken reports whether another category than the one given already uses the slug
+func taken(slug, categoryId string) (bool, error) {
+
+	var category categoryRepository.Category
+
+	// find category by slug
+	err := category.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"slug": slug,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return category.ID != categoryId, nil
+}
+
+// validate checks the name, slug and parent of the category
+func validate(tx *bun.Tx, c *categoryRepository.Category) error {
+
+	c.Name = strings.TrimSpace(c.Name)
+	c.Slug = strings.TrimSpace(c.Slug)
+	c.ParentID = strings.TrimSpace(c.ParentID)
+	c.Description = strings.TrimSpace(c.Description)
+
+	if c.Name == "" {
+		return errors.New("category name is required")
+	}
+
+	if c.Slug == "" {
+		c.Slug = slugify(c.Name)
+	}
+
+	if !slugPattern.MatchString(c.Slug) {
+		return errors.New("category slug can only contain lower case letters and digits separated by single hyphens")
+	}
+
+	exists, err := taken(c.Slug, c.ID)
+	if err != nil {
+		barf.Logger().Errorf(`[category.validate] [taken(c.Slug, c.ID)] %s`, err.Error())
+		return errors.New("we're having issues saving the category. please try again later")
+	}
+	if exists {
+		return errors.New("a category with this slug already exists")
+	}
+
+	if c.ParentID == "" {
+		return nil
+	}
+
+	if c.ParentID == c.ID {
+		return errors.New("a category cannot sit under itself")
+	}
+
+	var parent categoryRepository.Category
+
+	// find parent and lock so that it is not deleted or moved under the category at the same time
+	if err := parent.FUByMap(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": c.ParentID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[category.validate] [parent.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("parent category not found")
+		}
+		return errors.New("we're having issues saving the category. please try again later")
+	}
+
+	// a new category has nothing below it
+	if c.ID == "" {
+		return nil
+	}
+
+	// the parent must not be one of the categories below this one
+	below, err := new(categoryRepository.Categories).CByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": parent.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+			{
+				Map: map[string]interface{}{
+					"categories.id": enum.SQLAlmostRaw{
+						Operator: enum.In,
+						Value:    categoryRepository.Subtree(c.ID),
+					},
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[category.validate] [new(categoryRepository.Categories).CByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues saving the category. please try again later")
+	}
+	if below > 0 {
+		return errors.New("a category cannot be moved under one of its own subcategories")
+	}
+
+	return nil
+}
+
+// CreateCategory is the logic function for an admin to add a category to the product taxonomy
+func CreateCategory(userId string, payload types.CreateCategory) (*categoryRepository.Category, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	category := categoryRepository.Category{
+		ParentID:    payload.ParentId,
+		Name:        payload.Name,
+		Slug:        payload.Slug,
+		Description: payload.Description,
+		CreatedBy:   userId,
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	if err := validate(btx, &category); err != nil {
+		return nil, err
+	}
+
+	category.ID = helper.GenerateUUID()
+	category.Date()
+
+	// create category
+	if err := category.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":          category.ID,
+					"parent_id":   category.ParentID,
+					"name":        category.Name,
+					"slug":        category.Slug,
+					"description": category.Description,
+					"created_by":  category.CreatedBy,
+					"created_at":  category.CreatedAt,
+					"updated_at":  category.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[category.CreateCategory] [category.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the category. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[category.CreateCategory] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the category. please try again later")
+	}
+
+	return &category, nil
+}
+
+// UpdateCategory is the logic function for an admin to rename a category or move it under another one
+func UpdateCategory(userId string, payload types.UpdateCategory) (*categoryRepository.Category, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.CategoryId == "" {
+		return nil, errors.New("category id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	var category categoryRepository.Category
+
+	// find category and lock
+	if err := category.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.CategoryId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[category.UpdateCategory] [category.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("category not found")
+		}
+		return nil, errors.New("we're having issues updating the category. please try again later")
+	}
+
+	if payload.Name != nil {
+		category.Name = *payload.Name
+	}
+	if payload.Slug != nil {
+		category.Slug = *payload.Slug
+	}
+	if payload.ParentId != nil {
+		category.ParentID = *payload.ParentId
+	}
+	if payload.Description != nil {
+		category.Description = *payload.Description
+	}
+
+	if err := validate(btx, &category); err != nil {
+		return nil, err
+	}
+
+	// update category
+	if err := category.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": category.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"parent_id":   category.ParentID,
+				"name":        category.Name,
+				"slug":        category.Slug,
+				"description": category.Description,
+				"updated_at":  bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[category.UpdateCategory] [category.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the category. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[category.UpdateCategory] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the category. please try again later")
+	}
+
+	return &category, nil
+}
+
+/*
+DeleteCategory is the logic function for an admin to remove a category, taking its products out of it
+
+A category with subcategories or that pricing rules are limited to cannot be deleted until they are moved or changed
+*/
+func DeleteCategory(userId string, payload types.DeleteCategory) error {
+
+	if err := admin(userId); err != nil {
+		return err
+	}
+
+	if payload.CategoryId == "" {
+		return errors.New("category id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	var category categoryRepository.Category
+
+	// find category and lock
+	if err := category.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.CategoryId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [category.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("category not found")
+		}
+		return errors.New("we're having issues deleting the category. please try again later")
+	}
+
+	// count subcategories
+	children, err := new(categoryRepository.Categories).CByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"parent_id": category.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [new(categoryRepository.Categories).CByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting the category. please try again later")
+	}
+	if children > 0 {
+		return errors.New("this category has subcategories. please move or delete them first")
+	}
+
+	rules := make(pricingRepository.Rules, 0)
+
+	// find rules limited to the category
+	if err := rules.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"category_id": category.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[category.DeleteCategory] [rules.FByMap(types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting the category. please try again later")
+	}
+	if len(rules) > 0 {
+		return errors.New("pricing rules are limited to this category. please change them first")
+	}
+
+	links := make(categoryRepository.Links, 0)
+
+	// take products out of the category
+	if err := links.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"category_id": category.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [links.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting the category. please try again later")
+	}
+
+	// delete category
+	if err := category.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": category.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [category.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting the category. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[category.DeleteCategory] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues deleting the category. please try again later")
+	}
+
+	return nil
+}
+
+// Categories is the logic function to list the whole product taxonomy (each category names its parent so clients can build the tree)
+func Categories() (*categoryRepository.Categories, error) {
+
+	categories := make(categoryRepository.Categories, 0)
+
+	// find categories
+	if err := categories.FByMap(types.SQLMaps{}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[category.Categories] [categories.FByMap(types.SQLMaps{})] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving categories. please try again later")
+	}
+
+	return &categories, nil
+}
+
+// Listing is the public view of a category
+type Listing struct {
+	ID          string `json:"id"`
+	ParentID    string `json:"parent_id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+}
+
+// Catalogue is the logic function for anyone, signed in or not, to browse the product taxonomy
+func Catalogue() ([]Listing, error) {
+
+	categories, err := Categories()
+	if err != nil {
+		return nil, err
+	}
+
+	listings := make([]Listing, 0, len(*categories))
+	for _, category := range *categories {
+		listings = append(listings, Listing{
+			ID:          category.ID,
+			ParentID:    category.ParentID,
+			Name:        category.Name,
+			Slug:        category.Slug,
+			Description: category.Description,
+		})
+	}
+
+	return listings, nil
+}
@@ -0,0 +1,419 @@
+package coupon
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	couponRepository "github.com/funmi4194/ecommerce/repository/coupon"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// admin ensures the user exists and is an admin
+func admin(userId string) error {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[coupon.admin] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("looks like your account no longer exists. please contact support")
+		}
+		return errors.New("we're having issues retrieving your account. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return errors.New("you do not have the permission to access this feature")
+	}
+
+	return nil
+}
+
+// validate checks the terms of the coupon
+func validate(c *couponRepository.Coupon) error {
+
+	switch c.Type {
+	case enum.Percentage:
+		if c.Percent <= 0 || c.Percent > 100 {
+			return errors.New("a percentage coupon must take between 0 and 100 percent off")
+		}
+		if c.Amount != 0 || c.Units != 0 {
+			return errors.New("a percentage coupon can only take a percent off")
+		}
+	case enum.FixedAmount:
+		if c.Amount < 1 {
+			return errors.New("a fixed amount coupon must take an amount greater than zero off (in kobo or cents)")
+		}
+		if c.Percent != 0 || c.Units != 0 {
+			return errors.New("a fixed amount coupon can only take an amount off")
+		}
+	case enum.FreeItem:
+		if c.Units < 1 {
+			return errors.New("a free item coupon must give at least one unit away")
+		}
+		if c.Percent != 0 || c.Amount != 0 {
+			return errors.New("a free item coupon can only give units away")
+		}
+	}
+
+	if !c.Currency.IsValid() {
+		return errors.New("coupon currency must be one of the currencies we sell in")
+	}
+
+	if c.MinSpend < 0 {
+		return errors.New("minimum spend cannot be negative")
+	}
+
+	if c.MaxUses < 0 || c.MaxUsesPerUser < 0 {
+		return errors.New("usage limits cannot be negative")
+	}
+
+	if !c.StartsAt.IsZero() && !c.EndsAt.IsZero() && !c.EndsAt.After(c.StartsAt.Time) {
+		return errors.New("coupon end date must be after its start date")
+	}
+
+	return nil
+}
+
+// CreateCoupon is the logic function for an admin to create a coupon
+func CreateCoupon(userId string, payload types.CreateCoupon) (*couponRepository.Coupon, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	coupon := couponRepository.Coupon{
+		Code:           strings.ToUpper(strings.TrimSpace(payload.Code)),
+		Type:           payload.Type,
+		Percent:        payload.Percent,
+		Amount:         payload.Amount,
+		Units:          payload.Units,
+		ProductID:      payload.ProductId,
+		MinSpend:       payload.MinSpend,
+		Currency:       payload.Currency,
+		MaxUses:        payload.MaxUses,
+		MaxUsesPerUser: payload.MaxUsesPerUser,
+		Active:         true,
+		CreatedBy:      userId,
+	}
+
+	if coupon.Code == "" {
+		return nil, errors.New("coupon code is required")
+	}
+
+	if !coupon.Type.IsValid() {
+		return nil, errors.New("coupon type must be one of PERCENTAGE, FIXED_AMOUNT or FREE_ITEM")
+	}
+
+	if payload.StartsAt != nil {
+		coupon.StartsAt = bun.NullTime{Time: *payload.StartsAt}
+	}
+	if payload.EndsAt != nil {
+		coupon.EndsAt = bun.NullTime{Time: *payload.EndsAt}
+	}
+	if payload.Active != nil {
+		coupon.Active = *payload.Active
+	}
+	if coupon.Currency == "" {
+		coupon.Currency = primer.DefaultCurrency
+	}
+
+	if coupon.Type == enum.FreeItem {
+		if coupon.Units == 0 {
+			coupon.Units = 1
+		}
+
+		if coupon.ProductID == "" {
+			return nil, errors.New("a free item coupon needs the product it gives away")
+		}
+
+		var product productRepository.Product
+
+		// find product
+		if err := product.FByKeyVal("id", coupon.ProductID, true); err != nil {
+			barf.Logger().Errorf(`[coupon.CreateCoupon] [product.FByKeyVal("id", coupon.ProductID, true)] %s`, err.Error())
+			if err == sql.ErrNoRows {
+				return nil, errors.New("product not found")
+			}
+			return nil, errors.New("we're having issues creating the coupon. please try again later")
+		}
+	} else {
+		coupon.ProductID = ""
+	}
+
+	if err := validate(&coupon); err != nil {
+		return nil, err
+	}
+
+	var existing couponRepository.Coupon
+
+	// ensure code is unique
+	err := existing.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"code": coupon.Code,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err == nil {
+		return nil, errors.New("coupon code is already in use")
+	}
+	if err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[coupon.CreateCoupon] [existing.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the coupon. please try again later")
+	}
+
+	coupon.ID = helper.GenerateUUID()
+	coupon.Date()
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	// create coupon
+	if err := coupon.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":                coupon.ID,
+					"code":              coupon.Code,
+					"type":              coupon.Type,
+					"product_id":        coupon.ProductID,
+					"min_spend":         coupon.MinSpend,
+					"currency":          coupon.Currency,
+					"max_uses":          coupon.MaxUses,
+					"max_uses_per_user": coupon.MaxUsesPerUser,
+					"uses":              coupon.Uses,
+					"starts_at":         coupon.StartsAt,
+					"ends_at":           coupon.EndsAt,
+					"active":            coupon.Active,
+					"created_by":        coupon.CreatedBy,
+					"created_at":        coupon.CreatedAt,
+					"updated_at":        coupon.UpdatedAt,
+					"percent":           coupon.Percent,
+					"amount":            coupon.Amount,
+					"units":             coupon.Units,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[coupon.CreateCoupon] [coupon.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the coupon. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[coupon.CreateCoupon] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the coupon. please try again later")
+	}
+
+	return &coupon, nil
+}
+
+// UpdateCoupon is the logic function for an admin to change the terms of a coupon or switch it on and off
+func UpdateCoupon(userId string, payload types.UpdateCoupon) (*couponRepository.Coupon, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.CouponId == "" {
+		return nil, errors.New("coupon id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	var coupon couponRepository.Coupon
+
+	// find coupon and lock
+	if err := coupon.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.CouponId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[coupon.UpdateCoupon] [coupon.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, errors.New("we're having issues updating the coupon. please try again later")
+	}
+
+	if payload.Percent != nil {
+		coupon.Percent = *payload.Percent
+	}
+	if payload.Amount != nil {
+		coupon.Amount = *payload.Amount
+	}
+	if payload.Units != nil {
+		coupon.Units = *payload.Units
+	}
+	if payload.MinSpend != nil {
+		coupon.MinSpend = *payload.MinSpend
+	}
+	if payload.Currency != nil {
+		coupon.Currency = *payload.Currency
+	}
+	if payload.MaxUses != nil {
+		coupon.MaxUses = *payload.MaxUses
+	}
+	if payload.MaxUsesPerUser != nil {
+		coupon.MaxUsesPerUser = *payload.MaxUsesPerUser
+	}
+	if payload.StartsAt != nil {
+		coupon.StartsAt = bun.NullTime{Time: *payload.StartsAt}
+	}
+	if payload.EndsAt != nil {
+		coupon.EndsAt = bun.NullTime{Time: *payload.EndsAt}
+	}
+	if payload.Active != nil {
+		coupon.Active = *payload.Active
+	}
+
+	if err := validate(&coupon); err != nil {
+		return nil, err
+	}
+
+	// update coupon
+	if err := coupon.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": coupon.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"percent":           coupon.Percent,
+				"amount":            coupon.Amount,
+				"units":             coupon.Units,
+				"min_spend":         coupon.MinSpend,
+				"currency":          coupon.Currency,
+				"max_uses":          coupon.MaxUses,
+				"max_uses_per_user": coupon.MaxUsesPerUser,
+				"starts_at":         coupon.StartsAt,
+				"ends_at":           coupon.EndsAt,
+				"active":            coupon.Active,
+				"updated_at":        bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[coupon.UpdateCoupon] [coupon.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the coupon. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[coupon.UpdateCoupon] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the coupon. please try again later")
+	}
+
+	return &coupon, nil
+}
+
+// Coupons is the logic function for an admin to list coupons
+func Coupons(userId string, payload types.CouponFilter) (*couponRepository.Coupons, *commonRepository.Pagination, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, nil, err
+	}
+
+	coupons := make(couponRepository.Coupons, 0)
+
+	//  generate filter map
+	Eqfilter := map[string]interface{}{}
+
+	if payload.Code != "" {
+		Eqfilter["code"] = strings.ToUpper(strings.TrimSpace(payload.Code))
+	}
+	if payload.Active != nil {
+		Eqfilter["active"] = *payload.Active
+	}
+
+	limit := primer.PageLimit
+	page := 1
+	offset := 0
+
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+
+	if payload.Page != nil {
+		offset = (*payload.Page - 1) * limit
+		page = *payload.Page
+	}
+
+	queryMap := []types.SQLMap{
+		{
+			Map:                Eqfilter,
+			JoinOperator:       enum.And,
+			ComparisonOperator: enum.Equal,
+		},
+	}
+
+	if err := coupons.FByMap(types.SQLMaps{
+		WMaps:         queryMap,
+		WJoinOperator: enum.And,
+	}, limit, offset); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[coupon.Coupons] [coupons.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues retrieving coupons. please try again later")
+	}
+
+	total, err := coupons.CByMap(types.SQLMaps{
+		WMaps:         queryMap,
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[coupon.Coupons] [coupons.CByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues retrieving coupons. please try again later")
+	}
+
+	return &coupons, &commonRepository.Pagination{
+		Page:  page,
+		Limit: limit,
+		Total: total,
+		Pages: int(math.Ceil(float64(total) / float64(limit))),
+	}, nil
+}
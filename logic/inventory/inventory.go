@@ -0,0 +1,497 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	"github.com/funmi4194/ecommerce/primitive"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// Reconciliation is the stock of a product (or one of its variants) checked against the movements recorded for it
+type Reconciliation struct {
+	ProductID string `json:"product_id"`
+	VariantID string `json:"variant_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+
+	// the current stock and the stock the recorded movements add up to
+	Stock    int64 `json:"stock"`
+	Recorded int64 `json:"recorded"`
+
+	// the stock the movements do not account for (stock - recorded)
+	Drift int64 `json:"drift"`
+
+	// the movement recorded to account for the drift when the reconciliation was applied
+	Adjustment *inventoryRepository.Movement `json:"adjustment,omitempty"`
+}
+
+// admin ensures the user exists and is an admin
+func admin(userId string) error {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[inventory.admin] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("looks like your account no longer exists. please contact support")
+		}
+		return errors.New("we're having issues retrieving your account. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return errors.New("you do not have the permission to access this feature")
+	}
+
+	return nil
+}
+
+/*
+Record adds the movement to the stock history of its product (or variant) using the provided transaction, the change must already
+have been made to the stock and the movement's balance set to the stock left after it
+
+It returns an error if any
+*/
+func Record(tx *bun.Tx, movement *inventoryRepository.Movement) error {
+
+	movement.ID = helper.GenerateUUID()
+	movement.Date()
+
+	return movement.CreateTx(tx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         movement.ID,
+					"product_id": movement.ProductID,
+					"variant_id": movement.VariantID,
+					"kind":       movement.Kind,
+					"quantity":   movement.Quantity,
+					"balance":    movement.Balance,
+					"order_id":   movement.OrderID,
+					"actor_id":   movement.ActorID,
+					"reason":     movement.Reason,
+					"created_at": movement.CreatedAt,
+				},
+			},
+		},
+	})
+}
+
+/*
+Move changes the stock of the product (or variant) of the movement by the movement's quantity and records the movement with the stock
+left after it using the provided transaction. This is the only way stock should change once a product (or variant) exists
+
+The product (or variant) must be locked by the caller, and it returns an error if any
+*/
+func Move(tx *bun.Tx, movement inventoryRepository.Movement) (*inventoryRepository.Movement, error) {
+
+	id := movement.ProductID
+	if movement.VariantID != "" {
+		id = movement.VariantID
+	}
+
+	query := types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": id,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"stock": enum.SQLValueMerge{
+					Operator: enum.PLUS,
+					Values:   primitive.Array{movement.Quantity},
+				},
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}
+
+	// change stock
+	if movement.VariantID != "" {
+		var variant variantRepository.Variant
+		if err := variant.UByMapTx(tx, query); err != nil {
+			return nil, err
+		}
+		movement.Balance = variant.Stock
+	} else {
+		var product productRepository.Product
+		if err := product.UByMapTx(tx, query); err != nil {
+			return nil, err
+		}
+		movement.Balance = product.Stock
+	}
+
+	if err := Record(tx, &movement); err != nil {
+		return nil, err
+	}
+
+	return &movement, nil
+}
+
+/*
+lock finds and locks the product, or the variant of the product when a variant id is given, using the provided transaction and
+returns its current stock
+
+It returns an error if any
+*/
+func lock(tx *bun.Tx, productId, variantId string) (int64, error) {
+
+	if variantId == "" {
+		var product productRepository.Product
+
+		// find product and lock
+		if err := product.FUByMap(tx, types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"id": productId,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		}); err != nil {
+			barf.Logger().Errorf(`[inventory.lock] [product.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+			if err == sql.ErrNoRows {
+				return 0, errors.New("product item not found")
+			}
+			return 0, errors.New("we're having issues retrieving the product's stock. please try again later")
+		}
+
+		return product.Stock, nil
+	}
+
+	var variant variantRepository.Variant
+
+	// find variant and lock
+	if err := variant.FUByMap(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         variantId,
+					"product_id": productId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[inventory.lock] [variant.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return 0, errors.New("variant not found")
+		}
+		return 0, errors.New("we're having issues retrieving the variant's stock. please try again later")
+	}
+
+	return variant.Stock, nil
+}
+
+// Adjust is the logic function for an admin to add units to or take units from the stock of a product or variant
+func Adjust(userId string, payload types.AdjustStock) (*inventoryRepository.Movement, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.ProductId == "" {
+		return nil, errors.New("product id is required")
+	}
+
+	if payload.Quantity == 0 {
+		return nil, errors.New("quantity must be a number of units to add (positive) or take away (negative)")
+	}
+
+	if payload.Kind == "" {
+		payload.Kind = enum.Adjustment
+	}
+	payload.Kind = enum.MovementKind(strings.ToUpper(payload.Kind.String()))
+
+	if payload.Kind != enum.Restock && payload.Kind != enum.Adjustment {
+		return nil, errors.New("stock can only be adjusted as a RESTOCK or an ADJUSTMENT")
+	}
+
+	if payload.Kind == enum.Restock && payload.Quantity < 0 {
+		return nil, errors.New("a restock must add units to stock")
+	}
+
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if payload.Kind == enum.Adjustment && payload.Reason == "" {
+		return nil, errors.New("a reason is required to adjust stock")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	stock, err := lock(btx, payload.ProductId, payload.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if stock+payload.Quantity < 0 {
+		return nil, fmt.Errorf("stock cannot go below zero, only %d unit(s) are in stock", stock)
+	}
+
+	movement, err := Move(btx, inventoryRepository.Movement{
+		ProductID: payload.ProductId,
+		VariantID: payload.VariantId,
+		Kind:      payload.Kind,
+		Quantity:  payload.Quantity,
+		ActorID:   userId,
+		Reason:    payload.Reason,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[inventory.Adjust] [Move(btx, inventoryRepository.Movement{] %s`, err.Error())
+		return nil, errors.New("we're having issues adjusting stock. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[inventory.Adjust] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues adjusting stock. please try again later")
+	}
+
+	return movement, nil
+}
+
+// Movements is the logic function for an admin to list the stock history of a product, newest first
+func Movements(userId string, payload types.MovementFilter) (*inventoryRepository.Movements, *commonRepository.Pagination, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, nil, err
+	}
+
+	if payload.ProductId == "" {
+		return nil, nil, errors.New("product id is required")
+	}
+
+	//  generate filter map
+	Eqfilter := map[string]interface{}{
+		"product_id": payload.ProductId,
+	}
+
+	if payload.VariantId != "" {
+		Eqfilter["variant_id"] = payload.VariantId
+	}
+	if payload.Kind != "" {
+		Eqfilter["kind"] = enum.MovementKind(strings.ToUpper(payload.Kind.String()))
+	}
+	if payload.OrderId != "" {
+		Eqfilter["order_id"] = payload.OrderId
+	}
+
+	limit := primer.PageLimit
+	page := 1
+	offset := 0
+
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+
+	if payload.Page != nil {
+		offset = (*payload.Page - 1) * limit
+		page = *payload.Page
+	}
+
+	queryMap := []types.SQLMap{
+		{
+			Map:                Eqfilter,
+			JoinOperator:       enum.And,
+			ComparisonOperator: enum.Equal,
+		},
+	}
+
+	movements := make(inventoryRepository.Movements, 0)
+
+	// find movements
+	if err := movements.FByMap(types.SQLMaps{
+		WMaps:         queryMap,
+		WJoinOperator: enum.And,
+	}, limit, offset); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[inventory.Movements] [movements.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues retrieving stock movements. please try again later")
+	}
+
+	var pagination *commonRepository.Pagination
+
+	if payload.Paginate {
+		total, err := movements.CByMap(types.SQLMaps{
+			WMaps:         queryMap,
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[inventory.Movements] [movements.CByMap(types.SQLMaps{] %s`, err.Error())
+			return nil, nil, errors.New("we're having issues retrieving stock movements. please try again later")
+		}
+
+		pagination = &commonRepository.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+			Pages: int(math.Ceil(float64(total) / float64(limit))),
+		}
+	}
+
+	return &movements, pagination, nil
+}
+
+/*
+Reconcile is the logic function for an admin to check the stock of a product and each of its variants against the movements recorded for
+them, any drift means the stock was changed without a movement being recorded
+
+When applied, an ADJUSTMENT is recorded for the drift (the stock itself is left as it is) so that the movements add up to the stock again
+*/
+func Reconcile(userId string, payload types.ReconcileStock) ([]Reconciliation, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.ProductId == "" {
+		return nil, errors.New("product id is required")
+	}
+
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if payload.Reason == "" {
+		payload.Reason = "reconciled against stock"
+	}
+
+	variants := make(variantRepository.Variants, 0)
+
+	// find the product's variants
+	if err := variants.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": payload.ProductId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[inventory.Reconcile] [variants.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues reconciling stock. please try again later")
+	}
+
+	reconciliations := []Reconciliation{{ProductID: payload.ProductId}}
+	for _, variant := range variants {
+		reconciliations = append(reconciliations, Reconciliation{ProductID: payload.ProductId, VariantID: variant.ID, SKU: variant.SKU})
+	}
+
+	// rows are locked in the order of their ids, like checkouts do, so that a reconciliation cannot deadlock with them
+	sort.SliceStable(reconciliations, func(i, j int) bool {
+		return key(reconciliations[i]) < key(reconciliations[j])
+	})
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	for i, r := range reconciliations {
+
+		stock, err := lock(btx, r.ProductID, r.VariantID)
+		if err != nil {
+			return nil, err
+		}
+
+		var movements inventoryRepository.Movements
+
+		// add up the recorded movements
+		recorded, err := movements.SumByMapTx(btx, types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"product_id": r.ProductID,
+						"variant_id": r.VariantID,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[inventory.Reconcile] [movements.SumByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+			return nil, errors.New("we're having issues reconciling stock. please try again later")
+		}
+
+		reconciliations[i].Stock = stock
+		reconciliations[i].Recorded = recorded
+		reconciliations[i].Drift = stock - recorded
+
+		if !payload.Apply || reconciliations[i].Drift == 0 {
+			continue
+		}
+
+		adjustment := inventoryRepository.Movement{
+			ProductID: r.ProductID,
+			VariantID: r.VariantID,
+			Kind:      enum.Adjustment,
+			Quantity:  reconciliations[i].Drift,
+			Balance:   stock,
+			ActorID:   userId,
+			Reason:    payload.Reason,
+		}
+
+		// record the drift without changing stock
+		if err := Record(btx, &adjustment); err != nil {
+			barf.Logger().Errorf(`[inventory.Reconcile] [Record(btx, &adjustment)] %s`, err.Error())
+			return nil, errors.New("we're having issues reconciling stock. please try again later")
+		}
+		reconciliations[i].Adjustment = &adjustment
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[inventory.Reconcile] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues reconciling stock. please try again later")
+	}
+
+	return reconciliations, nil
+}
+
+// key returns the id of the row holding the stock being reconciled
+func key(r Reconciliation) string {
+	if r.VariantID != "" {
+		return r.VariantID
+	}
+	return r.ProductID
+}
@@ -0,0 +1,79 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/funmi4194/ecommerce/enum"
+	addressRepository "github.com/funmi4194/ecommerce/repository/address"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+/*
+shippingAddress resolves the address an order placed by the user is delivered to
+
+An address given in full is used as is, otherwise the saved address named by "addressId" or, failing that, the user's
+default address is used
+
+It returns a copy of the address to be stored on the order and an error if any
+*/
+func shippingAddress(userId, addressId string, given *types.Address) (*orderRepository.Address, error) {
+
+	if given != nil {
+		address := addressRepository.Address{
+			Recipient:  given.Recipient,
+			Phone:      given.Phone,
+			Line1:      given.Line1,
+			Line2:      given.Line2,
+			City:       given.City,
+			State:      given.State,
+			PostalCode: given.PostalCode,
+			Country:    given.Country,
+		}
+		if err := address.Prepare(); err != nil {
+			return nil, err
+		}
+		return address.Snapshot(), nil
+	}
+
+	filter := map[string]interface{}{
+		"user_id": userId,
+	}
+	if addressId != "" {
+		filter["id"] = addressId
+	} else {
+		filter["is_default"] = true
+	}
+
+	var address addressRepository.Address
+
+	// find address
+	if err := address.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                filter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			if addressId != "" {
+				return nil, errors.New("shipping address not found")
+			}
+			return nil, errors.New("a shipping address is required. please add an address or provide one with the order")
+		}
+		barf.Logger().Errorf(`[order.shippingAddress] [address.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving your shipping address. please try again later")
+	}
+
+	// addresses saved before a field became required are checked again
+	if err := address.Prepare(); err != nil {
+		return nil, err
+	}
+
+	return address.Snapshot(), nil
+}
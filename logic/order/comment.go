@@ -0,0 +1,182 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	commentRepository "github.com/funmi4194/ecommerce/repository/comment"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// viewableOrder finds the order the user wants to look into, users other than admins can only reach their own orders
+func viewableOrder(user *userRepository.User, orderId string) (*orderRepository.Order, error) {
+
+	if orderId == "" {
+		return nil, errors.New("order id is required")
+	}
+
+	filter := map[string]interface{}{
+		"id": orderId,
+	}
+	if user.Role != enum.Admin {
+		filter["user_id"] = user.ID
+	}
+
+	var order orderRepository.Order
+
+	// find order
+	if err := order.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                filter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.viewableOrder] [order.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, errors.New("we're having issues retrieving the order. please try again later")
+	}
+
+	return &order, nil
+}
+
+// AddComment is the logic function for the customer who placed an order or an admin to add to the order's comment thread
+func AddComment(userId string, payload types.AddComment) (*commentRepository.Comment, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.AddComment] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues adding the comment. please try again later")
+	}
+
+	payload.Body = strings.TrimSpace(payload.Body)
+	if payload.Body == "" {
+		return nil, errors.New("comment cannot be empty")
+	}
+	if utf8.RuneCountInString(payload.Body) > primer.MaxCommentLength {
+		return nil, fmt.Errorf("comment cannot be longer than %d characters", primer.MaxCommentLength)
+	}
+
+	// customers can only write comments they can read
+	visibility := enum.Customer
+	if user.Role == enum.Admin {
+		visibility = enum.CommentVisibility(strings.ToUpper(payload.Visibility.String()))
+		if visibility == "" {
+			visibility = enum.Internal
+		}
+		if !visibility.IsValid() {
+			return nil, errors.New("visibility must be INTERNAL or CUSTOMER")
+		}
+	} else if payload.Visibility != "" && payload.Visibility != enum.Customer {
+		return nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	order, err := viewableOrder(&user, payload.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := commentRepository.Comment{
+		ID:         helper.GenerateUUID(),
+		OrderID:    order.ID,
+		AuthorID:   user.ID,
+		AuthorRole: user.Role,
+		Body:       payload.Body,
+		Visibility: visibility,
+	}
+	comment.Date()
+
+	// create comment
+	if err := comment.Create(types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":          comment.ID,
+					"order_id":    comment.OrderID,
+					"author_id":   comment.AuthorID,
+					"author_role": comment.AuthorRole,
+					"body":        comment.Body,
+					"visibility":  comment.Visibility,
+					"created_at":  comment.CreatedAt,
+					"updated_at":  comment.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[order.AddComment] [comment.Create(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues adding the comment. please try again later")
+	}
+
+	return &comment, nil
+}
+
+// Comments is the logic function to read the comment thread of an order, customers only see the comments meant for them
+func Comments(userId string, payload types.ListComments) (*commentRepository.Comments, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Comments] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues retrieving comments. please try again later")
+	}
+
+	order, err := viewableOrder(&user, payload.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := map[string]interface{}{
+		"order_id": order.ID,
+	}
+	if user.Role != enum.Admin {
+		filter["visibility"] = enum.Customer
+	}
+
+	comments := make(commentRepository.Comments, 0)
+
+	// find comments
+	if err := comments.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                filter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.Comments] [comments.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving comments. please try again later")
+	}
+
+	return &comments, nil
+}
@@ -0,0 +1,329 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	"github.com/funmi4194/ecommerce/primitive"
+	couponRepository "github.com/funmi4194/ecommerce/repository/coupon"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// subtotal returns the sum of the product lines of an invoice
+func subtotal(items []orderRepository.Item) int64 {
+	total := int64(0)
+	for _, item := range items {
+		if item.IsProduct() {
+			total += item.Amount * int64(item.Quantity)
+		}
+	}
+	return total
+}
+
+/*
+couponDiscount checks the order qualifies for the coupon, where "used" is the number of times the order's user has used it, and
+returns the discount line to add to the order's invoice
+
+Nothing is read or written here, so the same checks back a quote and a checkout
+*/
+func couponDiscount(coupon *couponRepository.Coupon, order *orderRepository.Order, used int, at time.Time) (*orderRepository.Item, error) {
+
+	if !coupon.Usable(at) {
+		return nil, fmt.Errorf("coupon %s is not active or has expired", coupon.Code)
+	}
+
+	if coupon.MaxUses > 0 && coupon.Uses >= coupon.MaxUses {
+		return nil, fmt.Errorf("coupon %s has reached its usage limit", coupon.Code)
+	}
+
+	if coupon.MaxUsesPerUser > 0 && used >= coupon.MaxUsesPerUser {
+		return nil, fmt.Errorf("you have already used coupon %s the maximum number of times", coupon.Code)
+	}
+
+	// amounts on the coupon cannot be compared with an order in another currency
+	if coupon.Currency != order.Currency && (coupon.Type == enum.FixedAmount || coupon.MinSpend > 0) {
+		return nil, fmt.Errorf("coupon %s can only be used on orders paid in %s", coupon.Code, coupon.Currency)
+	}
+
+	total := subtotal(order.Invoice)
+	if total < coupon.MinSpend {
+		return nil, fmt.Errorf("you need to spend at least %s to use coupon %s", coupon.Currency.Format(coupon.MinSpend), coupon.Code)
+	}
+
+	discount := int64(0)
+	switch coupon.Type {
+	case enum.Percentage:
+		discount = percentage(total, coupon.Percent)
+	case enum.FixedAmount:
+		discount = coupon.Amount
+	case enum.FreeItem:
+		for _, item := range order.Invoice {
+			if item.IsProduct() && item.ProductID() == coupon.ProductID {
+				units := coupon.Units
+				if units > item.Quantity {
+					units = item.Quantity
+				}
+				discount = item.Amount * int64(units)
+				break
+			}
+		}
+		if discount == 0 {
+			return nil, fmt.Errorf("coupon %s only applies to orders containing the product it gives away", coupon.Code)
+		}
+	}
+
+	// an order never costs less than nothing
+	if discount > total {
+		discount = total
+	}
+
+	return &orderRepository.Item{
+		Key:      coupon.Code,
+		Name:     fmt.Sprintf("Coupon %s", coupon.Code),
+		Amount:   -discount,
+		Quantity: 1,
+		Metadata: primer.Stringify(orderRepository.LineMetadata{
+			Type:       enum.DiscountLine,
+			CouponID:   coupon.ID,
+			CouponCode: coupon.Code,
+		}),
+	}, nil
+}
+
+// quoteCoupon reads the coupon with the order's coupon code without a lock and returns the discount line it would give the order
+func quoteCoupon(order *orderRepository.Order) (*orderRepository.Item, error) {
+
+	var coupon couponRepository.Coupon
+
+	// find coupon
+	if err := coupon.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"code": order.CouponCode,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("coupon %s is not valid", order.CouponCode)
+		}
+		barf.Logger().Errorf(`[order.quoteCoupon] [coupon.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues applying your coupon. please try again later")
+	}
+
+	used := 0
+	if coupon.MaxUsesPerUser > 0 {
+
+		var redemption couponRepository.Redemption
+
+		// count the user's uses of the coupon
+		count, err := redemption.CByMap(types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"coupon_id": coupon.ID,
+						"user_id":   order.UserID,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[order.quoteCoupon] [redemption.CByMap(types.SQLMaps{] %s`, err.Error())
+			return nil, errors.New("we're having issues applying your coupon. please try again later")
+		}
+		used = count
+	}
+
+	return couponDiscount(&coupon, order, used, time.Now())
+}
+
+/*
+applyCoupon locks the coupon with the order's coupon code, checks the order qualifies for it and records its use by the order
+using the provided transaction
+
+It returns the discount line to add to the order's invoice
+*/
+func applyCoupon(tx *bun.Tx, order *orderRepository.Order) (*orderRepository.Item, error) {
+
+	var coupon couponRepository.Coupon
+
+	// find coupon and lock (this serializes the usage checks below)
+	if err := coupon.FUByMap(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"code": order.CouponCode,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("coupon %s is not valid", order.CouponCode)
+		}
+		barf.Logger().Errorf(`[order.applyCoupon] [coupon.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues applying your coupon. please try again later")
+	}
+
+	var redemption couponRepository.Redemption
+
+	used := 0
+	if coupon.MaxUsesPerUser > 0 {
+
+		// count the user's uses of the coupon
+		count, err := redemption.CByMapTx(tx, types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"coupon_id": coupon.ID,
+						"user_id":   order.UserID,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[order.applyCoupon] [redemption.CByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+			return nil, errors.New("we're having issues applying your coupon. please try again later")
+		}
+		used = count
+	}
+
+	discount, err := couponDiscount(&coupon, order, used, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// record the use
+	if err := redemption.CreateTx(tx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         helper.GenerateUUID(),
+					"coupon_id":  coupon.ID,
+					"user_id":    order.UserID,
+					"order_id":   order.ID,
+					"amount":     -discount.Amount,
+					"created_at": bun.NullTime{Time: time.Now()},
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[order.applyCoupon] [redemption.CreateTx(tx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues applying your coupon. please try again later")
+	}
+
+	if err := coupon.UByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": coupon.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"uses": enum.SQLValueMerge{
+					Operator: enum.PLUS,
+					Values:   primitive.Array{1},
+				},
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.applyCoupon] [coupon.UByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues applying your coupon. please try again later")
+	}
+
+	return discount, nil
+}
+
+/*
+releaseCoupon gives the use of a coupon held by the order back using the provided transaction, so that a cancelled,
+rejected or failed order does not count against the coupon's limits
+
+Calling it for an order whose coupon has already been released does nothing
+*/
+func releaseCoupon(tx *bun.Tx, order *orderRepository.Order) error {
+
+	if order.CouponCode == "" {
+		return nil
+	}
+
+	var redemption couponRepository.Redemption
+
+	// remove the use
+	if err := redemption.DByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"order_id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		barf.Logger().Errorf(`[order.releaseCoupon] [redemption.DByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues releasing the coupon on the order. please try again later")
+	}
+
+	var coupon couponRepository.Coupon
+
+	if err := coupon.UByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": redemption.CouponID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"uses": enum.SQLValueMerge{
+					Operator: enum.MINUS,
+					Values:   primitive.Array{1},
+				},
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.releaseCoupon] [coupon.UByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues releasing the coupon on the order. please try again later")
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package order
+
+import (
+	"testing"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/primer"
+	couponRepository "github.com/funmi4194/ecommerce/repository/coupon"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/uptrace/bun"
+)
+
+func TestCouponDiscount(t *testing.T) {
+
+	now := time.Now()
+
+	// two units at 25.00 and one at 10.00, the second product is sold in variants
+	invoice := []orderRepository.Item{
+		{Key: "product-1", Name: "Mug", Amount: 2500, Quantity: 2},
+		{Key: "variant-1", Name: "Shirt (L)", Amount: 1000, Quantity: 1, Metadata: primer.Stringify(orderRepository.LineMetadata{ProductID: "product-2"})},
+	}
+
+	coupon := func(c couponRepository.Coupon) couponRepository.Coupon {
+		c.Code, c.Active = "SAVE", true
+		if c.Currency == "" {
+			c.Currency = enum.NGN
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		coupon   couponRepository.Coupon
+		currency enum.Currency
+		used     int
+		discount int64
+		err      string
+	}{
+		{"a percentage of the subtotal", coupon(couponRepository.Coupon{Type: enum.Percentage, Percent: 10}), enum.NGN, 0, 600, ""},
+		{"a percentage rounded half away from zero", coupon(couponRepository.Coupon{Type: enum.Percentage, Percent: 12.5}), enum.NGN, 0, 750, ""},
+		{"a percentage of an order in another currency", coupon(couponRepository.Coupon{Type: enum.Percentage, Percent: 50, Currency: enum.USD}), enum.NGN, 0, 3000, ""},
+		{"a fixed amount", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 1500}), enum.NGN, 0, 1500, ""},
+		{"a fixed amount above the subtotal", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 10000}), enum.NGN, 0, 6000, ""},
+		{"a free unit", coupon(couponRepository.Coupon{Type: enum.FreeItem, Units: 1, ProductID: "product-1"}), enum.NGN, 0, 2500, ""},
+		{"more free units than were ordered", coupon(couponRepository.Coupon{Type: enum.FreeItem, Units: 5, ProductID: "product-1"}), enum.NGN, 0, 5000, ""},
+		{"a free unit of a variant", coupon(couponRepository.Coupon{Type: enum.FreeItem, Units: 1, ProductID: "product-2"}), enum.NGN, 0, 1000, ""},
+		{"a free unit of a product not ordered", coupon(couponRepository.Coupon{Type: enum.FreeItem, Units: 1, ProductID: "product-3"}), enum.NGN, 0, 0, "coupon SAVE only applies to orders containing the product it gives away"},
+		{"a minimum spend that is met", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 500, MinSpend: 6000}), enum.NGN, 0, 500, ""},
+		{"a minimum spend that is not met", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 500, MinSpend: 6001}), enum.NGN, 0, 0, "you need to spend at least NGN 60.01 to use coupon SAVE"},
+		{"a fixed amount in another currency", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 500, Currency: enum.USD}), enum.NGN, 0, 0, "coupon SAVE can only be used on orders paid in USD"},
+		{"an inactive coupon", couponRepository.Coupon{Code: "SAVE", Type: enum.FixedAmount, Amount: 500, Currency: enum.NGN}, enum.NGN, 0, 0, "coupon SAVE is not active or has expired"},
+		{"an expired coupon", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 500, EndsAt: bun.NullTime{Time: now.Add(-time.Hour)}}), enum.NGN, 0, 0, "coupon SAVE is not active or has expired"},
+		{"a coupon used up", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 500, MaxUses: 3, Uses: 3}), enum.NGN, 0, 0, "coupon SAVE has reached its usage limit"},
+		{"a coupon the user used up", coupon(couponRepository.Coupon{Type: enum.FixedAmount, Amount: 500, MaxUsesPerUser: 1}), enum.NGN, 1, 0, "you have already used coupon SAVE the maximum number of times"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := orderRepository.Order{UserID: "user-1", Currency: tt.currency, Invoice: invoice}
+
+			line, err := couponDiscount(&tt.coupon, &order, tt.used, now)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if line.Amount != -tt.discount || line.Quantity != 1 || line.Line() != enum.DiscountLine {
+				t.Fatalf("expected a discount line of %d, got %+v", -tt.discount, line)
+			}
+		})
+	}
+}
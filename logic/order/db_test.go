@@ -0,0 +1,99 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// fakeTable is what the fake database answers to a query reading from the table
+type fakeTable struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeConn is a database/sql connection answering every query with the rows of the table it reads from and keeping every statement it executes
+type fakeConn struct {
+	tables map[string]fakeTable
+	execs  []string
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.execs = append(c.execs, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	for name, table := range c.tables {
+		if strings.Contains(query, "FROM "+name+" ") {
+			return &fakeRows{table: table}, nil
+		}
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	table fakeTable
+	next  int
+}
+
+func (r *fakeRows) Columns() []string { return r.table.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.table.rows) {
+		return io.EOF
+	}
+	copy(dest, r.table.rows[r.next])
+	r.next++
+	return nil
+}
+
+type fakeConnector struct {
+	conn *fakeConn
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+// useFakeDB points the database package at a fake database holding an admin and the given orders for the duration of the test
+func useFakeDB(t *testing.T, orders [][]driver.Value) *fakeConn {
+	t.Helper()
+
+	previous := database.PostgreSQLDB
+	t.Cleanup(func() {
+		database.PostgreSQLDB = previous
+	})
+
+	conn := &fakeConn{tables: map[string]fakeTable{
+		"users": {
+			columns: []string{"id", "role"},
+			rows:    [][]driver.Value{{"admin-1", string(enum.Admin)}},
+		},
+		"orders": {
+			columns: []string{"id", "user_id", "status", "reference", "paid", "history", "invoice", "amount", "currency", "created_at"},
+			rows:    orders,
+		},
+	}}
+	database.PostgreSQLDB = bun.NewDB(sql.OpenDB(&fakeConnector{conn: conn}), pgdialect.New())
+
+	return conn
+}
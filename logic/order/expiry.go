@@ -0,0 +1,93 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/primer"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+/*
+ExpireOrders is the logic function to cancel unpaid pending orders created more than "ttl" ago and release their stock,
+where orders are processed in batches of primer.ExpiryBatch, each in its own transaction, and rows locked by another instance are skipped
+
+It returns the number of orders expired and an error if any
+*/
+func ExpireOrders(ttl time.Duration) (int, error) {
+	expired := 0
+	for {
+		n, err := expireBatch(time.Now().Add(-ttl), ttl)
+		expired += n
+		if err != nil {
+			return expired, err
+		}
+		if n < primer.ExpiryBatch {
+			return expired, nil
+		}
+	}
+}
+
+// expireBatch cancels a single batch of unpaid pending orders created before the cutoff
+func expireBatch(cutoff time.Time, ttl time.Duration) (int, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return 0, err
+	}
+	defer btx.Rollback()
+
+	orders := make(orderRepository.Orders, 0)
+
+	// find stale orders and lock
+	err = orders.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"status":    enum.Pending,
+					"paid":      false,
+					"failed":    false,
+					"cancelled": false,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+			{
+				Map: map[string]interface{}{
+					"created_at": cutoff,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.LessThan,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, primer.ExpiryBatch, true)
+	if err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.expireBatch] [orders.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		return 0, errors.New("we're having issues expiring pending orders")
+	}
+
+	for i := range orders {
+		if err := transition(btx, &orders[i], enum.Cancelled, primer.SystemActor, fmt.Sprintf("Order expired after %s without payment", ttl), map[string]interface{}{
+			"failed":    true,
+			"failed_at": "now()",
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.expireBatch] [btx.Commit()] %s`, err.Error())
+		return 0, errors.New("we're having issues expiring pending orders")
+	}
+
+	return len(orders), nil
+}
@@ -0,0 +1,257 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// exportFlushEvery is how many rows are written before they are pushed out to the client
+const exportFlushEvery = 100
+
+// ExportSink is where an export is written to once it has been checked, open is given the content type and file name of the export
+// and flush is called every few rows so that the client receives them as they are read
+type ExportSink struct {
+	Open  func(contentType, filename string) io.Writer
+	Flush func()
+}
+
+// exportLine is an invoice line of an order as written to a per line export
+type exportLine struct {
+	OrderID   string           `json:"order_id"`
+	Reference string           `json:"reference"`
+	UserID    string           `json:"user_id"`
+	Status    enum.OrderStatus `json:"status"`
+	Paid      bool             `json:"paid"`
+	Currency  enum.Currency    `json:"currency"`
+	CreatedAt time.Time        `json:"created_at"`
+
+	Key      string           `json:"key"`
+	Type     enum.InvoiceLine `json:"type"`
+	Name     string           `json:"name"`
+	Quantity int              `json:"quantity"`
+	Amount   int64            `json:"amount"`
+	Total    int64            `json:"total"`
+}
+
+var orderColumns = []string{"id", "reference", "user_id", "status", "paid", "paid_at", "cancelled", "cancelled_at", "failed", "failed_at", "currency", "amount", "coupon_code", "lines", "created_at", "updated_at"}
+
+var lineColumns = []string{"order_id", "reference", "user_id", "status", "paid", "currency", "created_at", "key", "type", "name", "quantity", "amount", "total"}
+
+// stamp formats a time for a CSV cell, leaving it empty when the time was never set
+func stamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+/*
+ExportOrders is the logic function for an admin to export every order matching the same filters as Orders, as CSV or NDJSON
+with a row per order or per invoice line (amounts are in the smallest unit of the order's currency)
+
+Orders are written to the sink as they are read from the database so the export is never held in memory, which also means errors
+after the sink has been opened can no longer be reported to the client (opened tells the caller whether that happened)
+*/
+func ExportOrders(ctx context.Context, userId string, payload types.ExportOrders, sink ExportSink) (opened bool, err error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.ExportOrders] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return false, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return false, errors.New("we're having issues exporting orders. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return false, errors.New("you do not have the permission to access this feature")
+	}
+
+	format := enum.ExportFormat(strings.ToUpper(payload.Format.String()))
+	if format == "" {
+		format = enum.CSV
+	}
+	if !format.IsValid() {
+		return false, errors.New("orders can only be exported as CSV or NDJSON")
+	}
+
+	rows := enum.ExportRow(strings.ToUpper(payload.Rows.String()))
+	if rows == "" {
+		rows = enum.OrderRow
+	}
+	if !rows.IsValid() {
+		return false, errors.New("orders can only be exported with a row per ORDER or per LINE")
+	}
+
+	// the file is named after the time of the export and what a row is
+	filename := fmt.Sprintf("orders-%s-%s.%s", strings.ToLower(rows.String()), time.Now().UTC().Format("20060102T150405Z"), strings.ToLower(format.String()))
+	contentType := "text/csv; charset=utf-8"
+	if format == enum.NDJSON {
+		contentType = "application/x-ndjson"
+	}
+
+	var write func(v interface{}, record []string) error
+	var flush func() error
+
+	// the sink is only opened once the first order has been read, so that an order that cannot be read is still reported to the client
+	open := func() error {
+		w := sink.Open(contentType, filename)
+		opened = true
+
+		if format == enum.CSV {
+			cw := csv.NewWriter(w)
+			columns := orderColumns
+			if rows == enum.LineRow {
+				columns = lineColumns
+			}
+			write = func(_ interface{}, record []string) error {
+				return cw.Write(record)
+			}
+			flush = func() error {
+				cw.Flush()
+				return cw.Error()
+			}
+			return cw.Write(columns)
+		}
+
+		encoder := json.NewEncoder(w)
+		write = func(v interface{}, _ []string) error {
+			return encoder.Encode(v)
+		}
+		flush = func() error {
+			return nil
+		}
+		return nil
+	}
+
+	written := 0
+	push := func() error {
+		written++
+		if written%exportFlushEvery != 0 {
+			return nil
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		sink.Flush()
+		return nil
+	}
+
+	orders := make(orderRepository.Orders, 0)
+
+	// walk through the matching orders
+	err = orders.EachByMap(ctx, types.SQLMaps{
+		WMaps:         orderFilter(&user, payload.OrderFilter),
+		WJoinOperator: enum.And,
+	}, func(order *orderRepository.Order) error {
+
+		if !opened {
+			if err := open(); err != nil {
+				return err
+			}
+		}
+
+		if rows == enum.OrderRow {
+			if err := write(order, []string{
+				order.ID,
+				order.Reference,
+				order.UserID,
+				order.Status.String(),
+				strconv.FormatBool(order.Paid),
+				stamp(order.PaidAt.Time),
+				strconv.FormatBool(order.Cancelled),
+				stamp(order.CancelledAt.Time),
+				strconv.FormatBool(order.Failed),
+				stamp(order.FailedAt.Time),
+				order.Currency.String(),
+				strconv.FormatInt(order.Amount, 10),
+				order.CouponCode,
+				strconv.Itoa(len(order.Invoice)),
+				stamp(order.CreatedAt.Time),
+				stamp(order.UpdatedAt.Time),
+			}); err != nil {
+				return err
+			}
+			return push()
+		}
+
+		for _, item := range order.Invoice {
+			line := exportLine{
+				OrderID:   order.ID,
+				Reference: order.Reference,
+				UserID:    order.UserID,
+				Status:    order.Status,
+				Paid:      order.Paid,
+				Currency:  order.Currency,
+				CreatedAt: order.CreatedAt.Time,
+				Key:       item.Key,
+				Type:      item.Line(),
+				Name:      item.Name,
+				Quantity:  item.Quantity,
+				Amount:    item.Amount,
+				Total:     item.Amount * int64(item.Quantity),
+			}
+			if err := write(line, []string{
+				line.OrderID,
+				line.Reference,
+				line.UserID,
+				line.Status.String(),
+				strconv.FormatBool(line.Paid),
+				line.Currency.String(),
+				stamp(line.CreatedAt),
+				line.Key,
+				line.Type.String(),
+				line.Name,
+				strconv.Itoa(line.Quantity),
+				strconv.FormatInt(line.Amount, 10),
+				strconv.FormatInt(line.Total, 10),
+			}); err != nil {
+				return err
+			}
+			if err := push(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !opened {
+			barf.Logger().Errorf(`[order.ExportOrders] [orders.EachByMap(ctx, types.SQLMaps{] %s`, err.Error())
+			return false, errors.New("we're having issues exporting orders. please try again later")
+		}
+		return true, err
+	}
+
+	// an export matching no orders is still a file (with just the header for CSV)
+	if !opened {
+		if err := open(); err != nil {
+			return true, err
+		}
+	}
+
+	if err := flush(); err != nil {
+		return true, err
+	}
+	sink.Flush()
+
+	return true, nil
+}
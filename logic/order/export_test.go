@@ -0,0 +1,117 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+)
+
+// exported runs an export into memory and returns what was written and whether the sink was opened
+func exported(t *testing.T, payload types.ExportOrders) (string, bool, bool, error) {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	sinkOpened := false
+	opened, err := ExportOrders(context.Background(), "admin-1", payload, ExportSink{
+		Open: func(contentType, filename string) io.Writer {
+			sinkOpened = true
+			return &buffer
+		},
+		Flush: func() {},
+	})
+	return buffer.String(), opened, sinkOpened, err
+}
+
+func TestExportOrdersWithHistoryAndInvoice(t *testing.T) {
+
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	history := []byte(`[{"act": "Initiated product(s) purchase", "by": "user-1", "at": "2026-01-02T03:04:05Z"}]`)
+	invoice := []byte(`[{"key": "product-1", "name": "Shirt", "amount": 2500, "quantity": 2, "metadata": ""}, {"key": "tax", "name": "VAT", "amount": 375, "quantity": 1, "metadata": "{\"type\": \"TAX\"}"}]`)
+
+	useFakeDB(t, [][]driver.Value{
+		{"order-1", "user-1", string(enum.Pending), "REF-1", false, history, invoice, int64(5375), "NGN", createdAt},
+	})
+
+	t.Run("a csv row per order", func(t *testing.T) {
+		out, opened, sinkOpened, err := exported(t, types.ExportOrders{})
+		if err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		if !opened || !sinkOpened {
+			t.Fatalf("expected the sink to be opened")
+		}
+
+		records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		if err != nil {
+			t.Fatalf("export is not valid csv: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("expected a header and 1 order, got %d records", len(records))
+		}
+
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = records[1][i]
+		}
+		if row["id"] != "order-1" || row["amount"] != "5375" || row["lines"] != "2" || row["created_at"] != "2026-01-02T03:04:05Z" {
+			t.Fatalf("unexpected order row: %v", row)
+		}
+	})
+
+	t.Run("an ndjson row per invoice line", func(t *testing.T) {
+		out, _, _, err := exported(t, types.ExportOrders{Format: enum.NDJSON, Rows: enum.LineRow})
+		if err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 invoice lines, got %d", len(lines))
+		}
+
+		var line exportLine
+		if err := json.Unmarshal([]byte(lines[1]), &line); err != nil {
+			t.Fatalf("line is not valid json: %v", err)
+		}
+		if line.OrderID != "order-1" || line.Key != "tax" || line.Type != enum.TaxLine || line.Total != 375 {
+			t.Fatalf("unexpected line: %+v", line)
+		}
+	})
+}
+
+func TestExportOrdersDoesNotOpenTheSinkWhenTheFirstOrderCannotBeRead(t *testing.T) {
+
+	useFakeDB(t, [][]driver.Value{
+		{"order-1", "user-1", string(enum.Pending), "REF-1", false, []byte(`[]`), []byte(`not json`), int64(0), "NGN", time.Now()},
+	})
+
+	out, opened, sinkOpened, err := exported(t, types.ExportOrders{})
+	if err == nil {
+		t.Fatalf("expected the export to fail")
+	}
+	if opened || sinkOpened || out != "" {
+		t.Fatalf("expected nothing to be sent to the client, got opened=%v sink=%v output=%q", opened, sinkOpened, out)
+	}
+}
+
+func TestExportOrdersWithNoOrdersIsJustTheHeader(t *testing.T) {
+
+	useFakeDB(t, nil)
+
+	out, opened, _, err := exported(t, types.ExportOrders{})
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if !opened || strings.TrimSpace(out) != strings.Join(orderColumns, ",") {
+		t.Fatalf("expected only the header, got %q", out)
+	}
+}
@@ -0,0 +1,40 @@
+package order
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+)
+
+func TestOrderFilterByOrderAndProduct(t *testing.T) {
+
+	admin := &userRepository.User{ID: "admin-1", Role: enum.Admin}
+
+	query, args := database.MapsToWQuery(types.SQLMaps{
+		WMaps: orderFilter(admin, types.OrderFilter{
+			OrderId:   "order-1",
+			ProductId: "product-1' OR '1' = '1",
+		}),
+		WJoinOperator: enum.And,
+	})
+
+	if !strings.Contains(query, "id = ?") || !strings.Contains(query, "orders.id IN (SELECT order_items.order_id FROM order_items WHERE order_items.product_id = ?)") {
+		t.Fatalf("expected both the order and the product to be filtered on, got %s", query)
+	}
+
+	if strings.Contains(query, "product-1") {
+		t.Fatalf("expected the product id to be bound, got %s", query)
+	}
+
+	found := map[interface{}]bool{}
+	for _, arg := range args {
+		found[arg] = true
+	}
+	if len(args) != 2 || !found["order-1"] || !found["product-1' OR '1' = '1"] {
+		t.Fatalf("unexpected arguments: %v", args)
+	}
+}
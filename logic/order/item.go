@@ -0,0 +1,329 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	orderItemRepository "github.com/funmi4194/ecommerce/repository/orderitem"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// recordItems writes a row for every product line of the new order's invoice using the provided transaction
+func recordItems(tx *bun.Tx, order *orderRepository.Order) error {
+
+	rows := []types.SQLMap{}
+	for _, line := range order.ProductLines() {
+		item := orderItemRepository.Item{
+			ID:        helper.GenerateUUID(),
+			OrderID:   order.ID,
+			ProductID: line.ProductID(),
+			VariantID: line.VariantID(),
+			Name:      line.Name,
+			Price:     line.Amount,
+			Quantity:  line.Quantity,
+			Currency:  order.Currency,
+			Status:    enum.ItemOpen,
+			CreatedAt: order.CreatedAt,
+			UpdatedAt: order.UpdatedAt,
+		}
+		rows = append(rows, types.SQLMap{
+			Map: map[string]interface{}{
+				"id":         item.ID,
+				"order_id":   item.OrderID,
+				"product_id": item.ProductID,
+				"variant_id": item.VariantID,
+				"name":       item.Name,
+				"price":      item.Price,
+				"quantity":   item.Quantity,
+				"currency":   item.Currency,
+				"status":     item.Status,
+				"created_at": item.CreatedAt,
+				"updated_at": item.UpdatedAt,
+			},
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var item orderItemRepository.Item
+	return item.CreateTx(tx, types.SQLMaps{
+		IMaps: rows,
+	})
+}
+
+// cancelItems moves every line of the order that has not been fulfilled to CANCELLED using the provided transaction
+func cancelItems(tx *bun.Tx, orderId string) error {
+	var item orderItemRepository.Item
+	return item.UByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"order_id": orderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+			{
+				Map: map[string]interface{}{
+					"status": []interface{}{enum.ItemOpen, enum.ItemBackordered},
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":     enum.ItemCancelled,
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	})
+}
+
+// UpdateOrderItem is the logic function for an admin to move a line of an order to another status e.g when it is backordered
+func UpdateOrderItem(userId string, payload types.UpdateOrderItem) (*orderItemRepository.Item, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	if payload.ItemId == "" {
+		return nil, errors.New("item id is required")
+	}
+
+	status := enum.ItemStatus(strings.ToUpper(payload.Status.String()))
+	if !status.IsValid() {
+		return nil, errors.New("status must be one of OPEN, BACKORDERED, FULFILLED or CANCELLED")
+	}
+
+	filter := types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.ItemId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}
+
+	items := make(orderItemRepository.Items, 0)
+
+	// find order item
+	if err := items.FByMap(filter, 1, 0); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [items.FByMap(filter, 1, 0)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+	if len(items) == 0 {
+		return nil, errors.New("order item not found")
+	}
+
+	var order orderRepository.Order
+
+	// find order and lock (the order is always locked before its items)
+	if err := order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": items[0].OrderID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true); err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+
+	if order.Status == enum.Cancelled || order.Status == enum.Rejected {
+		return nil, fmt.Errorf("%w: the lines of a %s order cannot be moved", ErrIllegalTransition, strings.ToLower(order.Status.String()))
+	}
+
+	var item orderItemRepository.Item
+
+	// find order item and lock
+	if err := item.FUByMap(btx, filter); err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [item.FUByMap(btx, filter)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+
+	if item.Status == enum.ItemCancelled {
+		return nil, fmt.Errorf("%w: a cancelled order item cannot be moved", ErrIllegalTransition)
+	}
+
+	if item.Status == status {
+		return &item, nil
+	}
+
+	act := fmt.Sprintf("Moved '%s' from %s to %s", item.Name, item.Status, status)
+	if note := strings.TrimSpace(payload.Note); note != "" {
+		act += ": " + note
+	}
+
+	// update order item
+	if err := item.UByMapTx(btx, types.SQLMaps{
+		WMaps: filter.WMaps,
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":     status,
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [item.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+
+	// record the move on the order
+	if err := recordOnOrder(btx, item.OrderID, act, user.ID); err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [recordOnOrder(btx, item.OrderID, act, user.ID)] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.UpdateOrderItem] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the order item. please try again later")
+	}
+
+	return &item, nil
+}
+
+// OrderItems is the logic function to list the lines of orders, users only see the lines of their own orders while admins can look across orders e.g by product
+func OrderItems(userId string, payload types.OrderItemFilter) (*orderItemRepository.Items, *commonRepository.Pagination, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OrderItems] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, nil, errors.New("we're having issues retrieving order items. please try again later")
+	}
+
+	//  generate filter map
+	Eqfilter := map[string]interface{}{}
+
+	if user.Role != enum.Admin {
+		// users can only look at the lines of one of their orders at a time
+		if _, err := viewableOrder(&user, payload.OrderId); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if payload.OrderId != "" {
+		Eqfilter["order_id"] = payload.OrderId
+	}
+	if payload.ProductId != "" {
+		Eqfilter["product_id"] = payload.ProductId
+	}
+	if payload.Status != "" {
+		Eqfilter["status"] = enum.ItemStatus(strings.ToUpper(payload.Status.String()))
+	}
+
+	limit := primer.PageLimit
+	page := 1
+	offset := 0
+
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+
+	if payload.Page != nil {
+		offset = (*payload.Page - 1) * limit
+		page = *payload.Page
+	}
+
+	queryMap := []types.SQLMap{
+		{
+			Map:                Eqfilter,
+			JoinOperator:       enum.And,
+			ComparisonOperator: enum.Equal,
+		},
+	}
+
+	items := make(orderItemRepository.Items, 0)
+
+	// find order items
+	if err := items.FByMap(types.SQLMaps{
+		WMaps:         queryMap,
+		WJoinOperator: enum.And,
+	}, limit, offset); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.OrderItems] [items.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues retrieving order items. please try again later")
+	}
+
+	var pagination *commonRepository.Pagination
+
+	if payload.Paginate {
+		total, err := items.CByMap(types.SQLMaps{
+			WMaps:         queryMap,
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[order.OrderItems] [items.CByMap(types.SQLMaps{] %s`, err.Error())
+			return nil, nil, errors.New("we're having issues retrieving order items. please try again later")
+		}
+
+		pagination = &commonRepository.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+			Pages: int(math.Ceil(float64(total) / float64(limit))),
+		}
+	}
+
+	return &items, pagination, nil
+}
@@ -5,23 +5,31 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"time"
+	"strings"
 
 	"github.com/funmi4194/ecommerce/enum"
 	"github.com/funmi4194/ecommerce/helper"
 	"github.com/funmi4194/ecommerce/primer"
-	"github.com/funmi4194/ecommerce/primitive"
 	commonRepository "github.com/funmi4194/ecommerce/repository/common"
 	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
 	productRepository "github.com/funmi4194/ecommerce/repository/product"
 	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
 	"github.com/funmi4194/ecommerce/types"
 	"github.com/opensaucerer/barf"
-	"github.com/uptrace/bun/schema"
 )
 
-// InitiateOrder is the logic function to create an order for a user
-func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository.Order, error) {
+/*
+draftOrder builds the order the user is asking for from the checkout payload without saving it, where the invoice holds
+the requested products at their current prices
+
+Products priced in another currency than the order is charged in are converted with the exchange rates, and an order for
+products in different currencies is refused unless the currency to pay in is given
+
+It returns an error if any of the products or the delivery address cannot be used
+*/
+func draftOrder(user *userRepository.User, payload types.InitiateOrder) (*orderRepository.Order, error) {
 
 	if len(payload.Items) == 0 {
 		return nil, errors.New("you need to select at least one product to order")
@@ -31,37 +39,20 @@ func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository
 		return nil, errors.New("you can only purchase up to 100 products at a time")
 	}
 
-	// create a new transaction
-	btx, err := commonRepository.BeginTx()
-	if err != nil {
-		return nil, err
-	}
-	defer btx.Rollback()
-
-	user := userRepository.User{
-		ID: userId,
-	}
-
-	// find user by ID
-	err = user.FByKeyVal("id", user.ID, true)
-	if err != nil {
-		barf.Logger().Errorf(`[order.InitiateOrder] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
-		if err == sql.ErrNoRows {
-			return nil, errors.New("looks like your account no longer exists. please contact support")
-		}
-		return nil, errors.New("we're having issues initiating order. please try again later")
-	}
-
 	// verify the items exist
 	itemIds := []interface{}{}
+	seen := map[string]bool{}
 	for _, item := range payload.Items {
-		if item.ProductId != "" {
+		if item.ProductId != "" && !seen[item.ProductId] {
+			seen[item.ProductId] = true
 			itemIds = append(itemIds, item.ProductId)
 		}
 	}
 
 	products := make(productRepository.Products, 0)
-	o := orderRepository.Order{}
+	o := orderRepository.Order{
+		UserID: user.ID,
+	}
 
 	// get all products
 	if err := products.FByMap(types.SQLMaps{
@@ -98,31 +89,167 @@ func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository
 		o.ProductID = products[0].ID
 	}
 
+	// resolve the currency the order is charged in
+	o.Currency = payload.Currency
+	if o.Currency == "" {
+		for _, product := range products {
+			if o.Currency == "" {
+				o.Currency = product.Currency
+			} else if product.Currency != o.Currency {
+				return nil, errors.New("the selected products are priced in different currencies. please choose a currency to pay in")
+			}
+		}
+	}
+
+	if !o.Currency.IsValid() {
+		return nil, fmt.Errorf("we do not accept payments in %s", o.Currency)
+	}
+
+	rates := make(pricingRepository.Rates, 0)
 	for _, product := range products {
+		if product.Currency != o.Currency {
 
-		for _, item := range payload.Items {
-			if item.ProductId == product.ID {
+			// find exchange rates
+			if err := rates.FByMap(types.SQLMaps{}); err != nil && err != sql.ErrNoRows {
+				barf.Logger().Errorf(`[order.draftOrder] [rates.FByMap(types.SQLMaps{})] %s`, err.Error())
+				return nil, errors.New("we're having issues checking out those products. please try again later")
+			}
+			break
+		}
+	}
 
-				if item.Quantity <= 0 {
-					return nil, errors.New("item quantity is required")
-				}
+	variants := make(variantRepository.Variants, 0)
 
-				if item.Quantity > int(product.Stock) {
-					return nil, fmt.Errorf("requested quantity for product '%s' exceeds available stock", product.Name)
-				}
+	// find the variants of the products
+	if err := variants.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": itemIds,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.draftOrder] [variants.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues checking out those products. please try again later")
+	}
+
+	found := map[string]productRepository.Product{}
+	for _, product := range products {
+		found[product.ID] = product
+	}
+
+	lines := map[string]bool{}
+	for _, item := range payload.Items {
+
+		product, ok := found[item.ProductId]
+		if !ok {
+			continue
+		}
+
+		if item.Quantity <= 0 {
+			return nil, errors.New("item quantity is required")
+		}
+
+		line := orderRepository.Item{
+			Key:      product.ID,
+			Name:     product.Name,
+			Amount:   product.Price,
+			Quantity: item.Quantity,
+		}
+		price, stock := product.Price, product.Stock
+		metadata := orderRepository.LineMetadata{}
+
+		// products sold in variants are ordered by variant, each with its own price and stock
+		variant, err := VariantOf(product, variants, item.VariantId)
+		if err != nil {
+			return nil, err
+		}
+		if variant != nil {
+			line.Key = variant.ID
+			line.Name = fmt.Sprintf("%s (%s)", product.Name, variant.Options.Label())
+			line.Amount = variant.Price
+			price, stock = variant.Price, variant.Stock
+			metadata = orderRepository.LineMetadata{
+				Type:      enum.ProductLine,
+				ProductID: product.ID,
+				SKU:       variant.SKU,
+			}
+		}
+
+		if lines[line.Key] {
+			return nil, fmt.Errorf("product '%s' was selected more than once", line.Name)
+		}
+		lines[line.Key] = true
+
+		if item.Quantity > int(stock) {
+			return nil, fmt.Errorf("requested quantity for product '%s' exceeds available stock", line.Name)
+		}
 
-				o.Invoice = append(o.Invoice, orderRepository.Item{
-					Key:      product.ID,
-					Name:     product.Name,
-					Amount:   product.Price,
-					Quantity: item.Quantity,
-				})
+		if product.Currency != o.Currency {
+			amount, ok := rates.Convert(price, product.Currency, o.Currency)
+			if !ok {
+				return nil, fmt.Errorf("product '%s' cannot be paid for in %s at the moment", product.Name, o.Currency)
 			}
+			line.Amount = amount
+			metadata.Type = enum.ProductLine
+			metadata.Price = price
+			metadata.Currency = product.Currency
 		}
+
+		if metadata.Type != "" {
+			line.Metadata = primer.Stringify(metadata)
+		}
+
+		o.Invoice = append(o.Invoice, line)
+	}
+
+	// resolve where the order is delivered to
+	address, err := shippingAddress(user.ID, payload.AddressId, payload.Address)
+	if err != nil {
+		return nil, err
+	}
+	o.ShippingAddress = address
+
+	o.CouponCode = strings.ToUpper(strings.TrimSpace(payload.CouponCode))
+
+	return &o, nil
+}
+
+// InitiateOrder is the logic function to create an order for a user
+func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository.Order, error) {
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.InitiateOrder] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues initiating order. please try again later")
+	}
+
+	o, err := draftOrder(&user, payload)
+	if err != nil {
+		return nil, err
 	}
 
 	// compute checksum - this helps prevent duplicate invoice for a tx without paramter changes
-	o.Checksum = primer.StringSha256(primer.Stringify(o.Invoice))
+	o.Checksum = primer.StringSha256(primer.Stringify(o.Invoice) + primer.Stringify(o.ShippingAddress) + o.CouponCode + o.Currency.String())
 
 	order := orderRepository.Order{}
 
@@ -131,6 +258,7 @@ func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository
 		WMaps: []types.SQLMap{
 			{
 				Map: map[string]interface{}{
+					"user_id":   user.ID,
 					"paid":      false,
 					"failed":    false,
 					"cancelled": false,
@@ -151,12 +279,6 @@ func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository
 		}
 	}
 
-	// compute total amount
-	o.Amount = 0
-	for _, item := range o.Invoice {
-		o.Amount += math.Ceil((item.Amount*float64(item.Quantity))*100) / 100
-	}
-
 	o.UserID = user.ID
 	o.ID = helper.GenerateUUID()
 	o.Status = enum.Pending
@@ -171,29 +293,52 @@ func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository
 	}
 	o.Remark = "Product(s) purchase"
 
+	// lock and decrement stock so that concurrent checkouts cannot oversell the same units
+	if err := reserveStock(btx, o); err != nil {
+		return nil, err
+	}
+
+	// apply the coupon (product rows are always locked before the coupon row)
+	if o.CouponCode != "" {
+		discount, err := applyCoupon(btx, o)
+		if err != nil {
+			return nil, err
+		}
+		o.Invoice = append(o.Invoice, *discount)
+	}
+
+	// add taxes and fees and compute the total amount
+	if err := price(o); err != nil {
+		return nil, err
+	}
+
 	// create order
 	if err := o.CreateTx(btx, types.SQLMaps{
 		IMaps: []types.SQLMap{
 			{
 				Map: map[string]interface{}{
-					"id":           o.ID,
-					"user_id":      o.UserID,
-					"status":       o.Status,
-					"reference":    o.Reference,
-					"paid":         o.Paid,
-					"paid_at":      o.PaidAt,
-					"cancelled":    o.Cancelled,
-					"cancelled_at": o.CancelledAt,
-					"failed":       o.Failed,
-					"failed_at":    o.FailedAt,
-					"checksum":     o.Checksum,
-					"history":      o.History,
-					"invoice":      o.Invoice,
-					"amount":       o.Amount,
-					"remark":       o.Remark,
-					"product_id":   o.ProductID,
-					"created_at":   o.CreatedAt,
-					"updated_at":   o.UpdatedAt,
+					"id":               o.ID,
+					"user_id":          o.UserID,
+					"status":           o.Status,
+					"reference":        o.Reference,
+					"paid":             o.Paid,
+					"paid_at":          o.PaidAt,
+					"cancelled":        o.Cancelled,
+					"cancelled_at":     o.CancelledAt,
+					"failed":           o.Failed,
+					"failed_at":        o.FailedAt,
+					"checksum":         o.Checksum,
+					"history":          o.History,
+					"invoice":          o.Invoice,
+					"amount":           o.Amount,
+					"remark":           o.Remark,
+					"product_id":       o.ProductID,
+					"created_at":       o.CreatedAt,
+					"updated_at":       o.UpdatedAt,
+					"stock_released":   o.StockReleased,
+					"shipping_address": o.ShippingAddress,
+					"coupon_code":      o.CouponCode,
+					"currency":         o.Currency,
 				},
 			},
 		},
@@ -201,13 +346,19 @@ func InitiateOrder(userId string, payload types.InitiateOrder) (*orderRepository
 		return nil, err
 	}
 
+	// keep a row for every product on the order so that orders can be found by product
+	if err := recordItems(btx, o); err != nil {
+		barf.Logger().Errorf(`[order.InitiateOrder] [recordItems(btx, o)] %s`, err.Error())
+		return nil, errors.New("we're having issues initiating order. please try again later")
+	}
+
 	// commit transaction
 	if err := btx.Commit(); err != nil {
 		barf.Logger().Errorf(`[creation.Checkout] [btx.Commit()] %s`, err.Error())
 		return nil, errors.New("we're having issues initiating order. please try again later")
 	}
 
-	return &o, nil
+	return o, nil
 }
 
 // CancelOrder is the logic function to cancel order
@@ -273,52 +424,13 @@ func CancelOrder(userId string, payload types.CancelOrder) (*orderRepository.Ord
 			return nil, errors.New("order cannot be cancelled")
 		}
 
-		// update order
-		err = order.UByMapTx(btx, types.SQLMaps{
-			WMaps: []types.SQLMap{
-				{
-					Map: map[string]interface{}{
-						"id":      order.ID,
-						"user_id": user.ID,
-					},
-					JoinOperator:       enum.And,
-					ComparisonOperator: enum.Equal,
-				},
-			},
-
-			SMap: types.SQLMap{
-				Map: map[string]interface{}{
-					"cancelled":    true,
-					"cancelled_at": "now()",
-					"remark":       "Order cancelled",
-					"status":       enum.Cancelled.String(),
-					"history": enum.SQLValueMerge{
-						Operator: enum.CONCAT,
-						Values: primitive.Array{
-							commonRepository.History{
-								Act: "Cancelled order",
-								By:  user.ID,
-								At:  schema.NullTime{Time: time.Now()},
-							},
-						},
-					},
-					"updated_at": "now()", // update updated_at
-				},
-				JoinOperator:       enum.Comma,
-				ComparisonOperator: enum.Equal,
-			},
-
-			RMap: types.SQLMap{
-				Map: map[string]interface{}{"*": nil},
-			},
+		// the money is in, so only an admin can take the order back through a refund
+		if order.Paid {
+			return nil, errors.New("order has already been paid for. please contact support to have it refunded")
+		}
 
-			WJoinOperator: enum.And,
-		})
-		if err != nil {
-			barf.Logger().Errorf(`[order.CancelOrder] [order.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
-			if err == sql.ErrNoRows {
-				return nil, errors.New("order not found")
-			}
+		// move order to cancelled
+		if err := transition(btx, &order, enum.Cancelled, user.ID, "Order cancelled"); err != nil {
 			return nil, err
 		}
 	}
@@ -386,47 +498,16 @@ func UpdateOrder(userId string, payload types.UpdateOrder) (*orderRepository.Ord
 		return nil, errors.New("we're having issues updating the order. please try again later")
 	}
 
-	// if order has been cnacelled be a user admin should not be able to update order
-	if order.Cancelled {
-		return nil, errors.New("order has already been cancelled")
+	if payload.Status == "" {
+		return nil, errors.New("order status is required")
 	}
 
-	if payload.Status != enum.Completed && payload.Status != enum.Approved && payload.Status != enum.Rejected && payload.Status != enum.Cancelled {
-		return nil, errors.New("order can either be completed, approved, rejected or cancelled")
+	if payload.Status == enum.PartiallyRefunded || payload.Status == enum.Refunded {
+		return nil, errors.New("orders can only be refunded through the refund endpoint")
 	}
 
-	// update order
-	err = order.UByMapTx(btx, types.SQLMaps{
-		WMaps: []types.SQLMap{
-			{
-				Map: map[string]interface{}{
-					"id": order.ID,
-				},
-				JoinOperator:       enum.And,
-				ComparisonOperator: enum.Equal,
-			},
-		},
-
-		SMap: types.SQLMap{
-			Map: map[string]interface{}{
-				"status":     payload.Status,
-				"updated_at": "now()", // update updated_at
-			},
-			JoinOperator:       enum.Comma,
-			ComparisonOperator: enum.Equal,
-		},
-
-		RMap: types.SQLMap{
-			Map: map[string]interface{}{"*": nil},
-		},
-
-		WJoinOperator: enum.And,
-	})
-	if err != nil {
-		barf.Logger().Errorf(`[order.UpdateOrder] [order.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
-		if err == sql.ErrNoRows {
-			return nil, err
-		}
+	// move order to the requested status
+	if err := transition(btx, &order, payload.Status, user.ID, ""); err != nil {
 		return nil, err
 	}
 
@@ -456,12 +537,70 @@ func Orders(userId string, payload types.OrderFilter) (*orderRepository.Orders,
 
 	orders := make(orderRepository.Orders, 0)
 
+	limit := primer.PageLimit
+	page := 1
+	offset := 0
+
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+
+	if payload.Page != nil {
+		offset = (*payload.Page - 1) * limit
+		page = *payload.Page
+	}
+
+	queryMap := orderFilter(&user, payload)
+
+	err = orders.FByMap(types.SQLMaps{
+		WMaps:         queryMap,
+		WJoinOperator: enum.And,
+	}, limit, offset, enum.DESC.String(), true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Orders] [orders.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("orders not found")
+		}
+		return nil, nil, errors.New("we're having issues retrieving orders. please try again later")
+	}
+
+	var pagination *commonRepository.Pagination
+
+	if payload.Paginate {
+		total, err := orders.CByMap(types.SQLMaps{
+			WMaps:         queryMap,
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[order.Orders] [orders.CByMap(types.SQLMaps{] %s`, err.Error())
+			if err == sql.ErrNoRows {
+				return nil, nil, errors.New("orders not found")
+			}
+			return nil, nil, errors.New("we're having issues retrieving orders. please try again later")
+		}
+
+		pagination = &commonRepository.Pagination{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+			Pages: int(math.Ceil(float64(total) / float64(limit))),
+		}
+	}
+
+	return &orders, pagination, nil
+}
+
+// orderFilter turns the filters of an order listing into where clauses, users other than admins only ever match their own orders
+func orderFilter(user *userRepository.User, payload types.OrderFilter) []types.SQLMap {
+
 	//  generate filter map
 	Eqfilter := map[string]interface{}{}
 
 	// verify user is an admin
 	if user.Role != enum.Admin {
 		Eqfilter["user_id"] = user.ID
+	} else if payload.UserId != "" {
+		Eqfilter["user_id"] = payload.UserId
 	}
 
 	if payload.Paid != nil {
@@ -479,6 +618,18 @@ func Orders(userId string, payload types.OrderFilter) (*orderRepository.Orders,
 	if payload.Reference != "" {
 		Eqfilter["reference"] = payload.Reference
 	}
+	if payload.Currency != "" {
+		Eqfilter["currency"] = payload.Currency
+	}
+
+	// kept apart from the order id so that both conditions apply
+	if payload.ProductId != "" {
+		Eqfilter["orders.id"] = enum.SQLAlmostRaw{
+			Operator: enum.In,
+			Value:    "(SELECT order_items.order_id FROM order_items WHERE order_items.product_id = ?)",
+			Args:     []interface{}{payload.ProductId},
+		}
+	}
 
 	gtEqFilter := map[string]interface{}{}
 	ltEqFilter := map[string]interface{}{}
@@ -496,20 +647,7 @@ func Orders(userId string, payload types.OrderFilter) (*orderRepository.Orders,
 		ltEqFilter["created_at"] = payload.EndDate
 	}
 
-	limit := primer.PageLimit
-	page := 1
-	offset := 0
-
-	if payload.Limit != nil {
-		limit = *payload.Limit
-	}
-
-	if payload.Page != nil {
-		offset = (*payload.Page - 1) * limit
-		page = *payload.Page
-	}
-
-	queryMap := []types.SQLMap{
+	return []types.SQLMap{
 		{
 			Map:                Eqfilter,
 			JoinOperator:       enum.And,
@@ -526,41 +664,4 @@ func Orders(userId string, payload types.OrderFilter) (*orderRepository.Orders,
 			ComparisonOperator: enum.LessThanOrEqual,
 		},
 	}
-
-	err = orders.FByMap(types.SQLMaps{
-		WMaps:         queryMap,
-		WJoinOperator: enum.And,
-	}, limit, offset, enum.DESC.String(), true)
-	if err != nil {
-		barf.Logger().Errorf(`[order.Orders] [orders.FByMap(types.SQLMaps{] %s`, err.Error())
-		if err == sql.ErrNoRows {
-			return nil, nil, errors.New("orders not found")
-		}
-		return nil, nil, errors.New("we're having issues retrieving orders. please try again later")
-	}
-
-	var pagination *commonRepository.Pagination
-
-	if payload.Paginate {
-		total, err := orders.CByMap(types.SQLMaps{
-			WMaps:         queryMap,
-			WJoinOperator: enum.And,
-		})
-		if err != nil {
-			barf.Logger().Errorf(`[order.Orders] [orders.CByMap(types.SQLMaps{] %s`, err.Error())
-			if err == sql.ErrNoRows {
-				return nil, nil, errors.New("orders not found")
-			}
-			return nil, nil, errors.New("we're having issues retrieving orders. please try again later")
-		}
-
-		pagination = &commonRepository.Pagination{
-			Page:  page,
-			Limit: limit,
-			Total: total,
-			Pages: int(math.Ceil(float64(total) / float64(limit))),
-		}
-	}
-
-	return &orders, pagination, nil
 }
@@ -0,0 +1,277 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/payment"
+	"github.com/funmi4194/ecommerce/primer"
+	"github.com/funmi4194/ecommerce/primitive"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun/schema"
+)
+
+// InitializePayment is the logic function to start the payment of a pending order with the configured gateway
+func InitializePayment(userId string, payload types.InitializePayment) (*payment.Authorization, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.InitializePayment] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues initializing payment. please try again later")
+	}
+
+	if payload.OrderId == "" {
+		return nil, errors.New("order id is required")
+	}
+
+	var order orderRepository.Order
+
+	// find order
+	err = order.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":      payload.OrderId,
+					"user_id": user.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.InitializePayment] [order.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, errors.New("we're having issues initializing payment. please try again later")
+	}
+
+	if order.Paid {
+		return nil, errors.New("order has already been paid for")
+	}
+
+	if order.Cancelled || order.Failed || order.Status != enum.Pending {
+		return nil, errors.New("payment can only be made for a pending order")
+	}
+
+	authorization, err := payment.PaymentGateway.Initialize(payment.Charge{
+		Reference:   order.Reference,
+		Email:       user.Email,
+		Amount:      order.Amount,
+		Currency:    order.Currency.String(),
+		CallbackURL: primer.ENV.PaymentCallbackURL,
+		Metadata: map[string]string{
+			"order_id": order.ID,
+			"user_id":  user.ID,
+		},
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[order.InitializePayment] [payment.PaymentGateway.Initialize(payment.Charge{] %s`, err.Error())
+		return nil, errors.New("we're having issues initializing payment. please try again later")
+	}
+
+	return authorization, nil
+}
+
+/*
+PaymentWebhook is the logic function to apply a payment notification sent by the gateway to the order sharing its reference
+
+It returns payment.ErrInvalidSignature if the notification was not signed by the gateway, notifications for unknown references and repeated notifications are acknowledged without changes.
+A confirmed charge for an order that has already failed, been cancelled or been rejected (e.g by the expiry worker) is still recorded
+as paid and flagged in the order's remark and history for an admin to refund, as the order no longer holds its stock or coupon
+*/
+func PaymentWebhook(body []byte, signature string) error {
+
+	event, err := payment.PaymentGateway.ParseWebhook(body, signature)
+	if err != nil {
+		barf.Logger().Errorf(`[order.PaymentWebhook] [payment.PaymentGateway.ParseWebhook(body, signature)] %s`, err.Error())
+		if err == payment.ErrInvalidSignature {
+			return err
+		}
+		return errors.New("we could not process the payment notification")
+	}
+
+	// refunds are settled by the order's refunds, not by notifications about them
+	if event.Refund || (event.Status != enum.PaymentSuccess && event.Status != enum.PaymentFailed) {
+		return nil
+	}
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	var order orderRepository.Order
+
+	// find order and lock
+	err = order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"reference": event.Reference,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			barf.Logger().Warnf(`[order.PaymentWebhook] no order found for reference %s`, event.Reference)
+			return nil
+		}
+		barf.Logger().Errorf(`[order.PaymentWebhook] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues processing the payment notification. please try again later")
+	}
+
+	// a paid order is never moved again by a notification
+	if order.Paid {
+		return nil
+	}
+
+	// the order has already given its units and coupon back
+	settled := order.Failed || order.Cancelled || order.Status == enum.Cancelled || order.Status == enum.Rejected
+	if settled && event.Status == enum.PaymentFailed {
+		return nil
+	}
+
+	update := map[string]interface{}{
+		"updated_at": "now()",
+	}
+
+	switch event.Status {
+	case enum.PaymentSuccess:
+
+		// never trust the notification alone - confirm the charge with the gateway
+		transaction, err := payment.PaymentGateway.Verify(order.Reference)
+		if err != nil {
+			barf.Logger().Errorf(`[order.PaymentWebhook] [payment.PaymentGateway.Verify(order.Reference)] %s`, err.Error())
+			return errors.New("we're having issues verifying the payment. please try again later")
+		}
+
+		if transaction.Status != enum.PaymentSuccess {
+			return fmt.Errorf("payment for order %s has not been confirmed by %s", order.ID, payment.PaymentGateway.Name())
+		}
+
+		if !strings.EqualFold(transaction.Currency, order.Currency.String()) {
+			return fmt.Errorf("payment for order %s was made in %s but the order is charged in %s", order.ID, transaction.Currency, order.Currency)
+		}
+
+		if transaction.Amount < order.Amount {
+			return fmt.Errorf("payment of %s for order %s is less than the order amount of %s", order.Currency.Format(transaction.Amount), order.ID, order.Currency.Format(order.Amount))
+		}
+
+		update["paid"] = true
+		update["paid_at"] = "now()"
+
+		// the customer has been charged for an order that will not be fulfilled, so an admin has to give the money back
+		if settled {
+			closed := "was " + strings.ToLower(order.Status.String())
+			if order.Failed {
+				closed = "failed"
+			}
+			barf.Logger().Warnf(`[order.PaymentWebhook] order %s was paid after it %s and must be refunded`, order.ID, closed)
+			update["remark"] = "Paid after the order " + closed + " - refund required"
+			update["history"] = historyEntry(fmt.Sprintf("Payment of %s confirmed by %s after the order %s, the payment must be refunded", order.Currency.Format(transaction.Amount), payment.PaymentGateway.Name(), closed), payment.PaymentGateway.Name().String())
+			break
+		}
+
+		update["history"] = enum.SQLValueMerge{
+			Operator: enum.CONCAT,
+			Values: primitive.Array{
+				commonRepository.History{
+					Act: fmt.Sprintf("Payment confirmed by %s", payment.PaymentGateway.Name()),
+					By:  payment.PaymentGateway.Name().String(),
+					At:  schema.NullTime{Time: time.Now()},
+				},
+			},
+		}
+
+	case enum.PaymentFailed:
+
+		update["failed"] = true
+		update["failed_at"] = "now()"
+		update["remark"] = "Payment failed"
+		update["history"] = enum.SQLValueMerge{
+			Operator: enum.CONCAT,
+			Values: primitive.Array{
+				commonRepository.History{
+					Act: fmt.Sprintf("Payment failed at %s", payment.PaymentGateway.Name()),
+					By:  payment.PaymentGateway.Name().String(),
+					At:  schema.NullTime{Time: time.Now()},
+				},
+			},
+		}
+
+		// return the reserved units to stock unless that already happened on cancellation or rejection
+		if holdsStock(&order) {
+			if err := releaseStock(btx, order.Invoice, inventoryRepository.Movement{
+				Kind:    enum.Cancellation,
+				OrderID: order.ID,
+				ActorID: payment.PaymentGateway.Name().String(),
+				Reason:  "Payment failed",
+			}); err != nil {
+				return err
+			}
+			update["stock_released"] = true
+		}
+
+		// give the coupon use back
+		if err := releaseCoupon(btx, &order); err != nil {
+			return err
+		}
+	}
+
+	// update order
+	err = order.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map:                update,
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[order.PaymentWebhook] [order.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues processing the payment notification. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.PaymentWebhook] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues processing the payment notification. please try again later")
+	}
+
+	return nil
+}
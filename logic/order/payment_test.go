@@ -0,0 +1,112 @@
+package order
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/payment"
+)
+
+// useFakeGateway points the payment package at a fake gateway holding a charge of the given amount for the duration of the test
+func useFakeGateway(t *testing.T, reference string, amount int64, currency string) *payment.FakeGateway {
+	t.Helper()
+
+	previous := payment.PaymentGateway
+	t.Cleanup(func() {
+		payment.PaymentGateway = previous
+	})
+
+	gateway := payment.NewFakeGateway("sk_test_secret")
+	if _, err := gateway.Initialize(payment.Charge{Reference: reference, Amount: amount, Currency: currency}); err != nil {
+		t.Fatalf("could not initialize charge: %v", err)
+	}
+	payment.PaymentGateway = gateway
+
+	return gateway
+}
+
+func TestPaymentWebhook(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		amount   int64
+		currency string
+		err      string
+	}{
+		{"the order amount in the order currency", 5000, "NGN", ""},
+		{"the currency code in another case", 5000, "ngn", ""},
+		{"more than the order amount", 6000, "NGN", ""},
+		{"less than the order amount", 4999, "NGN", "payment of NGN 49.99 for order order-1 is less than the order amount of NGN 50.00"},
+		{"another currency", 5000, "USD", "payment for order order-1 was made in USD but the order is charged in NGN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := useFakeDB(t, [][]driver.Value{
+				{"order-1", "user-1", string(enum.Pending), "REF-1", false, []byte(`[]`), []byte(`[]`), int64(5000), "NGN", time.Now()},
+			})
+			gateway := useFakeGateway(t, "REF-1", tt.amount, tt.currency)
+
+			body, signature, err := gateway.Settle("REF-1", enum.PaymentSuccess)
+			if err != nil {
+				t.Fatalf("could not settle charge: %v", err)
+			}
+
+			err = PaymentWebhook(body, signature)
+
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(conn.execs) != 1 || !strings.Contains(conn.execs[0], "paid") {
+					t.Fatalf("expected the order to be marked as paid, got %q", conn.execs)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.err {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			if len(conn.execs) != 0 {
+				t.Fatalf("expected the order to be left alone, got %q", conn.execs)
+			}
+		})
+	}
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+
+	conn := useFakeDB(t, [][]driver.Value{
+		{"order-1", "user-1", string(enum.Pending), "REF-1", false, []byte(`[]`), []byte(`[]`), int64(5000), "NGN", time.Now()},
+	})
+	gateway := useFakeGateway(t, "REF-1", 5000, "NGN")
+
+	body, signature, err := gateway.Settle("REF-1", enum.PaymentSuccess)
+	if err != nil {
+		t.Fatalf("could not settle charge: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+	}{
+		{"no signature", body, ""},
+		{"a signature of another body", []byte(strings.Replace(string(body), "5000", "500000", 1)), signature},
+		{"a signature made with another secret", body, strings.Repeat("0", len(signature))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := PaymentWebhook(tt.body, tt.signature); err != payment.ErrInvalidSignature {
+				t.Fatalf("expected %v, got %v", payment.ErrInvalidSignature, err)
+			}
+			if len(conn.execs) != 0 {
+				t.Fatalf("expected the order to be left alone, got %q", conn.execs)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	categoryRepository "github.com/funmi4194/ecommerce/repository/category"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// percentage returns the given percentage of an amount, rounded half away from zero to the smallest unit of its currency
+func percentage(amount int64, rate float64) int64 {
+	return int64(math.Round(float64(amount) * rate / 100))
+}
+
+/*
+charges returns the tax and fee lines the given rules add to an invoice in the given currency, where taxes come before fees
+and rules apply in the order they were created
+
+Percentage rules are charged on the product subtotal less any discount, so fees are never taxed and taxes never attract fees.
+A rule limited to a category only applies when the invoice has products in it (the ids of the invoice's products in each
+category or below it are given in "categories") and a percentage rule then charges on those products less their share of
+the discount
+*/
+func charges(items []orderRepository.Item, currency enum.Currency, address *orderRepository.Address, rules pricingRepository.Rules, categories map[string]map[string]bool) []orderRepository.Item {
+
+	subtotal, base := int64(0), int64(0)
+	for _, item := range items {
+		if item.IsProduct() {
+			subtotal += item.Amount * int64(item.Quantity)
+		}
+		if item.IsProduct() || item.Line() == enum.DiscountLine {
+			base += item.Amount * int64(item.Quantity)
+		}
+	}
+
+	country, state := "", ""
+	if address != nil {
+		country, state = address.Country, address.State
+	}
+
+	lines := []orderRepository.Item{}
+	for _, kind := range []enum.PricingRule{enum.Tax, enum.Fee} {
+		for _, rule := range rules {
+			if rule.Kind != kind || !rule.Active || !rule.Matches(currency, country, state) {
+				continue
+			}
+
+			metadata := orderRepository.LineMetadata{
+				Type:   enum.TaxLine,
+				RuleID: rule.ID,
+				Basis:  rule.Basis,
+			}
+			if kind == enum.Fee {
+				metadata.Type = enum.FeeLine
+			}
+
+			ruleBase := base
+			if rule.CategoryID != "" {
+				scoped := int64(0)
+				for _, item := range items {
+					if item.IsProduct() && categories[rule.CategoryID][item.ProductID()] {
+						scoped += item.Amount * int64(item.Quantity)
+					}
+				}
+				if scoped <= 0 {
+					continue
+				}
+				ruleBase = int64(math.Round(float64(base) * float64(scoped) / float64(subtotal)))
+			}
+
+			amount := rule.Amount
+			if rule.Basis == enum.Rate {
+				amount = percentage(ruleBase, rule.Rate)
+				metadata.Rate, metadata.Base = rule.Rate, ruleBase
+			}
+
+			if amount <= 0 {
+				continue
+			}
+
+			lines = append(lines, orderRepository.Item{
+				Key:      rule.ID,
+				Name:     rule.Name,
+				Amount:   amount,
+				Quantity: 1,
+				Metadata: primer.Stringify(metadata),
+			})
+		}
+	}
+
+	return lines
+}
+
+// ruleCategories returns the ids of the invoice's products in each category (or below it) that the rules are limited to
+func ruleCategories(items []orderRepository.Item, rules pricingRepository.Rules) (map[string]map[string]bool, error) {
+
+	productIds := []interface{}{}
+	for _, item := range items {
+		if item.IsProduct() {
+			productIds = append(productIds, item.ProductID())
+		}
+	}
+
+	categories := map[string]map[string]bool{}
+	if len(productIds) == 0 {
+		return categories, nil
+	}
+
+	for _, rule := range rules {
+		if rule.CategoryID == "" {
+			continue
+		}
+		if _, ok := categories[rule.CategoryID]; ok {
+			continue
+		}
+
+		links := make(categoryRepository.Links, 0)
+
+		// find the invoice's products in the category or below it (the category id was read back from the database)
+		if err := links.FByMap(types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"product_id": productIds,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.In,
+				},
+				{
+					Map: map[string]interface{}{
+						"category_id": enum.SQLAlmostRaw{
+							Operator: enum.In,
+							Value:    categoryRepository.Subtree(rule.CategoryID),
+						},
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		}); err != nil && err != sql.ErrNoRows {
+			barf.Logger().Errorf(`[order.ruleCategories] [links.FByMap(types.SQLMaps{] %s`, err.Error())
+			return nil, errors.New("we're having issues pricing your order. please try again later")
+		}
+
+		categories[rule.CategoryID] = map[string]bool{}
+		for _, link := range links {
+			categories[rule.CategoryID][link.ProductID] = true
+		}
+	}
+
+	return categories, nil
+}
+
+/*
+price completes the invoice of the draft order, which already carries the discount line of its coupon if any, with the active tax
+and fee rules, then computes the order's total amount
+*/
+func price(o *orderRepository.Order) error {
+
+	rules := make(pricingRepository.Rules, 0)
+
+	// find active rules
+	if err := rules.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"active": true,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.price] [rules.FByMap(types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues pricing your order. please try again later")
+	}
+
+	categories, err := ruleCategories(o.Invoice, rules)
+	if err != nil {
+		return err
+	}
+
+	o.Invoice = append(o.Invoice, charges(o.Invoice, o.Currency, o.ShippingAddress, rules, categories)...)
+
+	// compute total amount
+	o.Amount = 0
+	for _, item := range o.Invoice {
+		o.Amount += item.Amount * int64(item.Quantity)
+	}
+
+	return nil
+}
+
+// Quote is the priced invoice of an order that has not been created
+type Quote struct {
+	Invoice         []orderRepository.Item   `json:"invoice"`
+	ShippingAddress *orderRepository.Address `json:"shipping_address"`
+	CouponCode      string                   `json:"coupon_code"`
+	Currency        enum.Currency            `json:"currency"`
+
+	// the invoice totals by kind of line (in the smallest unit of the currency)
+	Subtotal int64 `json:"subtotal"`
+	Discount int64 `json:"discount"`
+	Tax      int64 `json:"tax"`
+	Fees     int64 `json:"fees"`
+
+	// what the order would cost
+	Amount int64 `json:"amount"`
+}
+
+// PriceOrder is the logic function to price an order exactly as InitiateOrder would without creating it, holding any stock or using its coupon
+func PriceOrder(userId string, payload types.InitiateOrder) (*Quote, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.PriceOrder] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues pricing your order. please try again later")
+	}
+
+	o, err := draftOrder(&user, payload)
+	if err != nil {
+		return nil, err
+	}
+	o.ID = helper.GenerateUUID()
+
+	// the coupon is only checked, its use is recorded at checkout
+	if o.CouponCode != "" {
+		discount, err := quoteCoupon(o)
+		if err != nil {
+			return nil, err
+		}
+		o.Invoice = append(o.Invoice, *discount)
+	}
+
+	if err := price(o); err != nil {
+		return nil, err
+	}
+
+	quote := Quote{
+		Invoice:         o.Invoice,
+		ShippingAddress: o.ShippingAddress,
+		CouponCode:      o.CouponCode,
+		Currency:        o.Currency,
+		Amount:          o.Amount,
+	}
+
+	for _, item := range o.Invoice {
+		amount := item.Amount * int64(item.Quantity)
+		switch item.Line() {
+		case enum.ProductLine:
+			quote.Subtotal += amount
+		case enum.DiscountLine:
+			quote.Discount -= amount
+		case enum.TaxLine:
+			quote.Tax += amount
+		case enum.FeeLine:
+			quote.Fees += amount
+		}
+	}
+
+	return &quote, nil
+}
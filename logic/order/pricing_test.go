@@ -0,0 +1,100 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/primer"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
+)
+
+func TestCharges(t *testing.T) {
+
+	// a subtotal of 60.00 less a discount of 10.00
+	items := []orderRepository.Item{
+		{Key: "product-1", Name: "Mug", Amount: 2500, Quantity: 2},
+		{Key: "product-2", Name: "Shirt", Amount: 1000, Quantity: 1},
+		{Key: "SAVE", Name: "Coupon SAVE", Amount: -1000, Quantity: 1, Metadata: primer.Stringify(orderRepository.LineMetadata{Type: enum.DiscountLine})},
+	}
+
+	lagos := &orderRepository.Address{Country: "NG", State: "Lagos"}
+
+	tests := []struct {
+		name       string
+		rule       pricingRepository.Rule
+		currency   enum.Currency
+		address    *orderRepository.Address
+		categories map[string]map[string]bool
+		amount     int64
+	}{
+		{"a percentage of the subtotal less the discount", pricingRepository.Rule{Active: true, Kind: enum.Tax, Basis: enum.Rate, Rate: 7.5}, enum.NGN, nil, nil, 375},
+		{"a percentage rounded half away from zero", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Rate, Rate: 0.25}, enum.NGN, nil, nil, 13},
+		{"a percentage in any currency", pricingRepository.Rule{Active: true, Kind: enum.Tax, Basis: enum.Rate, Rate: 10, Currency: enum.NGN}, enum.USD, nil, nil, 500},
+		{"a flat fee", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Flat, Amount: 1500, Currency: enum.NGN}, enum.NGN, nil, nil, 1500},
+		{"a flat fee in another currency", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Flat, Amount: 1500, Currency: enum.USD}, enum.NGN, nil, nil, 0},
+		{"a flat fee for the delivery state", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Flat, Amount: 800, Currency: enum.NGN, Country: "ng", State: "lagos"}, enum.NGN, lagos, nil, 800},
+		{"a flat fee for another state", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Flat, Amount: 800, Currency: enum.NGN, Country: "NG", State: "Abuja"}, enum.NGN, lagos, nil, 0},
+		{"a flat fee for a region without an address", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Flat, Amount: 800, Currency: enum.NGN, Country: "NG"}, enum.NGN, nil, nil, 0},
+		{"a percentage of the products in a category", pricingRepository.Rule{Active: true, Kind: enum.Tax, Basis: enum.Rate, Rate: 10, CategoryID: "mugs"}, enum.NGN, nil, map[string]map[string]bool{"mugs": {"product-1": true}}, 417},
+		{"a flat fee for a category", pricingRepository.Rule{Active: true, Kind: enum.Fee, Basis: enum.Flat, Amount: 300, Currency: enum.NGN, CategoryID: "mugs"}, enum.NGN, nil, map[string]map[string]bool{"mugs": {"product-1": true}}, 300},
+		{"a rule for a category not on the invoice", pricingRepository.Rule{Active: true, Kind: enum.Tax, Basis: enum.Rate, Rate: 10, CategoryID: "books"}, enum.NGN, nil, map[string]map[string]bool{"books": {}}, 0},
+		{"an inactive rule", pricingRepository.Rule{Kind: enum.Tax, Basis: enum.Rate, Rate: 10}, enum.NGN, nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.ID, tt.rule.Name = "rule-1", "Charge"
+
+			lines := charges(items, tt.currency, tt.address, pricingRepository.Rules{tt.rule}, tt.categories)
+
+			if tt.amount == 0 {
+				if len(lines) != 0 {
+					t.Fatalf("expected no charge, got %+v", lines)
+				}
+				return
+			}
+			if len(lines) != 1 || lines[0].Amount != tt.amount || lines[0].Quantity != 1 {
+				t.Fatalf("expected a charge of %d, got %+v", tt.amount, lines)
+			}
+
+			want := enum.TaxLine
+			if tt.rule.Kind == enum.Fee {
+				want = enum.FeeLine
+			}
+			if lines[0].Line() != want {
+				t.Fatalf("expected a %s line, got %s", want, lines[0].Line())
+			}
+		})
+	}
+}
+
+func TestChargesOrder(t *testing.T) {
+
+	items := []orderRepository.Item{
+		{Key: "product-1", Name: "Mug", Amount: 10000, Quantity: 1},
+	}
+
+	rules := pricingRepository.Rules{
+		{ID: "delivery", Name: "Delivery", Kind: enum.Fee, Basis: enum.Flat, Amount: 2000, Currency: enum.NGN, Active: true},
+		{ID: "vat", Name: "VAT", Kind: enum.Tax, Basis: enum.Rate, Rate: 7.5, Active: true},
+		{ID: "service", Name: "Service", Kind: enum.Fee, Basis: enum.Rate, Rate: 1, Active: true},
+	}
+
+	lines := charges(items, enum.NGN, nil, rules, nil)
+
+	// taxes come first and neither taxes nor fees are charged on one another
+	want := []struct {
+		key    string
+		amount int64
+	}{{"vat", 750}, {"delivery", 2000}, {"service", 100}}
+
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), lines)
+	}
+	for i, w := range want {
+		if lines[i].Key != w.key || lines[i].Amount != w.amount {
+			t.Fatalf("expected line %d to be %s of %d, got %s of %d", i, w.key, w.amount, lines[i].Key, lines[i].Amount)
+		}
+	}
+}
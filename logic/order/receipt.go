@@ -0,0 +1,84 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/receipt"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// Receipt is the logic function to prepare the receipt of an order for its owner or an admin, along with the format to render it in
+func Receipt(userId string, payload types.Receipt) (*receipt.Document, enum.ReceiptFormat, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Receipt] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, "", errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, "", errors.New("we're having issues preparing the receipt. please try again later")
+	}
+
+	if payload.OrderId == "" {
+		return nil, "", errors.New("order id is required")
+	}
+
+	format := enum.ReceiptFormat(strings.ToUpper(payload.Format.String()))
+	if format == "" {
+		format = enum.PDF
+	}
+	if !format.IsValid() {
+		return nil, "", errors.New("receipts can only be rendered as PDF or HTML")
+	}
+
+	filter := map[string]interface{}{
+		"id": payload.OrderId,
+	}
+	if user.Role != enum.Admin {
+		filter["user_id"] = user.ID
+	}
+
+	var order orderRepository.Order
+
+	// find order
+	err = order.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                filter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Receipt] [order.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, "", errors.New("order not found")
+		}
+		return nil, "", errors.New("we're having issues preparing the receipt. please try again later")
+	}
+
+	// admins are shown the buyer's email not theirs
+	buyer := user
+	if order.UserID != user.ID {
+		buyer = userRepository.User{}
+		if err := buyer.FByKeyVal("id", order.UserID, true); err != nil && err != sql.ErrNoRows {
+			barf.Logger().Errorf(`[order.Receipt] [buyer.FByKeyVal("id", order.UserID, true)] %s`, err.Error())
+			return nil, "", errors.New("we're having issues preparing the receipt. please try again later")
+		}
+	}
+
+	return receipt.New(&order, buyer.Email), format, nil
+}
@@ -0,0 +1,658 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/payment"
+	"github.com/funmi4194/ecommerce/primer"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	refundRepository "github.com/funmi4194/ecommerce/repository/refund"
+	returnRepository "github.com/funmi4194/ecommerce/repository/returns"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// orderRefunds returns the refunds of the given order using the provided transaction
+func orderRefunds(tx *bun.Tx, orderId string) (refundRepository.Refunds, error) {
+	refunds := make(refundRepository.Refunds, 0)
+	if err := refunds.FByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"order_id": orderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// orderReturns returns the returns of the given order using the provided transaction
+func orderReturns(tx *bun.Tx, orderId string) (returnRepository.Returns, error) {
+	returns := make(returnRepository.Returns, 0)
+	if err := returns.FByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"order_id": orderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return returns, nil
+}
+
+// restockedUnits returns how many units of each invoice item have been put back in stock by refunds and received returns using the provided transaction,
+// the units of refunds the provider declined were taken back out of stock and are not counted
+func restockedUnits(tx *bun.Tx, orderId string) (map[string]int, error) {
+
+	refunds, err := orderRefunds(tx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	returns, err := orderReturns(tx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	restocked := map[string]int{}
+	for _, r := range refunds {
+		if r.Status == enum.PaymentFailed.String() {
+			continue
+		}
+		for _, item := range r.Items {
+			restocked[item.Key] += item.Quantity
+		}
+	}
+	for _, r := range returns {
+		if r.Status == enum.ReturnReceived {
+			for _, item := range r.Items {
+				restocked[item.Key] += item.Quantity
+			}
+		}
+	}
+
+	return restocked, nil
+}
+
+// refundableBalance returns how much of the locked order's amount has not been refunded yet using the provided transaction, refunds the provider declined are not counted
+func refundableBalance(tx *bun.Tx, order *orderRepository.Order) (int64, error) {
+
+	refunds, err := orderRefunds(tx, order.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	balance := order.Amount
+	for _, r := range refunds {
+		if r.Status != enum.PaymentFailed.String() {
+			balance -= r.Amount
+		}
+	}
+
+	return balance, nil
+}
+
+/*
+issueRefund records a PENDING refund of the amount of the locked, paid order using the provided transaction, moving the order to
+PARTIALLY_REFUNDED or REFUNDED and recording the refund (under the given ID) along with the items it returned to stock
+
+No money is moved here, the caller must commit and then hand the refund to sendRefund so that the row exists before the provider is called
+*/
+func issueRefund(tx *bun.Tx, order *orderRepository.Order, refundId, by string, amount int64, reason string, items []orderRepository.Item) (*refundRepository.Refund, error) {
+
+	balance, err := refundableBalance(tx, order)
+	if err != nil {
+		barf.Logger().Errorf(`[order.issueRefund] [refundableBalance(tx, order)] %s`, err.Error())
+		return nil, errors.New("we're having issues refunding the order. please try again later")
+	}
+
+	if balance <= 0 {
+		return nil, errors.New("order has already been fully refunded")
+	}
+
+	if amount <= 0 {
+		return nil, errors.New("refund amount must be greater than zero")
+	}
+
+	if amount > balance {
+		return nil, fmt.Errorf("refund amount cannot exceed the refundable balance of %s", order.Currency.Format(balance))
+	}
+
+	previous := order.Status
+
+	next := enum.PartiallyRefunded
+	if amount == balance {
+		next = enum.Refunded
+	}
+
+	act := fmt.Sprintf("Refunded %s", order.Currency.Format(amount))
+	if reason != "" {
+		act += " - " + reason
+	}
+
+	// move order to the refund status
+	if err := transition(tx, order, next, by, act); err != nil {
+		return nil, err
+	}
+
+	refund := refundRepository.Refund{
+		ID:          refundId,
+		OrderID:     order.ID,
+		Amount:      amount,
+		Reason:      reason,
+		Items:       items,
+		Status:      enum.PaymentPending.String(),
+		CreatedBy:   by,
+		OrderStatus: previous,
+	}
+	refund.Date()
+
+	// create refund
+	if err := refund.CreateTx(tx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":           refund.ID,
+					"order_id":     refund.OrderID,
+					"amount":       refund.Amount,
+					"reason":       refund.Reason,
+					"items":        refund.Items,
+					"provider_id":  refund.ProviderID,
+					"status":       refund.Status,
+					"created_by":   refund.CreatedBy,
+					"created_at":   refund.CreatedAt,
+					"updated_at":   refund.UpdatedAt,
+					"order_status": refund.OrderStatus,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[order.issueRefund] [refund.CreateTx(tx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues refunding the order. please try again later")
+	}
+
+	return &refund, nil
+}
+
+/*
+sendRefund asks the payment provider to pay out a refund recorded by issueRefund once it has been committed, using the refund's ID as the
+idempotency key for the providers that honour one
+
+The refund is updated with the provider's ID and status when the provider accepts it, and handed to declineRefund when the provider
+declines it
+
+It returns an error if the provider declined the refund
+*/
+func sendRefund(order *orderRepository.Order, refund *refundRepository.Refund) error {
+
+	// give the money back
+	providerRefund, err := payment.PaymentGateway.Refund(order.Reference, refund.Amount, refund.ID)
+	if err != nil {
+		barf.Logger().Errorf(`[order.sendRefund] [payment.PaymentGateway.Refund(order.Reference, refund.Amount, refund.ID)] %s`, err.Error())
+		return declineRefund(order, refund)
+	}
+
+	markRefund(order, refund, providerRefund)
+	return nil
+}
+
+// markRefund records the ID and status the payment provider gave a refund, a refund that cannot be updated stays PENDING and is logged
+func markRefund(order *orderRepository.Order, refund *refundRepository.Refund, providerRefund *payment.Refund) {
+
+	// update refund
+	if err := refund.UByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": refund.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"provider_id": providerRefund.ID,
+				"status":      providerRefund.Status.String(),
+				"updated_at":  "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		// the refund stays PENDING and is matched to the provider's refund by RetryRefunds
+		barf.Logger().Errorf(`[order.markRefund] [refund.UByMap(types.SQLMaps{] refund %s of %d for order %s was issued at the provider as %s but not marked: %s`, refund.ID, refund.Amount, order.ID, providerRefund.ID, err.Error())
+		refund.ProviderID = providerRefund.ID
+		refund.Status = providerRefund.Status.String()
+	}
+}
+
+/*
+declineRefund undoes a refund the payment provider declined: the refund is marked FAILED, the units it put back in stock are taken out
+again and the order is moved back to the status it had before the refund, unless it has moved on since
+
+It always returns an error telling the caller the provider could not process the refund
+*/
+func declineRefund(order *orderRepository.Order, refund *refundRepository.Refund) error {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		barf.Logger().Errorf(`[order.declineRefund] [commonRepository.BeginTx()] refund %s for order %s was declined by the provider but not marked: %s`, refund.ID, order.ID, err.Error())
+		return errors.New("the payment provider could not process the refund. please try again later")
+	}
+	defer btx.Rollback()
+
+	// mark refund as failed
+	if err := refund.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": refund.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":     enum.PaymentFailed.String(),
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.declineRefund] [refund.UByMapTx(btx, types.SQLMaps{] refund %s for order %s was declined by the provider but not marked: %s`, refund.ID, order.ID, err.Error())
+		return errors.New("the payment provider could not process the refund. please try again later")
+	}
+
+	refunded := order.Status
+	act := fmt.Sprintf("Refund of %s was declined by the payment provider", order.Currency.Format(refund.Amount))
+
+	// find order and lock
+	if err := order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true); err != nil {
+		barf.Logger().Errorf(`[order.declineRefund] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("the payment provider could not process the refund. please try again later")
+	}
+
+	// the units the refund put back in stock go out again (a negative release)
+	if len(refund.Items) > 0 {
+		items := make([]orderRepository.Item, 0, len(refund.Items))
+		for _, item := range refund.Items {
+			item.Quantity = -item.Quantity
+			items = append(items, item)
+		}
+		if err := releaseStock(btx, items, inventoryRepository.Movement{
+			Kind:    enum.Adjustment,
+			OrderID: order.ID,
+			ActorID: primer.SystemActor,
+			Reason:  act,
+		}); err != nil {
+			barf.Logger().Errorf(`[order.declineRefund] [releaseStock(btx, items, inventoryRepository.Movement{] refund %s for order %s was declined by the provider but not marked: %s`, refund.ID, order.ID, err.Error())
+			return errors.New("the payment provider could not process the refund. please try again later")
+		}
+	}
+
+	update := map[string]interface{}{
+		"updated_at": "now()",
+	}
+
+	// the refund never happened, so the order goes back to where it was
+	if refund.OrderStatus != "" && order.Status == refunded {
+		update["status"] = refund.OrderStatus
+		act += fmt.Sprintf(" - moved order back from %s to %s", refunded, refund.OrderStatus)
+	}
+	update["history"] = historyEntry(act, primer.SystemActor)
+
+	// update order
+	if err := order.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map:                update,
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.declineRefund] [order.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("the payment provider could not process the refund. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.declineRefund] [btx.Commit()] refund %s for order %s was declined by the provider but not marked: %s`, refund.ID, order.ID, err.Error())
+	}
+
+	return errors.New("the payment provider could not process the refund. please try again later")
+}
+
+/*
+RetryRefunds is the logic function to hand refunds that never reached the payment provider (e.g. the server stopped between recording
+a refund and sending it) back to the provider once they have been PENDING without a provider ID for longer than "age"
+
+Each refund is first looked up at the provider under its ID so that one the provider already paid out is only marked. Refunds are claimed in
+batches of primer.RefundRetryBatch by pushing their updated_at forward, so another instance skips them until "age" has passed again
+
+It returns the number of refunds retried and an error if any
+*/
+func RetryRefunds(age time.Duration) (int, error) {
+	retried := 0
+	for {
+		refunds, err := claimRefunds(time.Now().Add(-age))
+		if err != nil {
+			return retried, err
+		}
+
+		for i := range refunds {
+			retryRefund(&refunds[i])
+		}
+		retried += len(refunds)
+
+		if len(refunds) < primer.RefundRetryBatch {
+			return retried, nil
+		}
+	}
+}
+
+// claimRefunds locks a batch of refunds left PENDING without a provider ID since before the cutoff and pushes their updated_at forward
+func claimRefunds(cutoff time.Time) (refundRepository.Refunds, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	refunds := make(refundRepository.Refunds, 0)
+
+	// find stale refunds and lock
+	err = refunds.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"status":      enum.PaymentPending.String(),
+					"provider_id": "",
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+			{
+				Map: map[string]interface{}{
+					"updated_at": cutoff,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.LessThan,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, primer.RefundRetryBatch)
+	if err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.claimRefunds] [refunds.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues retrying pending refunds")
+	}
+
+	for i := range refunds {
+		if err := refunds[i].UByMapTx(btx, types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"id": refunds[i].ID,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			SMap: types.SQLMap{
+				Map: map[string]interface{}{
+					"updated_at": "now()",
+				},
+				JoinOperator:       enum.Comma,
+				ComparisonOperator: enum.Equal,
+			},
+			WJoinOperator: enum.And,
+		}); err != nil {
+			barf.Logger().Errorf(`[order.claimRefunds] [refunds[i].UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+			return nil, errors.New("we're having issues retrying pending refunds")
+		}
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.claimRefunds] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues retrying pending refunds")
+	}
+
+	return refunds, nil
+}
+
+// retryRefund marks a claimed refund the payment provider already has and sends it otherwise, errors are logged as the refund is claimed again later
+func retryRefund(refund *refundRepository.Refund) {
+
+	var order orderRepository.Order
+
+	// find order
+	if err := order.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": refund.OrderID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true); err != nil {
+		barf.Logger().Errorf(`[order.retryRefund] [order.FByMap(types.SQLMaps{] refund %s: %s`, refund.ID, err.Error())
+		return
+	}
+
+	providerRefund, err := payment.PaymentGateway.FindRefund(order.Reference, refund.ID)
+	switch err {
+	case nil:
+		markRefund(&order, refund, providerRefund)
+	case payment.ErrRefundNotFound:
+		if err := sendRefund(&order, refund); err != nil {
+			barf.Logger().Errorf(`[order.retryRefund] [sendRefund(&order, refund)] refund %s: %s`, refund.ID, err.Error())
+		}
+	default:
+		barf.Logger().Errorf(`[order.retryRefund] [payment.PaymentGateway.FindRefund(order.Reference, refund.ID)] refund %s: %s`, refund.ID, err.Error())
+	}
+}
+
+// RefundOrder is the logic function for an admin to refund all or part of a paid order and optionally return its items to stock
+func RefundOrder(userId string, payload types.RefundOrder) (*orderRepository.Order, *refundRepository.Refund, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.RefundOrder] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, nil, errors.New("we're having issues refunding the order. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return nil, nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	if payload.OrderId == "" {
+		return nil, nil, errors.New("order id is required")
+	}
+
+	var order orderRepository.Order
+
+	// find order and lock (this also serializes concurrent refunds of the same order)
+	err = order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.OrderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.RefundOrder] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("order not found")
+		}
+		return nil, nil, errors.New("we're having issues refunding the order. please try again later")
+	}
+
+	if !order.Paid {
+		return nil, nil, errors.New("only paid orders can be refunded")
+	}
+
+	amount, err := refundableBalance(btx, &order)
+	if err != nil {
+		barf.Logger().Errorf(`[order.RefundOrder] [refundableBalance(btx, &order)] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues refunding the order. please try again later")
+	}
+	if payload.Amount != nil {
+		amount = *payload.Amount
+	}
+
+	// validate the items to be returned to stock
+	items := []orderRepository.Item{}
+	if len(payload.Restock) > 0 {
+
+		if !holdsStock(&order) {
+			return nil, nil, errors.New("the items on this order have already been returned to stock")
+		}
+
+		restocked, err := restockedUnits(btx, order.ID)
+		if err != nil {
+			barf.Logger().Errorf(`[order.RefundOrder] [restockedUnits(btx, order.ID)] %s`, err.Error())
+			return nil, nil, errors.New("we're having issues refunding the order. please try again later")
+		}
+
+		for _, r := range payload.Restock {
+			if r.Quantity <= 0 {
+				return nil, nil, errors.New("restock quantity must be greater than zero")
+			}
+
+			var line *orderRepository.Item
+			lines := order.ProductLines()
+			for i := range lines {
+				if lines[i].Key == r.Key {
+					line = &lines[i]
+					break
+				}
+			}
+			if line == nil {
+				return nil, nil, fmt.Errorf("item %s is not on the order", r.Key)
+			}
+
+			if restocked[r.Key]+r.Quantity > line.Quantity {
+				return nil, nil, fmt.Errorf("only %d unit(s) of '%s' can still be returned to stock", line.Quantity-restocked[r.Key], line.Name)
+			}
+			restocked[r.Key] += r.Quantity
+
+			items = append(items, orderRepository.Item{
+				Key:      line.Key,
+				Name:     line.Name,
+				Amount:   line.Amount,
+				Quantity: r.Quantity,
+				Metadata: line.Metadata,
+			})
+		}
+
+		// return the items to stock
+		if err := releaseStock(btx, items, inventoryRepository.Movement{
+			Kind:    enum.Return,
+			OrderID: order.ID,
+			ActorID: user.ID,
+			Reason:  strings.TrimSpace(payload.Reason),
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	refund, err := issueRefund(btx, &order, helper.GenerateUUID(), user.ID, amount, strings.TrimSpace(payload.Reason), items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.RefundOrder] [btx.Commit()] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues refunding the order. please try again later")
+	}
+
+	// give the money back now that the refund is recorded
+	if err := sendRefund(&order, refund); err != nil {
+		return nil, nil, err
+	}
+
+	return &order, refund, nil
+}
@@ -0,0 +1,272 @@
+package order
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// ReorderChange is a product of the previous order that could not be ordered again as it was
+type ReorderChange struct {
+	ProductID string `json:"product_id"`
+	VariantID string `json:"variant_id,omitempty"`
+	Name      string `json:"name"`
+
+	// the units on the previous order and the units on the new one
+	Quantity  int `json:"quantity"`
+	Reordered int `json:"reordered"`
+
+	// the unit price on the previous order and the current one (in the smallest unit of the currency the product is priced in)
+	PreviousPrice    int64         `json:"previous_price,omitempty"`
+	PreviousCurrency enum.Currency `json:"previous_currency,omitempty"`
+	Price            int64         `json:"price,omitempty"`
+	Currency         enum.Currency `json:"currency,omitempty"`
+
+	Reason string `json:"reason"`
+}
+
+// Reordered is the order placed again along with what changed since the previous order
+type Reordered struct {
+	Order *orderRepository.Order `json:"order"`
+
+	// products left out, ordered in fewer units or whose price has changed
+	Dropped  []ReorderChange `json:"dropped"`
+	Reduced  []ReorderChange `json:"reduced"`
+	Repriced []ReorderChange `json:"repriced"`
+}
+
+// listPrice returns the unit price and currency the product on the invoice line was priced at when the order was placed
+func listPrice(order *orderRepository.Order, line orderRepository.Item) (int64, enum.Currency) {
+	var metadata orderRepository.LineMetadata
+	if line.Metadata != "" && json.Unmarshal([]byte(line.Metadata), &metadata) == nil && metadata.Currency != "" {
+		return metadata.Price, metadata.Currency
+	}
+	return line.Amount, order.Currency
+}
+
+/*
+Reorder is the logic function for a user to order the products of one of their previous orders again
+
+Every product is checked against its current status, price and stock: products that can no longer be bought are dropped, products with
+too few units left are ordered in the units left and price changes are reported, before the order is placed through InitiateOrder
+*/
+func Reorder(userId string, payload types.Reorder) (*Reordered, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Reorder] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues placing the order again. please try again later")
+	}
+
+	if payload.OrderId == "" {
+		return nil, errors.New("order id is required")
+	}
+
+	var previous orderRepository.Order
+
+	// find order
+	err = previous.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":      payload.OrderId,
+					"user_id": user.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Reorder] [previous.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, errors.New("we're having issues placing the order again. please try again later")
+	}
+
+	lines := previous.ProductLines()
+	if len(lines) == 0 {
+		return nil, errors.New("there are no products on this order to order again")
+	}
+
+	ids := []interface{}{}
+	for _, line := range lines {
+		ids = append(ids, line.ProductID())
+	}
+
+	products := make(productRepository.Products, 0)
+
+	// find the products on the order whatever their status is now
+	if err := products.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": ids,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, len(ids), 0, enum.DESC.String(), true, true); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.Reorder] [products.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues placing the order again. please try again later")
+	}
+
+	found := map[string]productRepository.Product{}
+	for _, product := range products {
+		found[product.ID] = product
+	}
+
+	variants := make(variantRepository.Variants, 0)
+
+	// find the variants of the products
+	if err := variants.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": ids,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.Reorder] [variants.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues placing the order again. please try again later")
+	}
+
+	result := Reordered{
+		Dropped:  []ReorderChange{},
+		Reduced:  []ReorderChange{},
+		Repriced: []ReorderChange{},
+	}
+
+	items := []types.Item{}
+	for _, line := range lines {
+
+		price, currency := listPrice(&previous, line)
+		change := ReorderChange{
+			ProductID:        line.ProductID(),
+			VariantID:        line.VariantID(),
+			Name:             line.Name,
+			Quantity:         line.Quantity,
+			Reordered:        line.Quantity,
+			PreviousPrice:    price,
+			PreviousCurrency: currency,
+		}
+
+		product, ok := found[line.ProductID()]
+		if !ok || product.Status != enum.Published {
+			change.Reordered = 0
+			change.Reason = "product is no longer available"
+			result.Dropped = append(result.Dropped, change)
+			continue
+		}
+
+		// the line may be for a variant that is gone or the product may have been split into variants since
+		variant, err := VariantOf(product, variants, line.VariantID())
+		if err != nil {
+			change.Reordered = 0
+			change.Reason = "variant is no longer available"
+			if line.VariantID() == "" {
+				change.Reason = "product is now sold in variants. please choose one"
+			}
+			result.Dropped = append(result.Dropped, change)
+			continue
+		}
+
+		change.Name = product.Name
+		change.Price = product.Price
+		change.Currency = product.Currency
+		stock := product.Stock
+
+		if variant != nil {
+			change.Name = fmt.Sprintf("%s (%s)", product.Name, variant.Options.Label())
+			change.Price = variant.Price
+			stock = variant.Stock
+		}
+
+		if stock <= 0 {
+			change.Reordered = 0
+			change.Reason = "product is out of stock"
+			result.Dropped = append(result.Dropped, change)
+			continue
+		}
+
+		if int64(line.Quantity) > stock {
+			change.Reordered = int(stock)
+			change.Reason = fmt.Sprintf("only %d unit(s) are left in stock", stock)
+			result.Reduced = append(result.Reduced, change)
+		}
+
+		if change.Price != price || change.Currency != currency {
+			change.Reason = fmt.Sprintf("price changed from %s to %s", currency.Format(price), change.Currency.Format(change.Price))
+			result.Repriced = append(result.Repriced, change)
+		}
+
+		items = append(items, types.Item{
+			ProductId: product.ID,
+			VariantId: line.VariantID(),
+			Quantity:  change.Reordered,
+		})
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("none of the products on this order can be ordered at the moment")
+	}
+
+	order := types.InitiateOrder{
+		Items:      items,
+		AddressId:  payload.AddressId,
+		Address:    payload.Address,
+		CouponCode: payload.CouponCode,
+		Currency:   payload.Currency,
+	}
+
+	if order.Currency == "" {
+		order.Currency = previous.Currency
+	}
+
+	// deliver where the previous order was delivered unless told otherwise
+	if order.AddressId == "" && order.Address == nil && previous.ShippingAddress != nil {
+		a := previous.ShippingAddress
+		order.Address = &types.Address{
+			Recipient:  a.Recipient,
+			Phone:      a.Phone,
+			Line1:      a.Line1,
+			Line2:      a.Line2,
+			City:       a.City,
+			State:      a.State,
+			PostalCode: a.PostalCode,
+			Country:    a.Country,
+		}
+	}
+
+	result.Order, err = InitiateOrder(user.ID, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,585 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	refundRepository "github.com/funmi4194/ecommerce/repository/refund"
+	returnRepository "github.com/funmi4194/ecommerce/repository/returns"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// returnable reports whether items on the order can be sent back
+func returnable(order *orderRepository.Order) bool {
+	return order.Status == enum.Completed || order.Status == enum.PartiallyRefunded
+}
+
+// recordOnOrder appends the given act to the order's history using the provided transaction
+func recordOnOrder(tx *bun.Tx, orderId, act, by string) error {
+	var order orderRepository.Order
+	return order.UByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": orderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"history":    historyEntry(act, by),
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	})
+}
+
+/*
+OpenReturn is the logic function for a user to ask to send back items of their completed order
+
+Units already taken back through a refund or claimed by another return that was not rejected cannot be returned again
+*/
+func OpenReturn(userId string, payload types.OpenReturn) (*returnRepository.Return, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	if payload.OrderId == "" {
+		return nil, errors.New("order id is required")
+	}
+
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if payload.Reason == "" {
+		return nil, errors.New("please tell us why you are returning the items")
+	}
+
+	if len(payload.Items) == 0 {
+		return nil, errors.New("you need to select at least one item to return")
+	}
+
+	var order orderRepository.Order
+
+	// find order and lock (this also serializes concurrent returns of the same order)
+	err = order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":      payload.OrderId,
+					"user_id": user.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	if !returnable(&order) {
+		return nil, fmt.Errorf("%w: items can only be returned once the order is completed", ErrIllegalTransition)
+	}
+
+	refunds, err := orderRefunds(btx, order.ID)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [orderRefunds(btx, order.ID)] %s`, err.Error())
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	returns, err := orderReturns(btx, order.ID)
+	if err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [orderReturns(btx, order.ID)] %s`, err.Error())
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	// units that are already on their way back or back in stock
+	claimed := map[string]int{}
+	for _, r := range refunds {
+		if r.Status == enum.PaymentFailed.String() {
+			continue
+		}
+		for _, item := range r.Items {
+			claimed[item.Key] += item.Quantity
+		}
+	}
+	for _, r := range returns {
+		if r.Status != enum.ReturnRejected {
+			for _, item := range r.Items {
+				claimed[item.Key] += item.Quantity
+			}
+		}
+	}
+
+	items := []orderRepository.Item{}
+	units := 0
+	for _, r := range payload.Items {
+		if r.Quantity <= 0 {
+			return nil, errors.New("return quantity must be greater than zero")
+		}
+
+		var line *orderRepository.Item
+		lines := order.ProductLines()
+		for i := range lines {
+			if lines[i].Key == r.Key {
+				line = &lines[i]
+				break
+			}
+		}
+		if line == nil {
+			return nil, fmt.Errorf("item %s is not on the order", r.Key)
+		}
+
+		if claimed[r.Key]+r.Quantity > line.Quantity {
+			return nil, fmt.Errorf("only %d unit(s) of '%s' can still be returned", line.Quantity-claimed[r.Key], line.Name)
+		}
+		claimed[r.Key] += r.Quantity
+		units += r.Quantity
+
+		items = append(items, orderRepository.Item{
+			Key:      line.Key,
+			Name:     line.Name,
+			Amount:   line.Amount,
+			Quantity: r.Quantity,
+			Metadata: line.Metadata,
+		})
+	}
+
+	rma := returnRepository.Return{
+		ID:      helper.GenerateUUID(),
+		OrderID: order.ID,
+		UserID:  user.ID,
+		Items:   items,
+		Reason:  payload.Reason,
+		Status:  enum.ReturnRequested,
+	}
+	rma.Date()
+
+	// create return
+	if err := rma.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":          rma.ID,
+					"order_id":    rma.OrderID,
+					"user_id":     rma.UserID,
+					"items":       rma.Items,
+					"reason":      rma.Reason,
+					"status":      rma.Status,
+					"reviewed_by": rma.ReviewedBy,
+					"reviewed_at": rma.ReviewedAt,
+					"note":        rma.Note,
+					"received_at": rma.ReceivedAt,
+					"refund_id":   rma.RefundID,
+					"created_at":  rma.CreatedAt,
+					"updated_at":  rma.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [rma.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	// record the return on the order
+	if err := recordOnOrder(btx, order.ID, fmt.Sprintf("Requested the return of %d unit(s): %s", units, rma.Reason), user.ID); err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [recordOnOrder(btx, order.ID, fmt.Sprintf(...), user.ID)] %s`, err.Error())
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.OpenReturn] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues opening the return. please try again later")
+	}
+
+	return &rma, nil
+}
+
+// ReviewReturn is the logic function for an admin to approve or reject a requested return
+func ReviewReturn(userId string, payload types.ReviewReturn) (*returnRepository.Return, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues reviewing the return. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	if payload.ReturnId == "" {
+		return nil, errors.New("return id is required")
+	}
+
+	payload.Note = strings.TrimSpace(payload.Note)
+	if !payload.Approve && payload.Note == "" {
+		return nil, errors.New("please tell the customer why the return is rejected")
+	}
+
+	var rma returnRepository.Return
+
+	// find return and lock
+	if err := rma.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.ReturnId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [rma.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("return not found")
+		}
+		return nil, errors.New("we're having issues reviewing the return. please try again later")
+	}
+
+	if rma.Status != enum.ReturnRequested {
+		return nil, fmt.Errorf("%w: return has already been %s", ErrIllegalTransition, strings.ToLower(rma.Status.String()))
+	}
+
+	next, act := enum.ReturnApproved, "Approved the return"
+	if !payload.Approve {
+		next, act = enum.ReturnRejected, "Rejected the return"
+	}
+	if payload.Note != "" {
+		act += ": " + payload.Note
+	}
+
+	// update return
+	if err := rma.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": rma.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":      next,
+				"note":        payload.Note,
+				"reviewed_by": user.ID,
+				"reviewed_at": "now()",
+				"updated_at":  "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [rma.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues reviewing the return. please try again later")
+	}
+
+	// record the review on the order
+	if err := recordOnOrder(btx, rma.OrderID, act, user.ID); err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [recordOnOrder(btx, rma.OrderID, act, user.ID)] %s`, err.Error())
+		return nil, errors.New("we're having issues reviewing the return. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.ReviewReturn] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues reviewing the return. please try again later")
+	}
+
+	return &rma, nil
+}
+
+/*
+ReceiveReturn is the logic function for an admin to record the items of an approved return arriving back, which puts them back
+in stock
+
+When asked to, the customer is refunded the value of the returned items (or the given amount) up to what is left to refund on the order
+*/
+func ReceiveReturn(userId string, payload types.ReceiveReturn) (*returnRepository.Return, *refundRepository.Refund, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return nil, nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	if payload.ReturnId == "" {
+		return nil, nil, errors.New("return id is required")
+	}
+
+	filter := types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.ReturnId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}
+
+	var rma returnRepository.Return
+
+	// find return and lock
+	if err := rma.FUByMap(btx, filter); err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [rma.FUByMap(btx, filter)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("return not found")
+		}
+		return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+	}
+
+	var order orderRepository.Order
+
+	// find order and lock
+	err = order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": rma.OrderID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+	}
+
+	if rma.Status != enum.ReturnApproved {
+		return nil, nil, fmt.Errorf("%w: only approved returns can be received", ErrIllegalTransition)
+	}
+
+	if !holdsStock(&order) {
+		return nil, nil, errors.New("the items on this order have already been returned to stock")
+	}
+
+	// put the items back in stock
+	if err := releaseStock(btx, rma.Items, inventoryRepository.Movement{
+		Kind:    enum.Return,
+		OrderID: order.ID,
+		ActorID: user.ID,
+		Reason:  rma.Reason,
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	units := 0
+	value := int64(0)
+	for _, item := range rma.Items {
+		units += item.Quantity
+		value += item.Amount * int64(item.Quantity)
+	}
+
+	refundId := ""
+	amount := int64(0)
+	if payload.Refund {
+
+		if !order.Paid {
+			return nil, nil, errors.New("only paid orders can be refunded")
+		}
+
+		balance, err := refundableBalance(btx, &order)
+		if err != nil {
+			barf.Logger().Errorf(`[order.ReceiveReturn] [refundableBalance(btx, &order)] %s`, err.Error())
+			return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+		}
+
+		// discounts can leave the order worth less than its items
+		amount = value
+		if amount > balance {
+			amount = balance
+		}
+		if payload.Amount != nil {
+			amount = *payload.Amount
+		}
+
+		refundId = helper.GenerateUUID()
+	}
+
+	// update return
+	if err := rma.UByMapTx(btx, types.SQLMaps{
+		WMaps: filter.WMaps,
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":      enum.ReturnReceived,
+				"received_at": "now()",
+				"refund_id":   refundId,
+				"updated_at":  "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [rma.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+	}
+
+	// record the receipt on the order
+	if err := recordOnOrder(btx, order.ID, fmt.Sprintf("Received %d returned unit(s) back in stock", units), user.ID); err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [recordOnOrder(btx, order.ID, fmt.Sprintf(...), user.ID)] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+	}
+
+	// record the refund of the customer
+	var refund *refundRepository.Refund
+	if payload.Refund {
+		// the returned items were put back in stock by the return, so the refund carries none
+		refund, err = issueRefund(btx, &order, refundId, user.ID, amount, fmt.Sprintf("Return of %d unit(s): %s", units, rma.Reason), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.ReceiveReturn] [btx.Commit()] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues receiving the return. please try again later")
+	}
+
+	// give the money back now that the refund is recorded
+	if refund != nil {
+		if err := sendRefund(&order, refund); err != nil {
+			return nil, nil, errors.New("the return was received but the payment provider could not process the refund. please refund the order again later")
+		}
+	}
+
+	return &rma, refund, nil
+}
+
+// Returns is the logic function to list returns, users only see the returns of their own orders while admins see those of any order
+func Returns(userId string, payload types.ListReturns) (*returnRepository.Returns, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Returns] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues retrieving returns. please try again later")
+	}
+
+	filter := map[string]interface{}{}
+	if user.Role != enum.Admin {
+		filter["user_id"] = user.ID
+	}
+	if payload.OrderId != "" {
+		filter["order_id"] = payload.OrderId
+	}
+	if payload.Status != "" {
+		filter["status"] = payload.Status
+	}
+
+	returns := make(returnRepository.Returns, 0)
+
+	// find returns
+	if err := returns.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                filter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.Returns] [returns.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving returns. please try again later")
+	}
+
+	return &returns, nil
+}
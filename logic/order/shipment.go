@@ -0,0 +1,482 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	shipmentRepository "github.com/funmi4194/ecommerce/repository/shipment"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// shippable reports whether items on the order can be dispatched, nothing leaves the warehouse before the order is paid for
+func shippable(order *orderRepository.Order) bool {
+	return order.Paid && (order.Status == enum.Approved || order.Status == enum.Completed || order.Status == enum.PartiallyRefunded)
+}
+
+// orderShipments returns the shipments of the given order using the provided transaction
+func orderShipments(tx *bun.Tx, orderId string) (shipmentRepository.Shipments, error) {
+	shipments := make(shipmentRepository.Shipments, 0)
+	if err := shipments.FByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"order_id": orderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+/*
+CreateShipment is the logic function for an admin to record items of an approved order leaving the warehouse
+
+An order can be shipped in parts by naming the invoice items and quantities in the shipment, when none are named
+everything not yet shipped is included
+*/
+func CreateShipment(userId string, payload types.CreateShipment) (*shipmentRepository.Shipment, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues creating the shipment. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	if payload.OrderId == "" {
+		return nil, errors.New("order id is required")
+	}
+
+	payload.Carrier = strings.TrimSpace(payload.Carrier)
+	payload.TrackingNumber = strings.TrimSpace(payload.TrackingNumber)
+
+	if payload.Carrier == "" {
+		return nil, errors.New("carrier is required")
+	}
+
+	if payload.TrackingNumber == "" {
+		return nil, errors.New("tracking number is required")
+	}
+
+	var order orderRepository.Order
+
+	// find order and lock (this also serializes concurrent shipments of the same order)
+	err = order.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.OrderId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [order.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, errors.New("we're having issues creating the shipment. please try again later")
+	}
+
+	if !order.Paid {
+		return nil, fmt.Errorf("%w: an order cannot be shipped before it is paid for", ErrIllegalTransition)
+	}
+
+	if !shippable(&order) {
+		return nil, fmt.Errorf("%w: a %s order cannot be shipped", ErrIllegalTransition, order.Status)
+	}
+
+	// find prior shipments
+	shipments, err := orderShipments(btx, order.ID)
+	if err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [orderShipments(btx, order.ID)] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the shipment. please try again later")
+	}
+
+	shipped := map[string]int{}
+	for _, s := range shipments {
+		for _, item := range s.Items {
+			shipped[item.Key] += item.Quantity
+		}
+	}
+
+	items := []orderRepository.Item{}
+	if len(payload.Items) == 0 {
+
+		// ship everything that is left
+		for _, line := range order.ProductLines() {
+			if left := line.Quantity - shipped[line.Key]; left > 0 {
+				items = append(items, orderRepository.Item{
+					Key:      line.Key,
+					Name:     line.Name,
+					Amount:   line.Amount,
+					Quantity: left,
+				})
+			}
+		}
+
+		if len(items) == 0 {
+			return nil, errors.New("all the items on this order have already been shipped")
+		}
+	}
+
+	for _, s := range payload.Items {
+		if s.Quantity <= 0 {
+			return nil, errors.New("shipment quantity must be greater than zero")
+		}
+
+		var line *orderRepository.Item
+		lines := order.ProductLines()
+		for i := range lines {
+			if lines[i].Key == s.Key {
+				line = &lines[i]
+				break
+			}
+		}
+		if line == nil {
+			return nil, fmt.Errorf("item %s is not on the order", s.Key)
+		}
+
+		if shipped[s.Key]+s.Quantity > line.Quantity {
+			return nil, fmt.Errorf("only %d unit(s) of '%s' are left to ship", line.Quantity-shipped[s.Key], line.Name)
+		}
+		shipped[s.Key] += s.Quantity
+
+		items = append(items, orderRepository.Item{
+			Key:      line.Key,
+			Name:     line.Name,
+			Amount:   line.Amount,
+			Quantity: s.Quantity,
+		})
+	}
+
+	units := 0
+	for _, item := range items {
+		units += item.Quantity
+	}
+
+	shipment := shipmentRepository.Shipment{
+		ID:             helper.GenerateUUID(),
+		OrderID:        order.ID,
+		Items:          items,
+		Carrier:        payload.Carrier,
+		TrackingNumber: payload.TrackingNumber,
+		Status:         enum.Shipped,
+		CreatedBy:      user.ID,
+	}
+	shipment.Date()
+	shipment.ShippedAt = shipment.CreatedAt
+
+	// create shipment
+	if err := shipment.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":              shipment.ID,
+					"order_id":        shipment.OrderID,
+					"items":           shipment.Items,
+					"carrier":         shipment.Carrier,
+					"tracking_number": shipment.TrackingNumber,
+					"status":          shipment.Status,
+					"shipped_at":      shipment.ShippedAt,
+					"delivered_at":    shipment.DeliveredAt,
+					"created_by":      shipment.CreatedBy,
+					"created_at":      shipment.CreatedAt,
+					"updated_at":      shipment.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [shipment.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the shipment. please try again later")
+	}
+
+	// record the shipment on the order
+	if err := order.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"history":    historyEntry(fmt.Sprintf("Shipped %d unit(s) via %s with tracking number %s", units, shipment.Carrier, shipment.TrackingNumber), user.ID),
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [order.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the shipment. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.CreateShipment] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the shipment. please try again later")
+	}
+
+	return &shipment, nil
+}
+
+// DeliverShipment is the logic function for an admin to record a shipment reaching the customer
+func DeliverShipment(userId string, payload types.DeliverShipment) (*shipmentRepository.Shipment, error) {
+
+	// start a database transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err = user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, errors.New("we're having issues updating the shipment. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return nil, errors.New("you do not have the permission to access this feature")
+	}
+
+	if payload.ShipmentId == "" {
+		return nil, errors.New("shipment id is required")
+	}
+
+	var shipment shipmentRepository.Shipment
+
+	// find shipment and lock
+	if err := shipment.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.ShipmentId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [shipment.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("shipment not found")
+		}
+		return nil, errors.New("we're having issues updating the shipment. please try again later")
+	}
+
+	if shipment.Status != enum.Shipped {
+		return nil, fmt.Errorf("%w: shipment has already been %s", ErrIllegalTransition, strings.ToLower(shipment.Status.String()))
+	}
+
+	// update shipment
+	if err := shipment.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": shipment.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":       enum.Delivered,
+				"delivered_at": "now()",
+				"updated_at":   "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [shipment.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the shipment. please try again later")
+	}
+
+	var order orderRepository.Order
+
+	// record the delivery on the order
+	if err := order.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": shipment.OrderID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"history":    historyEntry(fmt.Sprintf("Delivered shipment with tracking number %s via %s", shipment.TrackingNumber, shipment.Carrier), user.ID),
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [order.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the shipment. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[order.DeliverShipment] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the shipment. please try again later")
+	}
+
+	return &shipment, nil
+}
+
+/*
+Shipments is the logic function to retrieve the shipments of an order along with how much of each invoice item has been shipped and delivered
+
+Users can only view the shipments of their own orders while admins can view those of any order
+*/
+func Shipments(userId string, payload types.ListShipments) (*shipmentRepository.Shipments, []types.ShipmentProgress, error) {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Shipments] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("looks like your account no longer exists. please contact support")
+		}
+		return nil, nil, errors.New("we're having issues retrieving shipments. please try again later")
+	}
+
+	if payload.OrderId == "" {
+		return nil, nil, errors.New("order id is required")
+	}
+
+	filter := map[string]interface{}{
+		"id": payload.OrderId,
+	}
+	if user.Role != enum.Admin {
+		filter["user_id"] = user.ID
+	}
+
+	var order orderRepository.Order
+
+	// find order
+	err = order.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                filter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true)
+	if err != nil {
+		barf.Logger().Errorf(`[order.Shipments] [order.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, nil, errors.New("order not found")
+		}
+		return nil, nil, errors.New("we're having issues retrieving shipments. please try again later")
+	}
+
+	shipments := make(shipmentRepository.Shipments, 0)
+
+	// find shipments
+	if err := shipments.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"order_id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.Shipments] [shipments.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, nil, errors.New("we're having issues retrieving shipments. please try again later")
+	}
+
+	shipped := map[string]int{}
+	delivered := map[string]int{}
+	for _, s := range shipments {
+		for _, item := range s.Items {
+			shipped[item.Key] += item.Quantity
+			if s.Status == enum.Delivered {
+				delivered[item.Key] += item.Quantity
+			}
+		}
+	}
+
+	lines := order.ProductLines()
+	progress := make([]types.ShipmentProgress, 0, len(lines))
+	for _, line := range lines {
+		progress = append(progress, types.ShipmentProgress{
+			Key:       line.Key,
+			Name:      line.Name,
+			Ordered:   line.Quantity,
+			Shipped:   shipped[line.Key],
+			Delivered: delivered[line.Key],
+		})
+	}
+
+	return &shipments, progress, nil
+}
@@ -0,0 +1,172 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/primitive"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// ErrIllegalTransition is returned when an order is asked to move to a status its current status does not lead to
+var ErrIllegalTransition = errors.New("illegal order status transition")
+
+// holdsStock reports whether the units on the order's invoice are still reserved against product stock
+func holdsStock(order *orderRepository.Order) bool {
+	return !order.StockReleased
+}
+
+// historyEntry returns the update value that appends the given act to an order's history
+func historyEntry(act, by string) enum.SQLValueMerge {
+	return enum.SQLValueMerge{
+		Operator: enum.CONCAT,
+		Values: primitive.Array{
+			commonRepository.History{
+				Act: act,
+				By:  by,
+				At:  schema.NullTime{Time: time.Now()},
+			},
+		},
+	}
+}
+
+/*
+transition moves the locked order to the given status using the provided transaction
+
+The move is checked against enum.OrderTransitions and recorded in the order's history against the given actor, while
+reserved stock and coupon uses are released when the order is rejected or cancelled. A paid order or one with items already
+shipped cannot be rejected or cancelled, it has to be refunded instead
+
+The "reason" parameter, when provided, is appended to the history entry and stored as the order's remark
+
+The "set" parameter can be used to update other columns alongside the status
+
+It returns an error wrapping ErrIllegalTransition if the move is not allowed
+*/
+func transition(tx *bun.Tx, order *orderRepository.Order, next enum.OrderStatus, by, reason string, set ...map[string]interface{}) error {
+
+	if !order.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: order cannot be moved from %s to %s", ErrIllegalTransition, order.Status, next)
+	}
+
+	// an order can only be completed once the money is in
+	if next == enum.Completed && !order.Paid {
+		return fmt.Errorf("%w: order cannot be completed before it is paid for", ErrIllegalTransition)
+	}
+
+	// money can only be given back once it is in
+	if (next == enum.PartiallyRefunded || next == enum.Refunded) && !order.Paid {
+		return fmt.Errorf("%w: order cannot be refunded before it is paid for", ErrIllegalTransition)
+	}
+
+	if next == enum.Rejected || next == enum.Cancelled {
+
+		// the customer would lose their money
+		if order.Paid {
+			return fmt.Errorf("%w: a paid order cannot be moved to %s, refund it instead", ErrIllegalTransition, next)
+		}
+
+		// units that have left the warehouse cannot go back in stock
+		shipments, err := orderShipments(tx, order.ID)
+		if err != nil {
+			barf.Logger().Errorf(`[order.transition] [orderShipments(tx, order.ID)] %s`, err.Error())
+			return errors.New("we're having issues updating the order. please try again later")
+		}
+		if len(shipments) > 0 {
+			return fmt.Errorf("%w: an order with shipped items cannot be moved to %s", ErrIllegalTransition, next)
+		}
+	}
+
+	act := fmt.Sprintf("Moved order from %s to %s", order.Status, next)
+	if reason != "" {
+		act += ": " + reason
+	}
+
+	update := map[string]interface{}{
+		"status":     next,
+		"history":    historyEntry(act, by),
+		"updated_at": "now()", // update updated_at
+	}
+
+	if reason != "" {
+		update["remark"] = reason
+	}
+
+	if next == enum.Cancelled {
+		update["cancelled"] = true
+		update["cancelled_at"] = "now()"
+	}
+
+	if next == enum.Rejected {
+		update["rejected"] = true
+		update["rejected_at"] = "now()"
+	}
+
+	for _, s := range set {
+		for k, v := range s {
+			update[k] = v
+		}
+	}
+
+	// return the reserved units to stock
+	if (next == enum.Rejected || next == enum.Cancelled) && holdsStock(order) {
+		if err := releaseStock(tx, order.Invoice, inventoryRepository.Movement{
+			Kind:    enum.Cancellation,
+			OrderID: order.ID,
+			ActorID: by,
+			Reason:  act,
+		}); err != nil {
+			return err
+		}
+		update["stock_released"] = true
+	}
+
+	// give the coupon use back and stop fulfilling the lines
+	if next == enum.Rejected || next == enum.Cancelled {
+		if err := releaseCoupon(tx, order); err != nil {
+			return err
+		}
+		if err := cancelItems(tx, order.ID); err != nil {
+			return err
+		}
+	}
+
+	// update order
+	err := order.UByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": order.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+
+		SMap: types.SQLMap{
+			Map:                update,
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[order.transition] [order.UByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues updating the order. please try again later")
+	}
+
+	return nil
+}
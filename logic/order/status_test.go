@@ -0,0 +1,62 @@
+package order
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+)
+
+func TestTransitionErrors(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		status    enum.OrderStatus
+		paid      bool
+		shipments [][]driver.Value
+		next      enum.OrderStatus
+		err       string
+	}{
+		{"a move the table does not allow", enum.Completed, true, nil, enum.Approved, "illegal order status transition: order cannot be moved from COMPLETED to APPROVED"},
+		{"out of a final status", enum.Refunded, true, nil, enum.PartiallyRefunded, "illegal order status transition: order cannot be moved from REFUNDED to PARTIALLY_REFUNDED"},
+		{"completing an unpaid order", enum.Approved, false, nil, enum.Completed, "illegal order status transition: order cannot be completed before it is paid for"},
+		{"refunding an unpaid order", enum.Pending, false, nil, enum.Refunded, "illegal order status transition: order cannot be refunded before it is paid for"},
+		{"rejecting a paid order", enum.Pending, true, nil, enum.Rejected, "illegal order status transition: a paid order cannot be moved to REJECTED, refund it instead"},
+		{"cancelling a paid order", enum.Approved, true, nil, enum.Cancelled, "illegal order status transition: a paid order cannot be moved to CANCELLED, refund it instead"},
+		{"cancelling a shipped order", enum.Approved, false, [][]driver.Value{{"shipment-1", "order-1", time.Now()}}, enum.Cancelled, "illegal order status transition: an order with shipped items cannot be moved to CANCELLED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := useFakeDB(t, nil)
+			conn.tables["shipments"] = fakeTable{
+				columns: []string{"id", "order_id", "created_at"},
+				rows:    tt.shipments,
+			}
+
+			tx, err := database.PostgreSQLDB.BeginTx(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("could not begin transaction: %v", err)
+			}
+			defer tx.Rollback()
+
+			order := orderRepository.Order{ID: "order-1", Status: tt.status, Paid: tt.paid}
+
+			err = transition(&tx, &order, tt.next, "admin-1", "")
+			if !errors.Is(err, ErrIllegalTransition) || err.Error() != tt.err {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			if order.Status != tt.status {
+				t.Fatalf("expected the order to stay %s, got %s", tt.status, order.Status)
+			}
+			if len(conn.execs) != 0 {
+				t.Fatalf("expected the order to be left alone, got %q", conn.execs)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/logic/inventory"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// lockOrder returns the product lines of the invoice sorted by their key so that rows are always locked in the same order (this prevents deadlocks between concurrent checkouts)
+func lockOrder(items []orderRepository.Item) []orderRepository.Item {
+	sorted := make([]orderRepository.Item, 0, len(items))
+	for _, item := range items {
+		if item.IsProduct() {
+			sorted = append(sorted, item)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+/*
+reserveStock locks the products referenced by the order's invoice and takes the ordered units from their stock as a SALE using the
+provided transaction (lines for a variant take from the stock of the variant)
+
+It returns an error if any of the products can no longer cover the requested quantity
+*/
+func reserveStock(tx *bun.Tx, order *orderRepository.Order) error {
+	for _, item := range lockOrder(order.Invoice) {
+
+		if item.VariantID() != "" {
+			if err := reserveVariant(tx, order, item); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var product productRepository.Product
+
+		// find product and lock
+		err := product.FUByMap(tx, types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"id":     item.Key,
+						"status": enum.Published,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		}, true)
+		if err != nil {
+			barf.Logger().Errorf(`[order.reserveStock] [product.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("product '%s' is no longer available. please refresh and try again", item.Name)
+			}
+			return errors.New("we're having issues reserving stock for your order. please try again later")
+		}
+
+		// another checkout may have taken the units while we waited on the lock
+		if int64(item.Quantity) > product.Stock {
+			if product.Stock <= 0 {
+				return fmt.Errorf("product '%s' just sold out. please refresh and try again", product.Name)
+			}
+			return fmt.Errorf("only %d unit(s) of product '%s' are left in stock. please refresh and try again", product.Stock, product.Name)
+		}
+
+		// take the units from stock
+		if _, err := inventory.Move(tx, inventoryRepository.Movement{
+			ProductID: product.ID,
+			Kind:      enum.Sale,
+			Quantity:  -int64(item.Quantity),
+			OrderID:   order.ID,
+			ActorID:   order.UserID,
+			Reason:    fmt.Sprintf("Order %s", order.Reference),
+		}); err != nil {
+			barf.Logger().Errorf(`[order.reserveStock] [inventory.Move(tx, inventoryRepository.Movement{] %s`, err.Error())
+			return errors.New("we're having issues reserving stock for your order. please try again later")
+		}
+	}
+	return nil
+}
+
+/*
+releaseStock locks the products referenced by the invoice items and returns the reserved quantities to their stock using the provided transaction,
+the "movement" parameter describes why the units are put back (its kind, order, actor and reason) and is recorded once per item
+
+Products and variants that no longer exist are skipped, and it returns an error if any
+*/
+func releaseStock(tx *bun.Tx, items []orderRepository.Item, movement inventoryRepository.Movement) error {
+	for _, item := range lockOrder(items) {
+
+		if item.VariantID() != "" {
+			if err := releaseVariant(tx, item, movement); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var product productRepository.Product
+
+		// find product and lock
+		err := product.FUByMap(tx, types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"id": item.Key,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			barf.Logger().Errorf(`[order.releaseStock] [product.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+			return errors.New("we're having issues releasing stock for the order. please try again later")
+		}
+
+		// put the units back in stock
+		movement.ProductID, movement.VariantID, movement.Quantity = product.ID, "", int64(item.Quantity)
+		if _, err := inventory.Move(tx, movement); err != nil {
+			barf.Logger().Errorf(`[order.releaseStock] [inventory.Move(tx, movement)] %s`, err.Error())
+			return errors.New("we're having issues releasing stock for the order. please try again later")
+		}
+	}
+	return nil
+}
+
+/*
+reserveVariant locks the variant a product line is for and takes the ordered units from its stock as a SALE using the provided transaction
+
+Only the variant is locked, the status of its product is read without a lock so that rows are still locked in the order of the line keys
+*/
+func reserveVariant(tx *bun.Tx, order *orderRepository.Order, item orderRepository.Item) error {
+
+	var product productRepository.Product
+
+	// find product
+	err := product.FByKeyVal("id", item.ProductID(), true)
+	if err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[order.reserveVariant] [product.FByKeyVal("id", item.ProductID(), true)] %s`, err.Error())
+		return errors.New("we're having issues reserving stock for your order. please try again later")
+	}
+	if err == sql.ErrNoRows || product.Status != enum.Published {
+		return fmt.Errorf("product '%s' is no longer available. please refresh and try again", item.Name)
+	}
+
+	var variant variantRepository.Variant
+
+	// find variant and lock
+	err = variant.FUByMap(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         item.Key,
+					"product_id": product.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[order.reserveVariant] [variant.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("product '%s' is no longer available. please refresh and try again", item.Name)
+		}
+		return errors.New("we're having issues reserving stock for your order. please try again later")
+	}
+
+	// another checkout may have taken the units while we waited on the lock
+	if int64(item.Quantity) > variant.Stock {
+		if variant.Stock <= 0 {
+			return fmt.Errorf("product '%s' just sold out. please refresh and try again", item.Name)
+		}
+		return fmt.Errorf("only %d unit(s) of product '%s' are left in stock. please refresh and try again", variant.Stock, item.Name)
+	}
+
+	// take the units from stock
+	if _, err := inventory.Move(tx, inventoryRepository.Movement{
+		ProductID: product.ID,
+		VariantID: variant.ID,
+		Kind:      enum.Sale,
+		Quantity:  -int64(item.Quantity),
+		OrderID:   order.ID,
+		ActorID:   order.UserID,
+		Reason:    fmt.Sprintf("Order %s", order.Reference),
+	}); err != nil {
+		barf.Logger().Errorf(`[order.reserveVariant] [inventory.Move(tx, inventoryRepository.Movement{] %s`, err.Error())
+		return errors.New("we're having issues reserving stock for your order. please try again later")
+	}
+
+	return nil
+}
+
+// releaseVariant locks the variant a product line is for and returns the reserved quantity to its stock using the provided transaction
+func releaseVariant(tx *bun.Tx, item orderRepository.Item, movement inventoryRepository.Movement) error {
+
+	var variant variantRepository.Variant
+
+	// find variant and lock
+	err := variant.FUByMap(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": item.Key,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		barf.Logger().Errorf(`[order.releaseVariant] [variant.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues releasing stock for the order. please try again later")
+	}
+
+	// put the units back in stock
+	movement.ProductID, movement.VariantID, movement.Quantity = variant.ProductID, variant.ID, int64(item.Quantity)
+	if _, err := inventory.Move(tx, movement); err != nil {
+		barf.Logger().Errorf(`[order.releaseVariant] [inventory.Move(tx, movement)] %s`, err.Error())
+		return errors.New("we're having issues releasing stock for the order. please try again later")
+	}
+
+	return nil
+}
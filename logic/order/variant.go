@@ -0,0 +1,37 @@
+package order
+
+import (
+	"fmt"
+
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+)
+
+/*
+VariantOf returns the variant of the product with the given id out of the variants found for an order, or nil for a product
+that is not sold in variants
+
+It returns an error if the product is sold in variants and none (or one of another product) was chosen
+*/
+func VariantOf(product productRepository.Product, variants variantRepository.Variants, variantId string) (*variantRepository.Variant, error) {
+
+	sold := false
+	for i := range variants {
+		if variants[i].ProductID != product.ID {
+			continue
+		}
+		sold = true
+		if variants[i].ID == variantId {
+			return &variants[i], nil
+		}
+	}
+
+	switch {
+	case variantId != "":
+		return nil, fmt.Errorf("the selected variant of product '%s' is no longer available. please refresh and try again", product.Name)
+	case sold:
+		return nil, fmt.Errorf("please choose a variant of product '%s'", product.Name)
+	}
+
+	return nil, nil
+}
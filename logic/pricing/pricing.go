@@ -0,0 +1,331 @@
+package pricing
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	categoryRepository "github.com/funmi4194/ecommerce/repository/category"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// admin ensures the user exists and is an admin
+func admin(userId string) error {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[pricing.admin] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("looks like your account no longer exists. please contact support")
+		}
+		return errors.New("we're having issues retrieving your account. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return errors.New("you do not have the permission to access this feature")
+	}
+
+	return nil
+}
+
+// validate checks the terms of the rule
+func validate(r *pricingRepository.Rule) error {
+
+	r.Name = strings.TrimSpace(r.Name)
+	r.Country = strings.TrimSpace(r.Country)
+	r.State = strings.TrimSpace(r.State)
+	r.CategoryID = strings.TrimSpace(r.CategoryID)
+
+	if r.Name == "" {
+		return errors.New("rule name is required")
+	}
+
+	if !r.Basis.IsValid() {
+		return errors.New("rule basis must be one of PERCENTAGE or FIXED")
+	}
+
+	switch r.Basis {
+	case enum.Rate:
+		if r.Rate <= 0 || r.Rate > 100 {
+			return errors.New("a percentage rule must charge between 0 and 100 percent")
+		}
+		if r.Amount != 0 {
+			return errors.New("a percentage rule can only charge a rate")
+		}
+	case enum.Flat:
+		if r.Amount < 1 {
+			return errors.New("a fixed rule must charge an amount greater than zero (in kobo or cents)")
+		}
+		if r.Rate != 0 {
+			return errors.New("a fixed rule can only charge an amount")
+		}
+	}
+
+	if r.Basis == enum.Flat && !r.Currency.IsValid() {
+		return errors.New("a fixed rule must charge in one of the currencies we sell in")
+	}
+
+	if r.State != "" && r.Country == "" {
+		return errors.New("a rule for a state must also name its country")
+	}
+
+	if r.CategoryID != "" {
+
+		var category categoryRepository.Category
+
+		// find category
+		if err := category.FByMap(types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"id": r.CategoryID,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		}); err != nil {
+			barf.Logger().Errorf(`[pricing.validate] [category.FByMap(types.SQLMaps{] %s`, err.Error())
+			if err == sql.ErrNoRows {
+				return errors.New("the rule's category was not found")
+			}
+			return errors.New("we're having issues saving the rule. please try again later")
+		}
+	}
+
+	return nil
+}
+
+// CreateRule is the logic function for an admin to create a tax or fee rule
+func CreateRule(userId string, payload types.CreatePricingRule) (*pricingRepository.Rule, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	rule := pricingRepository.Rule{
+		Name:       payload.Name,
+		Kind:       payload.Kind,
+		Basis:      payload.Basis,
+		Rate:       payload.Rate,
+		Amount:     payload.Amount,
+		Currency:   payload.Currency,
+		Country:    payload.Country,
+		State:      payload.State,
+		CategoryID: payload.CategoryId,
+		Active:     true,
+		CreatedBy:  userId,
+	}
+
+	if payload.Active != nil {
+		rule.Active = *payload.Active
+	}
+
+	if rule.Currency == "" {
+		rule.Currency = primer.DefaultCurrency
+	}
+
+	if !rule.Kind.IsValid() {
+		return nil, errors.New("rule kind must be one of TAX or FEE")
+	}
+
+	if err := validate(&rule); err != nil {
+		return nil, err
+	}
+
+	rule.ID = helper.GenerateUUID()
+	rule.Date()
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	// create rule
+	if err := rule.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":          rule.ID,
+					"name":        rule.Name,
+					"kind":        rule.Kind,
+					"basis":       rule.Basis,
+					"rate":        rule.Rate,
+					"amount":      rule.Amount,
+					"currency":    rule.Currency,
+					"country":     rule.Country,
+					"state":       rule.State,
+					"category_id": rule.CategoryID,
+					"active":      rule.Active,
+					"created_by":  rule.CreatedBy,
+					"created_at":  rule.CreatedAt,
+					"updated_at":  rule.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[pricing.CreateRule] [rule.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the rule. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[pricing.CreateRule] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues creating the rule. please try again later")
+	}
+
+	return &rule, nil
+}
+
+// UpdateRule is the logic function for an admin to change a tax or fee rule or switch it on and off
+func UpdateRule(userId string, payload types.UpdatePricingRule) (*pricingRepository.Rule, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.RuleId == "" {
+		return nil, errors.New("rule id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	var rule pricingRepository.Rule
+
+	// find rule and lock
+	if err := rule.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.RuleId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[pricing.UpdateRule] [rule.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("rule not found")
+		}
+		return nil, errors.New("we're having issues updating the rule. please try again later")
+	}
+
+	if payload.Name != nil {
+		rule.Name = *payload.Name
+	}
+	if payload.Basis != nil && *payload.Basis != rule.Basis {
+		// what the rule charged on its old basis does not carry over
+		rule.Basis, rule.Rate, rule.Amount = *payload.Basis, 0, 0
+	}
+	if payload.Rate != nil {
+		rule.Rate = *payload.Rate
+	}
+	if payload.Amount != nil {
+		rule.Amount = *payload.Amount
+	}
+	if payload.Currency != nil {
+		rule.Currency = *payload.Currency
+	}
+	if payload.Country != nil {
+		rule.Country = *payload.Country
+	}
+	if payload.State != nil {
+		rule.State = *payload.State
+	}
+	if payload.CategoryId != nil {
+		rule.CategoryID = *payload.CategoryId
+	}
+	if payload.Active != nil {
+		rule.Active = *payload.Active
+	}
+
+	if err := validate(&rule); err != nil {
+		return nil, err
+	}
+
+	// update rule
+	if err := rule.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": rule.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"name":        rule.Name,
+				"basis":       rule.Basis,
+				"rate":        rule.Rate,
+				"amount":      rule.Amount,
+				"currency":    rule.Currency,
+				"country":     rule.Country,
+				"state":       rule.State,
+				"category_id": rule.CategoryID,
+				"active":      rule.Active,
+				"updated_at":  bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[pricing.UpdateRule] [rule.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the rule. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[pricing.UpdateRule] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating the rule. please try again later")
+	}
+
+	return &rule, nil
+}
+
+// Rules is the logic function for an admin to list all tax and fee rules
+func Rules(userId string) (*pricingRepository.Rules, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	rules := make(pricingRepository.Rules, 0)
+
+	// find rules
+	if err := rules.FByMap(types.SQLMaps{}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[pricing.Rules] [rules.FByMap(types.SQLMaps{})] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving rules. please try again later")
+	}
+
+	return &rules, nil
+}
@@ -0,0 +1,150 @@
+package pricing
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	pricingRepository "github.com/funmi4194/ecommerce/repository/pricing"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+/*
+SetRate is the logic function for an admin to set the exchange rate between two currencies, replacing any rate already set
+for the pair
+
+Orders are charged in another currency than their products are priced in with this rate (or the inverse of the rate set for
+the opposite pair)
+*/
+func SetRate(userId string, payload types.SetExchangeRate) (*pricingRepository.Rate, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if !payload.Base.IsValid() || !payload.Quote.IsValid() {
+		return nil, errors.New("rates can only be set between the currencies we sell in")
+	}
+
+	if payload.Base == payload.Quote {
+		return nil, errors.New("a rate must be between two different currencies")
+	}
+
+	if payload.Rate <= 0 {
+		return nil, errors.New("rate must be greater than zero")
+	}
+
+	rate := pricingRepository.Rate{
+		ID:        helper.GenerateUUID(),
+		Base:      payload.Base,
+		Quote:     payload.Quote,
+		Rate:      payload.Rate,
+		UpdatedBy: userId,
+	}
+	rate.Date()
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	// create or replace rate
+	if err := rate.UpsertTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         rate.ID,
+					"base":       rate.Base,
+					"quote":      rate.Quote,
+					"rate":       rate.Rate,
+					"updated_by": rate.UpdatedBy,
+					"created_at": rate.CreatedAt,
+					"updated_at": rate.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[pricing.SetRate] [rate.UpsertTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues setting the rate. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[pricing.SetRate] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues setting the rate. please try again later")
+	}
+
+	return &rate, nil
+}
+
+// DeleteRate is the logic function for an admin to remove an exchange rate so that its currencies can no longer be converted
+func DeleteRate(userId string, payload types.DeleteExchangeRate) error {
+
+	if err := admin(userId); err != nil {
+		return err
+	}
+
+	if payload.RateId == "" {
+		return errors.New("rate id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	var rate pricingRepository.Rate
+
+	// delete rate
+	if err := rate.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.RateId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("rate not found")
+		}
+		barf.Logger().Errorf(`[pricing.DeleteRate] [rate.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting the rate. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[pricing.DeleteRate] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues deleting the rate. please try again later")
+	}
+
+	return nil
+}
+
+// Rates is the logic function for an admin to list all exchange rates
+func Rates(userId string) (*pricingRepository.Rates, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	rates := make(pricingRepository.Rates, 0)
+
+	// find rates
+	if err := rates.FByMap(types.SQLMaps{}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[pricing.Rates] [rates.FByMap(types.SQLMaps{})] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving rates. please try again later")
+	}
+
+	return &rates, nil
+}
@@ -0,0 +1,133 @@
+package product
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/funmi4194/ecommerce/enum"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// Listing is the public view of a published product, stock counts are left out and only whether the product can be bought is shown
+type Listing struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// in the smallest unit of the currency (kobo, cents)
+	Price    int64         `json:"price"`
+	Currency enum.Currency `json:"currency"`
+
+	ProductUrl  string   `json:"product_url"`
+	Description string   `json:"description"`
+	CategoryIds []string `json:"category_ids"`
+	InStock     bool     `json:"in_stock"`
+
+	Variants []VariantListing `json:"variants"`
+}
+
+// VariantListing is the public view of a variant of a published product
+type VariantListing struct {
+	ID       string                    `json:"id"`
+	SKU      string                    `json:"sku"`
+	Options  variantRepository.Options `json:"options"`
+	Price    int64                     `json:"price"`
+	ImageUrl string                    `json:"image_url"`
+	InStock  bool                      `json:"in_stock"`
+}
+
+// listing returns the public view of the product
+func listing(product productRepository.Product) Listing {
+
+	l := Listing{
+		ID:          product.ID,
+		Name:        product.Name,
+		Price:       product.Price,
+		Currency:    product.Currency,
+		ProductUrl:  product.ProductUrl,
+		Description: product.Description,
+		CategoryIds: product.CategoryIds,
+		InStock:     product.Stock > 0,
+		Variants:    []VariantListing{},
+	}
+
+	for _, variant := range product.Variants {
+		l.Variants = append(l.Variants, VariantListing{
+			ID:       variant.ID,
+			SKU:      variant.SKU,
+			Options:  variant.Options,
+			Price:    variant.Price,
+			ImageUrl: variant.ImageUrl,
+			InStock:  variant.Stock > 0,
+		})
+
+		// a product sold in variants can be bought while any of its variants can
+		if variant.Stock > 0 {
+			l.InStock = true
+		}
+	}
+
+	return l
+}
+
+// Catalogue is the logic function for anyone, signed in or not, to browse the published products
+func Catalogue(payload types.CatalogueFilter) ([]Listing, *commonRepository.Pagination, error) {
+
+	products, pagination, err := list(types.ProductFilter{
+		MinAmount: payload.MinAmount,
+		MaxAmount: payload.MaxAmount,
+		Currency:  payload.Currency,
+		Category:  payload.Category,
+		Search:    payload.Search,
+		Page:      payload.Page,
+		Limit:     payload.Limit,
+		Paginate:  payload.Paginate,
+	}, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	listings := make([]Listing, 0, len(*products))
+	for _, product := range *products {
+		listings = append(listings, listing(product))
+	}
+
+	return listings, pagination, nil
+}
+
+// CatalogueProduct is the logic function for anyone, signed in or not, to view a published product
+func CatalogueProduct(payload types.ProductFilter) (*Listing, error) {
+
+	if payload.ProductId == "" {
+		return nil, errors.New("product id is required")
+	}
+
+	var product productRepository.Product
+
+	// find product
+	err := product.FByKeyVal("id", payload.ProductId, true)
+	if err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.CatalogueProduct] [product.FByKeyVal("id", payload.ProductId, true)] %s`, err.Error())
+		return nil, errors.New("we're having issues getting product. please try again later")
+	}
+
+	// products that are not for sale are not shown to shoppers at all
+	if err == sql.ErrNoRows || product.Status != enum.Published || !product.DeletedAt.IsZero() {
+		return nil, errors.New("product item not found")
+	}
+
+	products := productRepository.Products{product}
+	if err := withCategories(products); err != nil {
+		return nil, err
+	}
+
+	if err := withVariants(products); err != nil {
+		return nil, err
+	}
+
+	l := listing(products[0])
+	return &l, nil
+}
@@ -0,0 +1,194 @@
+package product
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	categoryRepository "github.com/funmi4194/ecommerce/repository/category"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/google/uuid"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+/*
+categorize lists the product in the given categories using the provided transaction, replacing the categories it was
+listed in before
+
+It returns the ids of the categories the product is now listed in
+*/
+func categorize(tx *bun.Tx, productId string, categoryIds []string) ([]string, error) {
+
+	ids := []interface{}{}
+	seen := map[string]bool{}
+	for _, id := range categoryIds {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	links := make(categoryRepository.Links, 0)
+
+	// take the product out of its current categories
+	if err := links.DByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": productId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.categorize] [links.DByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the product's categories. please try again later")
+	}
+
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	categories := make(categoryRepository.Categories, 0)
+
+	// find categories
+	if err := categories.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": ids,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.categorize] [categories.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the product's categories. please try again later")
+	}
+
+	if len(categories) != len(ids) {
+		return nil, errors.New("one or more of the product's categories were not found")
+	}
+
+	insertMap := types.SQLMaps{
+		IMaps: []types.SQLMap{},
+	}
+
+	listed := []string{}
+	for _, category := range categories {
+		listed = append(listed, category.ID)
+		insertMap.IMaps = append(insertMap.IMaps, types.SQLMap{
+			Map: map[string]interface{}{
+				"product_id":  productId,
+				"category_id": category.ID,
+				"created_at":  bun.NullTime{Time: time.Now()},
+			},
+		})
+	}
+
+	// list the product in the categories
+	if err := links.CreateTx(tx, insertMap); err != nil {
+		barf.Logger().Errorf(`[product.categorize] [links.CreateTx(tx, insertMap)] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the product's categories. please try again later")
+	}
+
+	return listed, nil
+}
+
+// withCategories loads the ids of the categories each of the products is listed in
+func withCategories(products productRepository.Products) error {
+
+	productIds := []interface{}{}
+	for i := range products {
+		products[i].CategoryIds = []string{}
+		productIds = append(productIds, products[i].ID)
+	}
+
+	if len(productIds) == 0 {
+		return nil
+	}
+
+	links := make(categoryRepository.Links, 0)
+
+	// find the products' links
+	if err := links.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": productIds,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.withCategories] [links.FByMap(types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues retrieving the products' categories. please try again later")
+	}
+
+	for _, link := range links {
+		for i := range products {
+			if products[i].ID == link.ProductID {
+				products[i].CategoryIds = append(products[i].CategoryIds, link.CategoryID)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+inCategory returns the filter matching the products listed in the category with the given id or slug or in any category
+below it
+
+The category is looked up first so that only an id read back from the database is written into the sub query
+*/
+func inCategory(idOrSlug string) (map[string]interface{}, error) {
+
+	idOrSlug = strings.TrimSpace(idOrSlug)
+
+	key := "slug"
+	if _, err := uuid.Parse(idOrSlug); err == nil {
+		key = "id"
+	}
+
+	var category categoryRepository.Category
+
+	// find category
+	if err := category.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					key: idOrSlug,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.inCategory] [category.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("category not found")
+		}
+		return nil, errors.New("we're having issues retrieving products. please try again later")
+	}
+
+	return map[string]interface{}{
+		"products.id": enum.SQLAlmostRaw{
+			Operator: enum.In,
+			Value:    `(SELECT product_categories.product_id FROM product_categories WHERE product_categories.category_id IN ` + categoryRepository.Subtree(category.ID) + `)`,
+		},
+	}, nil
+}
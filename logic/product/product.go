@@ -5,14 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/funmi4194/ecommerce/enum"
 	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/inventory"
 	"github.com/funmi4194/ecommerce/primer"
+	categoryRepository "github.com/funmi4194/ecommerce/repository/category"
 	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
 	productRepository "github.com/funmi4194/ecommerce/repository/product"
 	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
 	"github.com/funmi4194/ecommerce/types"
 	"github.com/opensaucerer/barf"
 	"github.com/uptrace/bun"
@@ -71,16 +76,26 @@ func Publish(userId string, payload types.Publish) (*productRepository.Products,
 			return nil, errors.New("product image is required")
 		}
 
+		if p.Currency == "" {
+			p.Currency = primer.DefaultCurrency
+		}
+
+		if !p.Currency.IsValid() {
+			return nil, fmt.Errorf("product '%s' must be priced in one of the currencies we sell in", p.Name)
+		}
+
 		products = append(products, productRepository.Product{
 			ID:          id,
 			Name:        p.Name,
 			Price:       p.Price,
+			Currency:    p.Currency,
 			Stock:       p.Stock,
 			ProductUrl:  p.ProductUrl,
 			Status:      enum.Published,
 			Description: p.Description,
 			CreatedAt:   bun.NullTime{Time: time.Now()},
 			UpdatedAt:   bun.NullTime{Time: time.Now()},
+			Variants:    variantRepository.Variants{},
 		})
 
 		insertMap.IMaps = append(insertMap.IMaps, types.SQLMap{
@@ -88,6 +103,7 @@ func Publish(userId string, payload types.Publish) (*productRepository.Products,
 				"id":          id,
 				"name":        p.Name,
 				"price":       p.Price,
+				"currency":    p.Currency,
 				"stock":       p.Stock,
 				"product_url": p.ProductUrl,
 				"status":      enum.Published,
@@ -105,6 +121,30 @@ func Publish(userId string, payload types.Publish) (*productRepository.Products,
 		}
 	}
 
+	// start the stock history of each product
+	for _, p := range products {
+		if err := inventory.Record(btx, &inventoryRepository.Movement{
+			ProductID: p.ID,
+			Kind:      enum.Restock,
+			Quantity:  p.Stock,
+			Balance:   p.Stock,
+			ActorID:   user.ID,
+			Reason:    "Initial stock",
+		}); err != nil {
+			barf.Logger().Errorf(`[product.Publish] [inventory.Record(btx, &inventoryRepository.Movement{] %s`, err.Error())
+			return nil, errors.New("we're having issues publishing products. please try again later")
+		}
+	}
+
+	// list the products in their categories
+	for i, p := range payload.Products {
+		listed, err := categorize(btx, products[i].ID, p.CategoryIds)
+		if err != nil {
+			return nil, err
+		}
+		products[i].CategoryIds = listed
+	}
+
 	// commit transaction
 	if err := btx.Commit(); err != nil {
 		barf.Logger().Errorf(`[product.Publish] [btx.Commit()] %s`, err.Error())
@@ -147,16 +187,20 @@ func UpdateProduct(userId string, payload types.UpdateProduct) (*productReposito
 
 	var product productRepository.Product
 
-	// do an update if the product already exists
-	err = product.FByKeyVal("id", payload.ProductId, true)
+	// do an update if the product already exists (locked so that its stock cannot change before the update)
+	err = product.FUByKeyVal(btx, "id", payload.ProductId, true)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			barf.Logger().Errorf(`[product.UpdateProduct] [product.FByKeyVal("id", payload.ProductId)] %s`, err.Error())
+			barf.Logger().Errorf(`[product.UpdateProduct] [product.FUByKeyVal(btx, "id", payload.ProductId)] %s`, err.Error())
 			return nil, errors.New("product item not found")
 		}
 		return nil, errors.New("we're having issues updating product. please try again later")
 	}
 
+	if !product.DeletedAt.IsZero() {
+		return nil, errors.New("this product has been deleted. please restore it before updating it")
+	}
+
 	//  generate filter map
 	query := map[string]interface{}{
 		"updated_at": bun.NullTime{Time: time.Now()},
@@ -174,8 +218,34 @@ func UpdateProduct(userId string, payload types.UpdateProduct) (*productReposito
 		query["price"] = &payload.Price
 	}
 
-	if payload.Stock != nil {
-		query["stock"] = &payload.Stock
+	if payload.Currency != nil {
+		if !payload.Currency.IsValid() {
+			return nil, errors.New("product must be priced in one of the currencies we sell in")
+		}
+		query["currency"] = *payload.Currency
+	}
+
+	// stock is not overwritten but changed by the difference so that the change is kept in the product's stock history
+	if payload.Stock != nil && *payload.Stock != product.Stock {
+		if *payload.Stock < 0 {
+			return nil, errors.New("product stock cannot be negative")
+		}
+
+		reason := strings.TrimSpace(payload.StockReason)
+		if reason == "" {
+			reason = fmt.Sprintf("Stock set to %d", *payload.Stock)
+		}
+
+		if _, err := inventory.Move(btx, inventoryRepository.Movement{
+			ProductID: product.ID,
+			Kind:      enum.Adjustment,
+			Quantity:  *payload.Stock - product.Stock,
+			ActorID:   user.ID,
+			Reason:    reason,
+		}); err != nil {
+			barf.Logger().Errorf(`[product.UpdateProduct] [inventory.Move(btx, inventoryRepository.Movement{] %s`, err.Error())
+			return nil, errors.New("we're having issues updating product. please try again later")
+		}
 	}
 
 	if payload.ProductUrl != nil {
@@ -210,13 +280,32 @@ func UpdateProduct(userId string, payload types.UpdateProduct) (*productReposito
 		return nil, errors.New("we're having issues updating product. please try again later")
 	}
 
+	if payload.CategoryIds != nil {
+		listed, err := categorize(btx, product.ID, *payload.CategoryIds)
+		if err != nil {
+			return nil, err
+		}
+		product.CategoryIds = listed
+	}
+
 	// commit transaction
 	if err := btx.Commit(); err != nil {
 		barf.Logger().Errorf(`[user.Deactivate] [btx.Commit()] %s`, err.Error())
 		return nil, errors.New("we're having issues updating product. please try again later")
 	}
 
-	return &product, nil
+	products := productRepository.Products{product}
+	if payload.CategoryIds == nil {
+		if err := withCategories(products); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := withVariants(products); err != nil {
+		return nil, err
+	}
+
+	return &products[0], nil
 }
 
 // Product retrieve a single product by Id
@@ -256,7 +345,16 @@ func Product(userId string, payload types.ProductFilter) (*productRepository.Pro
 		return nil, errors.New("we're having issues getting product. please try again later")
 	}
 
-	return &product, nil
+	products := productRepository.Products{product}
+	if err := withCategories(products); err != nil {
+		return nil, err
+	}
+
+	if err := withVariants(products); err != nil {
+		return nil, err
+	}
+
+	return &products[0], nil
 }
 
 // Products retrieve all products
@@ -276,6 +374,15 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 		return nil, nil, errors.New("we're having issues updating product. please try again later")
 	}
 
+	return list(payload, user.Role == enum.Admin)
+}
+
+/*
+list finds the products matching the filter, along with their categories and variants, where only admins can filter by status
+and list deleted products while everyone else only sees published products, at most primer.MaxPageLimit of them per page
+*/
+func list(payload types.ProductFilter, isAdmin bool) (*productRepository.Products, *commonRepository.Pagination, error) {
+
 	//  generate filter map
 	EqFilter := map[string]interface{}{}
 	gtEqFilter := map[string]interface{}{}
@@ -283,7 +390,7 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 	searchFilter := map[string]interface{}{}
 
 	// if user is an admin support status filter
-	if user.Role == enum.Admin {
+	if isAdmin {
 		if payload.Status != "" {
 			EqFilter["status"] = payload.Status
 		}
@@ -291,20 +398,37 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 		EqFilter["status"] = enum.Published
 	}
 
+	// deleted products are hidden unless an admin asks for them
+	if isAdmin && payload.Deleted {
+		EqFilter["deleted_at"] = enum.SQLAlmostRaw{Operator: enum.IsNotNull, Value: ""}
+	} else {
+		EqFilter["deleted_at"] = enum.SQLAlmostRaw{Operator: enum.IsNull, Value: ""}
+	}
+
 	if payload.ProductId != "" {
 		EqFilter["id"] = payload.ProductId
 	}
 
-	if payload.MinAmount != nil {
-		gtEqFilter["CAST(price AS NUMERIC)"] = enum.SQLRaw{
-			Value: fmt.Sprintf("CAST(price AS NUMERIC) >= %d", *payload.MinAmount),
+	if payload.Currency != "" {
+		EqFilter["currency"] = payload.Currency
+	}
+
+	if payload.Category != "" {
+		categoryFilter, err := inCategory(payload.Category)
+		if err != nil {
+			return nil, nil, err
 		}
+		for key, value := range categoryFilter {
+			EqFilter[key] = value
+		}
+	}
+
+	if payload.MinAmount != nil {
+		gtEqFilter["price"] = *payload.MinAmount
 	}
 
 	if payload.MaxAmount != nil {
-		ltEqFilter["CAST(price AS NUMERIC)"] = enum.SQLRaw{
-			Value: fmt.Sprintf("CAST(price AS NUMERIC) <= %d", *payload.MaxAmount),
-		}
+		ltEqFilter["price"] = *payload.MaxAmount
 	}
 
 	if !payload.StartDate.IsZero() {
@@ -331,6 +455,10 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 		limit = *payload.Limit
 	}
 
+	if !isAdmin && limit > primer.MaxPageLimit {
+		limit = primer.MaxPageLimit
+	}
+
 	if payload.Page != nil && *payload.Page > 0 {
 		offset = (*payload.Page - 1) * limit
 		page = *payload.Page
@@ -368,13 +496,21 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 		},
 		WJoinOperator: enum.And,
 	}, limit, offset, enum.DESC.String(), true, true); err != nil {
-		barf.Logger().Errorf(`[product.Products] [products.FByMap(types.SQLMaps{] %s`, err.Error())
+		barf.Logger().Errorf(`[product.list] [products.FByMap(types.SQLMaps{] %s`, err.Error())
 		if err == sql.ErrNoRows {
 			return nil, nil, errors.New("we couldn't find any products")
 		}
 		return nil, nil, errors.New("we're having issues retrieving products. please try again later")
 	}
 
+	if err := withCategories(products); err != nil {
+		return nil, nil, err
+	}
+
+	if err := withVariants(products); err != nil {
+		return nil, nil, err
+	}
+
 	var pagination = &commonRepository.Pagination{}
 
 	if payload.Paginate {
@@ -383,7 +519,7 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 			WJoinOperator: enum.And,
 		})
 		if err != nil {
-			barf.Logger().Errorf(`[product.Products] [product.Products].CByMap(types.SQLMaps{] %s`, err.Error())
+			barf.Logger().Errorf(`[product.list] [product.list].CByMap(types.SQLMaps{] %s`, err.Error())
 			if err == sql.ErrNoRows {
 				return nil, nil, errors.New("products not found")
 			}
@@ -401,6 +537,7 @@ func Products(userId string, payload types.ProductFilter) (*productRepository.Pr
 	return &products, pagination, nil
 }
 
+// DeleteProduct is the logic function for an admin to delete products, which are archived and hidden from listings until they are restored or purged
 func DeleteProduct(userId string, payload types.Delete) error {
 	// create a new transaction
 	btx, err := commonRepository.BeginTx()
@@ -434,25 +571,237 @@ func DeleteProduct(userId string, payload types.Delete) error {
 		}
 	}
 
+	if len(itemIds) == 0 {
+		return nil
+	}
+
 	products := make(productRepository.Products, 0)
 
-	if len(itemIds) > 0 {
-		if err := products.DByMap(types.SQLMaps{
-			WMaps: []types.SQLMap{
-				{
-					Map: map[string]interface{}{
-						"products.id": itemIds,
-					},
-					JoinOperator:       enum.And,
-					ComparisonOperator: enum.In,
+	// archive the products instead of deleting them so that the orders that reference them still resolve (they are purged after primer.DeletedProductRetention)
+	if err := products.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"products.id": itemIds,
 				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
 			},
-			WJoinOperator: enum.And,
-		}); err != nil {
-			barf.Logger().Errorf(`[product.Delete] [product.DByMap(types.SQLMaps{] %s`, err.Error())
-			return errors.New("we're having issues deleting products. please try again later")
-		}
+			{
+				Map: map[string]interface{}{
+					"products.deleted_at": enum.SQLAlmostRaw{Operator: enum.IsNull, Value: ""},
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":     enum.Archived,
+				"deleted_at": bun.NullTime{Time: time.Now()},
+				"updated_at": bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.Delete] [products.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting products. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[product.Delete] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues deleting products. please try again later")
 	}
 
 	return nil
 }
+
+// RestoreProducts is the logic function for an admin to bring back deleted products, which come back DELISTED so that they are only sold again once published
+func RestoreProducts(userId string, payload types.Restore) (*productRepository.Products, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	itemIds := []interface{}{}
+	for _, item := range payload.Products {
+		if item.ProductId != "" {
+			itemIds = append(itemIds, item.ProductId)
+		}
+	}
+
+	if len(itemIds) == 0 {
+		return nil, errors.New("product id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	products := make(productRepository.Products, 0)
+
+	// restore products
+	if err := products.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"products.id": itemIds,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+			{
+				Map: map[string]interface{}{
+					"products.deleted_at": enum.SQLAlmostRaw{Operator: enum.IsNotNull, Value: ""},
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"status":     enum.Delisted,
+				"deleted_at": nil,
+				"updated_at": bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.RestoreProducts] [products.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues restoring products. please try again later")
+	}
+
+	if len(products) == 0 {
+		return nil, errors.New("none of the products are deleted or they have already been purged")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[product.RestoreProducts] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues restoring products. please try again later")
+	}
+
+	if err := withCategories(products); err != nil {
+		return nil, err
+	}
+
+	if err := withVariants(products); err != nil {
+		return nil, err
+	}
+
+	return &products, nil
+}
+
+/*
+PurgeProducts is the logic function to permanently delete products deleted more than "retention" ago along with their variants and
+category links (the stock history of the products is kept). Products that any order was placed for are kept archived so that order
+history can still resolve them
+
+It returns the number of products purged and an error if any
+*/
+func PurgeProducts(retention time.Duration) (int, error) {
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return 0, err
+	}
+	defer btx.Rollback()
+
+	products := make(productRepository.Products, 0)
+
+	// delete products
+	if err := products.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"deleted_at": time.Now().Add(-retention),
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.LessThan,
+			},
+			{
+				Map: map[string]interface{}{
+					"orders": enum.SQLRaw{
+						Value: `NOT EXISTS (SELECT 1 FROM orders WHERE orders.product_id = products.id)`,
+					},
+					"order_items": enum.SQLRaw{
+						Value: `NOT EXISTS (SELECT 1 FROM order_items WHERE order_items.product_id = products.id)`,
+					},
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.PurgeProducts] [products.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return 0, errors.New("we're having issues purging deleted products")
+	}
+
+	if len(products) == 0 {
+		return 0, nil
+	}
+
+	itemIds := []interface{}{}
+	for _, product := range products {
+		itemIds = append(itemIds, product.ID)
+	}
+
+	links := make(categoryRepository.Links, 0)
+
+	// take the products out of their categories
+	if err := links.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": itemIds,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.PurgeProducts] [links.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return 0, errors.New("we're having issues purging deleted products")
+	}
+
+	var variant variantRepository.Variant
+
+	// delete the products' variants
+	if err := variant.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": itemIds,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.PurgeProducts] [variant.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return 0, errors.New("we're having issues purging deleted products")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[product.PurgeProducts] [btx.Commit()] %s`, err.Error())
+		return 0, errors.New("we're having issues purging deleted products")
+	}
+
+	return len(products), nil
+}
@@ -0,0 +1,494 @@
+package product
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/logic/inventory"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	inventoryRepository "github.com/funmi4194/ecommerce/repository/inventory"
+	productRepository "github.com/funmi4194/ecommerce/repository/product"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// admin ensures the user exists and is an admin
+func admin(userId string) error {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[product.admin] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("looks like your account no longer exists. please contact support")
+		}
+		return errors.New("we're having issues retrieving your account. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return errors.New("you do not have the permission to access this feature")
+	}
+
+	return nil
+}
+
+// withVariants loads the variants of each of the products
+func withVariants(products productRepository.Products) error {
+
+	productIds := []interface{}{}
+	for i := range products {
+		products[i].Variants = variantRepository.Variants{}
+		productIds = append(productIds, products[i].ID)
+	}
+
+	if len(productIds) == 0 {
+		return nil
+	}
+
+	variants := make(variantRepository.Variants, 0)
+
+	// find the products' variants
+	if err := variants.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": productIds,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.In,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.withVariants] [variants.FByMap(types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues retrieving the products' variants. please try again later")
+	}
+
+	for _, variant := range variants {
+		for i := range products {
+			if products[i].ID == variant.ProductID {
+				products[i].Variants = append(products[i].Variants, variant)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+validateVariant checks the sku, options, price and stock of the variant against the other variants of its product using
+the provided transaction, where every variant of a product must take a value for the same option types and no two variants
+can take the same values
+
+The product must be locked by the caller so that its variants do not change during the check
+*/
+func validateVariant(tx *bun.Tx, v *variantRepository.Variant) error {
+
+	v.SKU = strings.ToUpper(strings.TrimSpace(v.SKU))
+	v.ImageUrl = strings.TrimSpace(v.ImageUrl)
+
+	if v.SKU == "" {
+		return errors.New("variant sku is required")
+	}
+
+	if v.Price < 0 {
+		return errors.New("variant price cannot be negative")
+	}
+
+	if v.Stock < 0 {
+		return errors.New("variant stock cannot be negative")
+	}
+
+	options := variantRepository.Options{}
+	for name, value := range v.Options {
+		name, value = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+		if name == "" || value == "" {
+			return errors.New("variant options must name an option type and its value e.g {\"size\": \"M\"}")
+		}
+		options[name] = value
+	}
+	if len(options) == 0 {
+		return errors.New("variant options are required")
+	}
+	v.Options = options
+
+	siblings := make(variantRepository.Variants, 0)
+
+	// find the other variants of the product and any variant using the sku
+	if err := siblings.FByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"product_id": v.ProductID,
+					"sku":        v.SKU,
+				},
+				JoinOperator:       enum.Or,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[product.validateVariant] [siblings.FByMapTx(tx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	for _, sibling := range siblings {
+		if sibling.ID == v.ID {
+			continue
+		}
+		if sibling.SKU == v.SKU {
+			return errors.New("a variant with this sku already exists")
+		}
+		if strings.Join(sibling.Options.Types(), ", ") != strings.Join(v.Options.Types(), ", ") {
+			return errors.New("every variant of the product must take a value for the same options (" + strings.Join(sibling.Options.Types(), ", ") + ")")
+		}
+		if sibling.Options.Same(v.Options) {
+			return errors.New("the product already has a variant with these options")
+		}
+	}
+
+	return nil
+}
+
+// lockProduct finds and locks the product with the given id using the provided transaction
+func lockProduct(tx *bun.Tx, productId string) (*productRepository.Product, error) {
+
+	var product productRepository.Product
+
+	// find product and lock
+	if err := product.FUByMap(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": productId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, true); err != nil {
+		barf.Logger().Errorf(`[product.lockProduct] [product.FUByMap(tx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("product item not found")
+		}
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	if !product.DeletedAt.IsZero() {
+		return nil, errors.New("this product has been deleted. please restore it before changing its variants")
+	}
+
+	return &product, nil
+}
+
+// CreateVariant is the logic function for an admin to add a variant to a product
+func CreateVariant(userId string, payload types.CreateVariant) (*variantRepository.Variant, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.ProductId == "" {
+		return nil, errors.New("product id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	product, err := lockProduct(btx, payload.ProductId)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := variantRepository.Variant{
+		ProductID: product.ID,
+		SKU:       payload.Sku,
+		Options:   payload.Options,
+		Price:     product.Price,
+		Stock:     payload.Stock,
+		ImageUrl:  payload.ImageUrl,
+	}
+
+	if payload.Price != nil {
+		variant.Price = *payload.Price
+	}
+
+	if err := validateVariant(btx, &variant); err != nil {
+		return nil, err
+	}
+
+	if variant.ImageUrl == "" {
+		variant.ImageUrl = product.ProductUrl
+	}
+
+	variant.ID = helper.GenerateUUID()
+	variant.Date()
+
+	// create variant
+	if err := variant.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":         variant.ID,
+					"product_id": variant.ProductID,
+					"sku":        variant.SKU,
+					"options":    variant.Options,
+					"price":      variant.Price,
+					"stock":      variant.Stock,
+					"image_url":  variant.ImageUrl,
+					"created_at": variant.CreatedAt,
+					"updated_at": variant.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[product.CreateVariant] [variant.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	// start the stock history of the variant
+	if err := inventory.Record(btx, &inventoryRepository.Movement{
+		ProductID: variant.ProductID,
+		VariantID: variant.ID,
+		Kind:      enum.Restock,
+		Quantity:  variant.Stock,
+		Balance:   variant.Stock,
+		ActorID:   userId,
+		Reason:    "Initial stock",
+	}); err != nil {
+		barf.Logger().Errorf(`[product.CreateVariant] [inventory.Record(btx, &inventoryRepository.Movement{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[product.CreateVariant] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	return &variant, nil
+}
+
+// findVariant finds the variant with the given id
+func findVariant(variantId string) (*variantRepository.Variant, error) {
+
+	var variant variantRepository.Variant
+
+	// find variant
+	if err := variant.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": variantId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.findVariant] [variant.FByMap(types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("variant not found")
+		}
+		return nil, errors.New("we're having issues retrieving the variant. please try again later")
+	}
+
+	return &variant, nil
+}
+
+// UpdateVariant is the logic function for an admin to change the sku, options, price, stock or image of a variant
+func UpdateVariant(userId string, payload types.UpdateVariant) (*variantRepository.Variant, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	if payload.VariantId == "" {
+		return nil, errors.New("variant id is required")
+	}
+
+	found, err := findVariant(payload.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	// the product is locked first so that its variants are checked against each other one change at a time
+	if _, err := lockProduct(btx, found.ProductID); err != nil {
+		return nil, err
+	}
+
+	var variant variantRepository.Variant
+
+	// find variant and lock
+	if err := variant.FUByMap(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": found.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.UpdateVariant] [variant.FUByMap(btx, types.SQLMaps{] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("variant not found")
+		}
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	stock := variant.Stock
+
+	if payload.Sku != nil {
+		variant.SKU = *payload.Sku
+	}
+	if payload.Options != nil {
+		variant.Options = *payload.Options
+	}
+	if payload.Price != nil {
+		variant.Price = *payload.Price
+	}
+	if payload.Stock != nil {
+		variant.Stock = *payload.Stock
+	}
+	if payload.ImageUrl != nil {
+		variant.ImageUrl = *payload.ImageUrl
+	}
+
+	if err := validateVariant(btx, &variant); err != nil {
+		return nil, err
+	}
+
+	// stock is not overwritten but changed by the difference so that the change is kept in the product's stock history
+	if variant.Stock != stock {
+
+		reason := strings.TrimSpace(payload.StockReason)
+		if reason == "" {
+			reason = fmt.Sprintf("Stock set to %d", variant.Stock)
+		}
+
+		if _, err := inventory.Move(btx, inventoryRepository.Movement{
+			ProductID: variant.ProductID,
+			VariantID: variant.ID,
+			Kind:      enum.Adjustment,
+			Quantity:  variant.Stock - stock,
+			ActorID:   userId,
+			Reason:    reason,
+		}); err != nil {
+			barf.Logger().Errorf(`[product.UpdateVariant] [inventory.Move(btx, inventoryRepository.Movement{] %s`, err.Error())
+			return nil, errors.New("we're having issues saving the variant. please try again later")
+		}
+	}
+
+	// update variant
+	if err := variant.UByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": variant.ID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"sku":        variant.SKU,
+				"options":    variant.Options,
+				"price":      variant.Price,
+				"image_url":  variant.ImageUrl,
+				"updated_at": bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.UpdateVariant] [variant.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[product.UpdateVariant] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues saving the variant. please try again later")
+	}
+
+	return &variant, nil
+}
+
+// DeleteVariant is the logic function for an admin to stop selling a variant (orders keep its name and sku on their invoices)
+func DeleteVariant(userId string, payload types.DeleteVariant) error {
+
+	if err := admin(userId); err != nil {
+		return err
+	}
+
+	if payload.VariantId == "" {
+		return errors.New("variant id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	var variant variantRepository.Variant
+
+	// delete variant
+	if err := variant.DByMapTx(btx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id": payload.VariantId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[product.DeleteVariant] [variant.DByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return errors.New("we're having issues deleting the variant. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[product.DeleteVariant] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues deleting the variant. please try again later")
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package report
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/enum"
+	reportRepository "github.com/funmi4194/ecommerce/repository/report"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// Summary sums up orders placed over a date range
+type Summary struct {
+	// paid orders, their revenue and average value by currency
+	Totals reportRepository.CurrencyTotals `json:"totals"`
+
+	// orders in each status
+	Statuses reportRepository.StatusCounts `json:"statuses"`
+
+	Orders    int `json:"orders"`
+	Cancelled int `json:"cancelled"`
+	Rejected  int `json:"rejected"`
+
+	// the share of orders cancelled and rejected (0 to 1)
+	CancellationRate float64 `json:"cancellation_rate"`
+	RejectionRate    float64 `json:"rejection_rate"`
+}
+
+// admin ensures the user exists and is an admin
+func admin(userId string) error {
+
+	user := userRepository.User{
+		ID: userId,
+	}
+
+	// find user by ID
+	err := user.FByKeyVal("id", user.ID, true)
+	if err != nil {
+		barf.Logger().Errorf(`[report.admin] [user.FByKeyVal("id", user.ID, true)] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("looks like your account no longer exists. please contact support")
+		}
+		return errors.New("we're having issues retrieving your account. please try again later")
+	}
+
+	if user.Role != enum.Admin {
+		return errors.New("you do not have the permission to access this feature")
+	}
+
+	return nil
+}
+
+// filter turns the date range and currency of a report into where clauses the same way orders are filtered
+func filter(payload types.ReportFilter) (types.SQLMaps, error) {
+
+	if payload.Currency != "" && !payload.Currency.IsValid() {
+		return types.SQLMaps{}, errors.New("currency is not supported")
+	}
+
+	if !payload.StartDate.IsZero() && !payload.EndDate.IsZero() && payload.EndDate.Before(payload.StartDate) {
+		return types.SQLMaps{}, errors.New("end date cannot be before start date")
+	}
+
+	Eqfilter := map[string]interface{}{}
+	gtEqFilter := map[string]interface{}{}
+	ltEqFilter := map[string]interface{}{}
+
+	if payload.Currency != "" {
+		Eqfilter["orders.currency"] = payload.Currency
+	}
+	if !payload.StartDate.IsZero() {
+		gtEqFilter["orders.created_at"] = payload.StartDate
+	}
+	if !payload.EndDate.IsZero() {
+		ltEqFilter["orders.created_at"] = payload.EndDate
+	}
+
+	return types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map:                Eqfilter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+			{
+				Map:                gtEqFilter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.GreaterThanOrEqual,
+			},
+			{
+				Map:                ltEqFilter,
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.LessThanOrEqual,
+			},
+		},
+		WJoinOperator: enum.And,
+	}, nil
+}
+
+// Sales is the logic function for an admin to see order counts and revenue by day, week or month
+func Sales(userId string, payload types.ReportFilter) (*reportRepository.Sales, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	period := enum.ReportPeriod(strings.ToUpper(payload.Period.String()))
+	if period == "" {
+		period = enum.Day
+	}
+	if !period.IsValid() {
+		return nil, errors.New("period must be one of DAY, WEEK or MONTH")
+	}
+
+	where, err := filter(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sales := make(reportRepository.Sales, 0)
+
+	if err := sales.FByMap(where, period); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[report.Sales] [sales.FByMap(where, period)] %s`, err.Error())
+		return nil, errors.New("we're having issues preparing the report. please try again later")
+	}
+
+	return &sales, nil
+}
+
+// ProductSales is the logic function for an admin to see the units and revenue of products sold by day, week or month
+func ProductSales(userId string, payload types.ReportFilter) (*reportRepository.ProductPeriods, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	period := enum.ReportPeriod(strings.ToUpper(payload.Period.String()))
+	if period == "" {
+		period = enum.Day
+	}
+	if !period.IsValid() {
+		return nil, errors.New("period must be one of DAY, WEEK or MONTH")
+	}
+
+	where, err := filter(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.ProductId != "" {
+		where.WMaps = append(where.WMaps, types.SQLMap{
+			Map: map[string]interface{}{
+				"order_items.product_id": payload.ProductId,
+			},
+			JoinOperator:       enum.And,
+			ComparisonOperator: enum.Equal,
+		})
+	}
+
+	sales := make(reportRepository.ProductPeriods, 0)
+
+	if err := sales.FByMap(where, period); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[report.ProductSales] [sales.FByMap(where, period)] %s`, err.Error())
+		return nil, errors.New("we're having issues preparing the report. please try again later")
+	}
+
+	return &sales, nil
+}
+
+// TopProducts is the logic function for an admin to see the products that sold the most units
+func TopProducts(userId string, payload types.ReportFilter) (*reportRepository.ProductSales, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	where, err := filter(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := 10
+	if payload.Limit != nil {
+		limit = *payload.Limit
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than zero")
+	}
+
+	products := make(reportRepository.ProductSales, 0)
+
+	if err := products.FByMap(where, limit); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[report.TopProducts] [products.FByMap(where, limit)] %s`, err.Error())
+		return nil, errors.New("we're having issues preparing the report. please try again later")
+	}
+
+	return &products, nil
+}
+
+// Summarize is the logic function for an admin to see the average order value, the cancellation and rejection rates and how many orders are in each status
+func Summarize(userId string, payload types.ReportFilter) (*Summary, error) {
+
+	if err := admin(userId); err != nil {
+		return nil, err
+	}
+
+	where, err := filter(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := Summary{
+		Totals:   make(reportRepository.CurrencyTotals, 0),
+		Statuses: make(reportRepository.StatusCounts, 0),
+	}
+
+	if err := summary.Totals.FByMap(where); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[report.Summarize] [summary.Totals.FByMap(where)] %s`, err.Error())
+		return nil, errors.New("we're having issues preparing the report. please try again later")
+	}
+
+	if err := summary.Statuses.FByMap(where); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[report.Summarize] [summary.Statuses.FByMap(where)] %s`, err.Error())
+		return nil, errors.New("we're having issues preparing the report. please try again later")
+	}
+
+	var outcome reportRepository.Outcome
+	if err := outcome.FByMap(where); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[report.Summarize] [outcome.FByMap(where)] %s`, err.Error())
+		return nil, errors.New("we're having issues preparing the report. please try again later")
+	}
+
+	summary.Orders = outcome.Orders
+	summary.Cancelled = outcome.Cancelled
+	summary.Rejected = outcome.Rejected
+	if outcome.Orders > 0 {
+		summary.CancellationRate = math.Round(float64(outcome.Cancelled)/float64(outcome.Orders)*10000) / 10000
+		summary.RejectionRate = math.Round(float64(outcome.Rejected)/float64(outcome.Orders)*10000) / 10000
+	}
+
+	return &summary, nil
+}
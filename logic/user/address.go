@@ -0,0 +1,384 @@
+package user
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/helper"
+	"github.com/funmi4194/ecommerce/primer"
+	addressRepository "github.com/funmi4194/ecommerce/repository/address"
+	commonRepository "github.com/funmi4194/ecommerce/repository/common"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/uptrace/bun"
+)
+
+// ownedBy returns the filter matching the given address of the given user
+func ownedBy(addressId, userId string) types.SQLMaps {
+	return types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":      addressId,
+					"user_id": userId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}
+}
+
+// clearDefault unsets the default flag on all the addresses of the user using the provided transaction
+func clearDefault(tx *bun.Tx, userId string) error {
+	var address addressRepository.Address
+	return address.UByMapTx(tx, types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"user_id":    userId,
+					"is_default": true,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"is_default": false,
+				"updated_at": "now()",
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		WJoinOperator: enum.And,
+	})
+}
+
+// CreateAddress adds an address to the user's address book, the first address saved becomes the default
+func CreateAddress(userId string, payload types.Address) (*addressRepository.Address, error) {
+
+	address := addressRepository.Address{
+		UserID:     userId,
+		Label:      payload.Label,
+		Recipient:  payload.Recipient,
+		Phone:      payload.Phone,
+		Line1:      payload.Line1,
+		Line2:      payload.Line2,
+		City:       payload.City,
+		State:      payload.State,
+		PostalCode: payload.PostalCode,
+		Country:    payload.Country,
+		IsDefault:  payload.IsDefault,
+	}
+
+	// verify address payload
+	if err := address.Prepare(); err != nil {
+		return nil, err
+	}
+
+	addresses := make(addressRepository.Addresses, 0)
+
+	// count saved addresses
+	count, err := addresses.CByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"user_id": userId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	})
+	if err != nil {
+		barf.Logger().Errorf(`[user.CreateAddress] [addresses.CByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving your address. please try again later")
+	}
+
+	if count >= primer.MaxAddresses {
+		return nil, fmt.Errorf("you can only save up to %d addresses", primer.MaxAddresses)
+	}
+
+	if count == 0 {
+		address.IsDefault = true
+	}
+
+	address.ID = helper.GenerateUUID()
+	address.Date()
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	// a user has a single default address
+	if address.IsDefault {
+		if err := clearDefault(btx, userId); err != nil {
+			barf.Logger().Errorf(`[user.CreateAddress] [clearDefault(btx, userId)] %s`, err.Error())
+			return nil, errors.New("we're having issues saving your address. please try again later")
+		}
+	}
+
+	// create address
+	if err := address.CreateTx(btx, types.SQLMaps{
+		IMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"id":          address.ID,
+					"user_id":     address.UserID,
+					"label":       address.Label,
+					"recipient":   address.Recipient,
+					"phone":       address.Phone,
+					"line1":       address.Line1,
+					"line2":       address.Line2,
+					"city":        address.City,
+					"state":       address.State,
+					"postal_code": address.PostalCode,
+					"country":     address.Country,
+					"is_default":  address.IsDefault,
+					"created_at":  address.CreatedAt,
+					"updated_at":  address.UpdatedAt,
+				},
+			},
+		},
+	}); err != nil {
+		barf.Logger().Errorf(`[user.CreateAddress] [address.CreateTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues saving your address. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[user.CreateAddress] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues saving your address. please try again later")
+	}
+
+	return &address, nil
+}
+
+// Addresses returns the user's address book with the default address first
+func Addresses(userId string) (*addressRepository.Addresses, error) {
+
+	addresses := make(addressRepository.Addresses, 0)
+
+	// find addresses
+	if err := addresses.FByMap(types.SQLMaps{
+		WMaps: []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"user_id": userId,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil && err != sql.ErrNoRows {
+		barf.Logger().Errorf(`[user.Addresses] [addresses.FByMap(types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues retrieving your addresses. please try again later")
+	}
+
+	return &addresses, nil
+}
+
+// UpdateAddress updates an address in the user's address book
+func UpdateAddress(userId string, payload types.UpdateAddress) (*addressRepository.Address, error) {
+
+	if payload.AddressId == "" {
+		return nil, errors.New("address id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer btx.Rollback()
+
+	var address addressRepository.Address
+
+	// find address and lock
+	if err := address.FUByMap(btx, ownedBy(payload.AddressId, userId)); err != nil {
+		barf.Logger().Errorf(`[user.UpdateAddress] [address.FUByMap(btx, ownedBy(payload.AddressId, userId))] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return nil, errors.New("address not found")
+		}
+		return nil, errors.New("we're having issues updating your address. please try again later")
+	}
+
+	if payload.Label != nil {
+		address.Label = *payload.Label
+	}
+	if payload.Recipient != nil {
+		address.Recipient = *payload.Recipient
+	}
+	if payload.Phone != nil {
+		address.Phone = *payload.Phone
+	}
+	if payload.Line1 != nil {
+		address.Line1 = *payload.Line1
+	}
+	if payload.Line2 != nil {
+		address.Line2 = *payload.Line2
+	}
+	if payload.City != nil {
+		address.City = *payload.City
+	}
+	if payload.State != nil {
+		address.State = *payload.State
+	}
+	if payload.PostalCode != nil {
+		address.PostalCode = *payload.PostalCode
+	}
+	if payload.Country != nil {
+		address.Country = *payload.Country
+	}
+
+	// the default address can only be replaced by making another address the default
+	if payload.IsDefault != nil && *payload.IsDefault && !address.IsDefault {
+		if err := clearDefault(btx, userId); err != nil {
+			barf.Logger().Errorf(`[user.UpdateAddress] [clearDefault(btx, userId)] %s`, err.Error())
+			return nil, errors.New("we're having issues updating your address. please try again later")
+		}
+		address.IsDefault = true
+	}
+
+	// verify the updated address
+	if err := address.Prepare(); err != nil {
+		return nil, err
+	}
+
+	// update address
+	if err := address.UByMapTx(btx, types.SQLMaps{
+		WMaps: ownedBy(address.ID, userId).WMaps,
+		SMap: types.SQLMap{
+			Map: map[string]interface{}{
+				"label":       address.Label,
+				"recipient":   address.Recipient,
+				"phone":       address.Phone,
+				"line1":       address.Line1,
+				"line2":       address.Line2,
+				"city":        address.City,
+				"state":       address.State,
+				"postal_code": address.PostalCode,
+				"country":     address.Country,
+				"is_default":  address.IsDefault,
+				"updated_at":  bun.NullTime{Time: time.Now()},
+			},
+			JoinOperator:       enum.Comma,
+			ComparisonOperator: enum.Equal,
+		},
+		RMap: types.SQLMap{
+			Map: map[string]interface{}{"*": nil},
+		},
+		WJoinOperator: enum.And,
+	}); err != nil {
+		barf.Logger().Errorf(`[user.UpdateAddress] [address.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your address. please try again later")
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[user.UpdateAddress] [btx.Commit()] %s`, err.Error())
+		return nil, errors.New("we're having issues updating your address. please try again later")
+	}
+
+	return &address, nil
+}
+
+/*
+DeleteAddress removes an address from the user's address book
+
+When the default address is removed, the oldest remaining address becomes the default. Orders keep their own copy of the address and are not affected
+*/
+func DeleteAddress(userId string, payload types.DeleteAddress) error {
+
+	if payload.AddressId == "" {
+		return errors.New("address id is required")
+	}
+
+	// create a new transaction
+	btx, err := commonRepository.BeginTx()
+	if err != nil {
+		return err
+	}
+	defer btx.Rollback()
+
+	var address addressRepository.Address
+
+	// find address and lock
+	if err := address.FUByMap(btx, ownedBy(payload.AddressId, userId)); err != nil {
+		barf.Logger().Errorf(`[user.DeleteAddress] [address.FUByMap(btx, ownedBy(payload.AddressId, userId))] %s`, err.Error())
+		if err == sql.ErrNoRows {
+			return errors.New("address not found")
+		}
+		return errors.New("we're having issues deleting your address. please try again later")
+	}
+
+	// delete address
+	if err := address.DByMapTx(btx, ownedBy(address.ID, userId)); err != nil {
+		barf.Logger().Errorf(`[user.DeleteAddress] [address.DByMapTx(btx, ownedBy(address.ID, userId))] %s`, err.Error())
+		return errors.New("we're having issues deleting your address. please try again later")
+	}
+
+	if address.IsDefault {
+
+		addresses := make(addressRepository.Addresses, 0)
+
+		// find the remaining addresses
+		if err := addresses.FByMap(types.SQLMaps{
+			WMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"user_id": userId,
+					},
+					JoinOperator:       enum.And,
+					ComparisonOperator: enum.Equal,
+				},
+			},
+			WJoinOperator: enum.And,
+		}); err != nil && err != sql.ErrNoRows {
+			barf.Logger().Errorf(`[user.DeleteAddress] [addresses.FByMap(types.SQLMaps{] %s`, err.Error())
+			return errors.New("we're having issues deleting your address. please try again later")
+		}
+
+		for _, a := range addresses {
+			if a.ID == address.ID {
+				continue
+			}
+
+			// promote the oldest remaining address
+			if err := a.UByMapTx(btx, types.SQLMaps{
+				WMaps: ownedBy(a.ID, userId).WMaps,
+				SMap: types.SQLMap{
+					Map: map[string]interface{}{
+						"is_default": true,
+						"updated_at": "now()",
+					},
+					JoinOperator:       enum.Comma,
+					ComparisonOperator: enum.Equal,
+				},
+				WJoinOperator: enum.And,
+			}); err != nil {
+				barf.Logger().Errorf(`[user.DeleteAddress] [a.UByMapTx(btx, types.SQLMaps{] %s`, err.Error())
+				return errors.New("we're having issues deleting your address. please try again later")
+			}
+			break
+		}
+	}
+
+	// commit transaction
+	if err := btx.Commit(); err != nil {
+		barf.Logger().Errorf(`[user.DeleteAddress] [btx.Commit()] %s`, err.Error())
+		return errors.New("we're having issues deleting your address. please try again later")
+	}
+
+	return nil
+}
@@ -3,12 +3,17 @@ package main
 import (
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/funmi4194/ecommerce/database"
 	"github.com/funmi4194/ecommerce/database/migration"
+	"github.com/funmi4194/ecommerce/middleware"
+	"github.com/funmi4194/ecommerce/payment"
 	"github.com/funmi4194/ecommerce/primer"
+	"github.com/funmi4194/ecommerce/scheduler"
 	"github.com/funmi4194/ecommerce/version"
 	"github.com/opensaucerer/barf"
+	"github.com/opensaucerer/barf/server"
 )
 
 func main() {
@@ -55,6 +60,15 @@ func main() {
 		barf.Logger().Fatalf(`[main.main] [database.CreateTables()] %s`, err.Error())
 	}
 
+	if err := migration.Migrate(); err != nil {
+		barf.Logger().Fatalf(`[main.main] [migration.Migrate()] %s`, err.Error())
+	}
+
+	// configure payment gateway
+	if err := payment.NewPaymentGateway(primer.ENV.PaymentProvider, primer.ENV.PaymentSecretKey); err != nil {
+		barf.Logger().Fatalf(`[main.main] [payment.NewPaymentGateway(primer.ENV.PaymentProvider, primer.ENV.PaymentSecretKey)] %s`, err.Error())
+	}
+
 	// if err := database.ReadFileAndExecuteQueries(primer.ENV.SQLFilePath); err != nil {
 	// 	barf.Logger().Fatalf(`[main.main] [database.ReadFileAndExecuteQueries(primer.ENV.SQLFilePath)] %s`, err.Error())
 	// }
@@ -62,6 +76,29 @@ func main() {
 	// preload v1 routes
 	version.V1()
 
+	// let handlers that stream write past barf's buffered response writer
+	server.HTTP.Handler = middleware.Stream(server.HTTP.Handler)
+
+	// expire stale pending orders in the background
+	ttl := primer.DefaultPendingOrderTTL
+	if primer.ENV.PendingOrderTTL != "" {
+		d, err := time.ParseDuration(primer.ENV.PendingOrderTTL)
+		if err != nil {
+			barf.Logger().Fatalf(`[main.main] [time.ParseDuration(primer.ENV.PendingOrderTTL)] %s`, err.Error())
+		}
+		ttl = d
+	}
+	scheduler.ExpirePendingOrders(ttl, primer.ExpiryInterval)
+
+	// forget idempotency keys once they can no longer be replayed
+	scheduler.PurgeIdempotencyKeys(primer.IdempotencyKeyTTL, primer.PurgeInterval)
+
+	// permanently delete products once they can no longer be restored
+	scheduler.PurgeDeletedProducts(primer.DeletedProductRetention, primer.PurgeInterval)
+
+	// send refunds that were recorded but never reached the payment provider
+	scheduler.RetryPendingRefunds(primer.RefundRetryAge, primer.RefundRetryInterval)
+
 	// call upon barf to listen and serve
 	if err := barf.Beck(); err != nil {
 		barf.Logger().Errorf(`[main.main] [barf.Beck()] %s`, err.Error())
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/primer"
+	idempotencyRepository "github.com/funmi4194/ecommerce/repository/idempotency"
+	userRepository "github.com/funmi4194/ecommerce/repository/user"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+	"github.com/opensaucerer/barf/server"
+	"github.com/uptrace/bun"
+)
+
+/*
+Idempotency makes POST and PATCH requests carrying an Idempotency-Key header safe to retry
+
+The first request made with a key is processed and its response stored against the key and the user making it,
+retries with the same request get the stored response back while reusing the key for a different request is rejected.
+Requests without an authenticated user are passed through untouched so that responses are never shared between clients
+*/
+func Idempotency(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// get idempotency key header
+		key := r.Header.Get(primer.IdempotencyHeader)
+
+		// keys are scoped to the user making the request
+		user, ok := r.Context().Value(types.AuthCtxKey{}).(*userRepository.User)
+
+		if key == "" || !ok || user == nil || user.ID == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > primer.MaxIdempotencyKey {
+			barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+				Status:  false,
+				Message: "Idempotency-Key is too long.",
+			})
+			return
+		}
+
+		// read the body and put it back for the handler
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			barf.Logger().Errorf(`[middleware.Idempotency] [io.ReadAll(r.Body)] %s`, err.Error())
+			barf.Response(w).Status(http.StatusBadRequest).JSON(barf.Res{
+				Status:  false,
+				Message: "We could not process your request at this time. Please try again later.",
+			})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		idempotency := idempotencyRepository.Key{
+			Key:         key,
+			UserID:      user.ID,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: primer.StringSha256(r.Method + " " + r.URL.Path + " " + string(body)),
+			CreatedAt:   bun.NullTime{Time: time.Now()},
+			UpdatedAt:   bun.NullTime{Time: time.Now()},
+		}
+
+		where := []types.SQLMap{
+			{
+				Map: map[string]interface{}{
+					"key":     idempotency.Key,
+					"user_id": idempotency.UserID,
+				},
+				JoinOperator:       enum.And,
+				ComparisonOperator: enum.Equal,
+			},
+		}
+
+		// reserve the key
+		created, err := idempotency.Create(types.SQLMaps{
+			IMaps: []types.SQLMap{
+				{
+					Map: map[string]interface{}{
+						"key":          idempotency.Key,
+						"user_id":      idempotency.UserID,
+						"method":       idempotency.Method,
+						"path":         idempotency.Path,
+						"request_hash": idempotency.RequestHash,
+						"status_code":  idempotency.StatusCode,
+						"response":     idempotency.Response,
+						"created_at":   idempotency.CreatedAt,
+						"updated_at":   idempotency.UpdatedAt,
+					},
+				},
+			},
+		})
+		if err != nil {
+			barf.Logger().Errorf(`[middleware.Idempotency] [idempotency.Create(types.SQLMaps{] %s`, err.Error())
+			barf.Response(w).Status(http.StatusInternalServerError).JSON(barf.Res{
+				Status:  false,
+				Message: "We could not process your request at this time. Please try again later.",
+			})
+			return
+		}
+
+		if !created {
+
+			var existing idempotencyRepository.Key
+
+			// find the request that first used the key
+			if err := existing.FByMap(types.SQLMaps{
+				WMaps:         where,
+				WJoinOperator: enum.And,
+			}); err != nil {
+				barf.Logger().Errorf(`[middleware.Idempotency] [existing.FByMap(types.SQLMaps{] %s`, err.Error())
+				status := http.StatusInternalServerError
+				if err == sql.ErrNoRows {
+					// the first request failed and released the key in the meantime
+					status = http.StatusConflict
+				}
+				barf.Response(w).Status(status).JSON(barf.Res{
+					Status:  false,
+					Message: "We could not process your request at this time. Please try again later.",
+				})
+				return
+			}
+
+			if existing.RequestHash != idempotency.RequestHash {
+				barf.Response(w).Status(http.StatusUnprocessableEntity).JSON(barf.Res{
+					Status:  false,
+					Message: "Idempotency-Key has already been used for a different request.",
+				})
+				return
+			}
+
+			if existing.StatusCode == 0 {
+				barf.Response(w).Status(http.StatusConflict).JSON(barf.Res{
+					Status:  false,
+					Message: "A request with this Idempotency-Key is still being processed.",
+				})
+				return
+			}
+
+			// replay the stored response
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(primer.IdempotencyReplayHeader, "true")
+			w.WriteHeader(existing.StatusCode)
+			w.Write([]byte(existing.Response))
+			server.Write(w)
+			return
+		}
+
+		stored := false
+		defer func() {
+			// release the key so that the request can be retried
+			if !stored {
+				if err := idempotency.DByMap(types.SQLMaps{
+					WMaps:         where,
+					WJoinOperator: enum.And,
+				}); err != nil {
+					barf.Logger().Errorf(`[middleware.Idempotency] [idempotency.DByMap(types.SQLMaps{] %s`, err.Error())
+				}
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+
+		// server errors are not stored so that the request can be retried
+		status := server.Status(w)
+		res, ok := w.(*server.ResponseWriter)
+		if !ok || status == 0 || status >= http.StatusInternalServerError {
+			return
+		}
+
+		// store the response
+		if err := idempotency.UByMap(types.SQLMaps{
+			WMaps: where,
+			SMap: types.SQLMap{
+				Map: map[string]interface{}{
+					"status_code": status,
+					"response":    string(res.Body),
+					"updated_at":  bun.NullTime{Time: time.Now()},
+				},
+				JoinOperator:       enum.Comma,
+				ComparisonOperator: enum.Equal,
+			},
+			WJoinOperator: enum.And,
+		}); err != nil {
+			barf.Logger().Errorf(`[middleware.Idempotency] [idempotency.UByMap(types.SQLMaps{] %s`, err.Error())
+			return
+		}
+		stored = true
+	})
+}
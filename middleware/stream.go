@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/funmi4194/ecommerce/types"
+)
+
+/*
+Stream keeps the connection's own response writer in the request context under types.StreamCtxKey
+
+barf swaps the writer for one that holds the whole response until the handler is done, which rules out responses too large to
+hold in memory, so Stream has to wrap the server's handler from the outside (before barf gets to the writer)
+*/
+func Stream(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), types.StreamCtxKey{}, w)))
+	})
+}
@@ -0,0 +1,22 @@
+package payment
+
+import (
+	"fmt"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+var (
+	PaymentGateway Gateway
+)
+
+// NewPaymentGateway configures the payment gateway for the given provider
+func NewPaymentGateway(provider enum.PaymentProvider, secret string) error {
+	switch provider {
+	case enum.Paystack:
+		PaymentGateway = NewPaystackGateway(secret)
+	default:
+		return fmt.Errorf("unsupported payment provider %s", provider)
+	}
+	return nil
+}
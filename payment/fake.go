@@ -0,0 +1,177 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+/*
+FakeGateway is an in-process gateway that keeps charges in memory
+
+It signs its webhooks exactly like a real provider so the whole payment flow can be exercised in tests without network access,
+it is never used by a running server as its charges only live in the memory of the process
+*/
+type FakeGateway struct {
+	secret  string
+	mu      sync.Mutex
+	charges map[string]*Transaction
+	refunds map[string][]Refund
+	keys    map[string]Refund
+}
+
+// NewFakeGateway creates a new in-process gateway signing webhooks with the given secret
+func NewFakeGateway(secret string) *FakeGateway {
+	return &FakeGateway{
+		secret:  secret,
+		charges: map[string]*Transaction{},
+		refunds: map[string][]Refund{},
+		keys:    map[string]Refund{},
+	}
+}
+
+// Name returns the provider behind the gateway
+func (f *FakeGateway) Name() enum.PaymentProvider {
+	return enum.FakeProvider
+}
+
+// Initialize records a pending charge
+func (f *FakeGateway) Initialize(charge Charge) (*Authorization, error) {
+	if charge.Reference == "" {
+		return nil, errors.New("reference is required")
+	}
+	if charge.Amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.charges[charge.Reference]; !ok {
+		f.charges[charge.Reference] = &Transaction{
+			Reference: charge.Reference,
+			Amount:    charge.Amount,
+			Currency:  charge.Currency,
+			Status:    enum.PaymentPending,
+		}
+	}
+
+	return &Authorization{
+		Reference:  charge.Reference,
+		URL:        "https://checkout.fake.local/" + charge.Reference,
+		AccessCode: charge.Reference,
+	}, nil
+}
+
+// Verify returns the recorded charge with the given reference
+func (f *FakeGateway) Verify(reference string) (*Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	charge, ok := f.charges[reference]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	t := *charge
+	return &t, nil
+}
+
+// Refund records a refund against a successful charge, returning the refund already recorded under the key if any
+func (f *FakeGateway) Refund(reference string, amount int64, key string) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if refund, ok := f.keys[key]; ok && key != "" {
+		return &refund, nil
+	}
+
+	charge, ok := f.charges[reference]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	if charge.Status != enum.PaymentSuccess {
+		return nil, errors.New("only successful charges can be refunded")
+	}
+
+	refunded := int64(0)
+	for _, r := range f.refunds[reference] {
+		refunded += r.Amount
+	}
+	if amount <= 0 || refunded+amount > charge.Amount {
+		return nil, errors.New("refund amount exceeds the refundable balance")
+	}
+
+	refund := Refund{
+		ID:        fmt.Sprintf("%s-%d", reference, len(f.refunds[reference])+1),
+		Reference: reference,
+		Amount:    amount,
+		Status:    enum.PaymentReversed,
+	}
+	f.refunds[reference] = append(f.refunds[reference], refund)
+	if key != "" {
+		f.keys[key] = refund
+	}
+
+	return &refund, nil
+}
+
+// FindRefund returns the refund recorded under the key for the charge with the given reference
+func (f *FakeGateway) FindRefund(reference string, key string) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	refund, ok := f.keys[key]
+	if !ok || refund.Reference != reference {
+		return nil, ErrRefundNotFound
+	}
+	return &refund, nil
+}
+
+// SignatureHeader returns the request header carrying the webhook signature
+func (f *FakeGateway) SignatureHeader() string {
+	return "X-Fake-Signature"
+}
+
+// ParseWebhook verifies and decodes a webhook produced by Settle
+func (f *FakeGateway) ParseWebhook(body []byte, signature string) (*Event, error) {
+	if !verify(f.secret, body, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+/*
+Settle moves the charge with the given reference to the given status as if the customer had completed checkout
+
+It returns the webhook body and signature the provider would have sent
+*/
+func (f *FakeGateway) Settle(reference string, status enum.PaymentStatus) ([]byte, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	charge, ok := f.charges[reference]
+	if !ok {
+		return nil, "", ErrTransactionNotFound
+	}
+	charge.Status = status
+
+	body, err := json.Marshal(Event{
+		Reference: charge.Reference,
+		Amount:    charge.Amount,
+		Currency:  charge.Currency,
+		Status:    charge.Status,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, sign(f.secret, body), nil
+}
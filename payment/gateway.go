@@ -0,0 +1,114 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+var (
+	// ErrInvalidSignature is returned when a webhook payload was not signed by the provider
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrTransactionNotFound is returned when the provider has no record of a reference
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrRefundNotFound is returned when the provider has no refund issued under a key
+	ErrRefundNotFound = errors.New("refund not found")
+)
+
+// Gateway is the contract every payment provider adapter must satisfy
+type Gateway interface {
+	// Name returns the provider behind the gateway
+	Name() enum.PaymentProvider
+
+	// Initialize creates a charge and returns the details needed to complete it
+	Initialize(charge Charge) (*Authorization, error)
+
+	// Verify fetches the current state of the charge with the given reference
+	Verify(reference string) (*Transaction, error)
+
+	// Refund returns the given amount of a successful charge to the customer, the key identifies the refund at the provider and only
+	// providers that honour it as an idempotency key pay a repeated call out once, so a refund that may have reached the provider must be
+	// looked up with FindRefund before it is sent again
+	Refund(reference string, amount int64, key string) (*Refund, error)
+
+	// FindRefund returns the refund of the charge with the given reference that was issued under the given key
+	FindRefund(reference string, key string) (*Refund, error)
+
+	// SignatureHeader returns the request header carrying the webhook signature
+	SignatureHeader() string
+
+	// ParseWebhook verifies the signature of a webhook payload and normalizes it into an event
+	ParseWebhook(body []byte, signature string) (*Event, error)
+}
+
+// Charge describes the money to be collected from a customer
+type Charge struct {
+	// the reference shared between the order and the provider
+	Reference string `json:"reference"`
+
+	// the email of the paying customer
+	Email string `json:"email"`
+
+	// the amount to be collected (in the smallest unit of the currency)
+	Amount int64 `json:"amount"`
+
+	// the ISO 4217 currency code of the amount
+	Currency string `json:"currency"`
+
+	// where the provider should send the customer after checkout
+	CallbackURL string `json:"callback_url"`
+
+	// extra details to be stored against the charge
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Authorization holds the details needed to complete a charge
+type Authorization struct {
+	Reference  string `json:"reference"`
+	URL        string `json:"authorization_url"`
+	AccessCode string `json:"access_code"`
+}
+
+// Transaction is the provider's view of a charge
+type Transaction struct {
+	Reference string             `json:"reference"`
+	Amount    int64              `json:"amount"`
+	Currency  string             `json:"currency"`
+	Status    enum.PaymentStatus `json:"status"`
+}
+
+// Refund is the provider's view of a refund
+type Refund struct {
+	ID        string             `json:"id"`
+	Reference string             `json:"reference"`
+	Amount    int64              `json:"amount"`
+	Status    enum.PaymentStatus `json:"status"`
+}
+
+// Event is a normalized webhook notification
+type Event struct {
+	Reference string             `json:"reference"`
+	Amount    int64              `json:"amount"`
+	Currency  string             `json:"currency"`
+	Status    enum.PaymentStatus `json:"status"`
+
+	// whether the notification is about a refund of the charge rather than the charge itself
+	Refund bool `json:"refund"`
+}
+
+// sign computes the hex encoded HMAC-SHA512 of the body using the given secret
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify reports whether the signature matches the body signed with the given secret
+func verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,197 @@
+package payment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+func TestParseWebhookSignature(t *testing.T) {
+
+	const secret = "sk_test_secret"
+
+	paystackBody := []byte(`{"event": "charge.success", "data": {"reference": "REF-1", "amount": 5000, "currency": "NGN", "status": "success"}}`)
+	fakeBody := []byte(`{"reference": "REF-1", "amount": 5000, "currency": "NGN", "status": "SUCCESS"}`)
+
+	tests := []struct {
+		name      string
+		gateway   Gateway
+		body      []byte
+		signature string
+		err       error
+	}{
+		{"paystack signed with the secret", NewPaystackGateway(secret), paystackBody, sign(secret, paystackBody), nil},
+		{"paystack signed with another secret", NewPaystackGateway(secret), paystackBody, sign("sk_test_other", paystackBody), ErrInvalidSignature},
+		{"paystack body changed after signing", NewPaystackGateway(secret), append([]byte(`{"x": 1, `), paystackBody[1:]...), sign(secret, paystackBody), ErrInvalidSignature},
+		{"paystack without a signature", NewPaystackGateway(secret), paystackBody, "", ErrInvalidSignature},
+		{"fake signed with the secret", NewFakeGateway(secret), fakeBody, sign(secret, fakeBody), nil},
+		{"fake signed with another secret", NewFakeGateway(secret), fakeBody, sign("sk_test_other", fakeBody), ErrInvalidSignature},
+		{"fake without a signature", NewFakeGateway(secret), fakeBody, "", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := tt.gateway.ParseWebhook(tt.body, tt.signature)
+			if err != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if event.Reference != "REF-1" || event.Amount != 5000 || event.Currency != "NGN" || event.Status != enum.PaymentSuccess {
+				t.Fatalf("unexpected event: %+v", event)
+			}
+		})
+	}
+}
+
+func TestParsePaystackWebhookEvents(t *testing.T) {
+
+	gateway := NewPaystackGateway("sk_test_secret")
+
+	charge := `{"reference": "REF-1", "amount": 5000, "currency": "NGN"}`
+	refund := func(status string) string {
+		return `{"id": 7, "transaction_reference": "REF-1", "refund_reference": "RF-7", "amount": 5000, "currency": "NGN", "status": "` + status + `"}`
+	}
+
+	tests := []struct {
+		event  string
+		data   string
+		status enum.PaymentStatus
+		refund bool
+	}{
+		{"charge.success", charge, enum.PaymentSuccess, false},
+		{"charge.failed", charge, enum.PaymentFailed, false},
+		{"transfer.success", charge, enum.PaymentPending, false},
+		{"refund.pending", refund("pending"), enum.PaymentPending, true},
+		{"refund.processed", refund("processed"), enum.PaymentReversed, true},
+		{"refund.failed", refund("failed"), enum.PaymentFailed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			body := []byte(`{"event": "` + tt.event + `", "data": ` + tt.data + `}`)
+			event, err := gateway.ParseWebhook(body, sign("sk_test_secret", body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if event.Status != tt.status || event.Refund != tt.refund {
+				t.Fatalf("expected status %s (refund %t), got %s (refund %t)", tt.status, tt.refund, event.Status, event.Refund)
+			}
+			if event.Reference != "REF-1" || event.Amount != 5000 || event.Currency != "NGN" {
+				t.Fatalf("unexpected event: %+v", event)
+			}
+		})
+	}
+}
+
+func TestPaystackRefund(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		code   int
+		body   string
+		status enum.PaymentStatus
+		err    string
+	}{
+		{"processed", http.StatusOK, `{"status": true, "message": "Refund queued", "data": {"id": 7, "amount": 5000, "status": "processed"}}`, enum.PaymentReversed, ""},
+		{"pending", http.StatusOK, `{"status": true, "message": "Refund queued", "data": {"id": 7, "amount": 5000, "status": "pending"}}`, enum.PaymentPending, ""},
+		{"failed", http.StatusOK, `{"status": true, "message": "Refund queued", "data": {"id": 7, "amount": 5000, "status": "failed"}}`, enum.PaymentFailed, ""},
+		{"declined", http.StatusBadRequest, `{"status": false, "message": "Refund amount is more than transaction amount"}`, "", "Refund amount is more than transaction amount"},
+		{"an error page in front of the api", http.StatusBadGateway, `<html><body>502 Bad Gateway</body></html>`, "", "paystack responded with 502 Bad Gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.code)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			gateway := NewPaystackGateway("sk_test_secret")
+			gateway.baseURL = server.URL
+
+			refund, err := gateway.Refund("REF-1", 5000, "refund-1")
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if refund.ID != "7" || refund.Status != tt.status {
+				t.Fatalf("expected refund 7 with status %s, got %+v", tt.status, refund)
+			}
+		})
+	}
+}
+
+func TestFakeSettleIsSignedForParseWebhook(t *testing.T) {
+
+	gateway := NewFakeGateway("sk_test_secret")
+
+	if _, _, err := gateway.Settle("REF-1", enum.PaymentSuccess); err != ErrTransactionNotFound {
+		t.Fatalf("expected %v for an unknown reference, got %v", ErrTransactionNotFound, err)
+	}
+
+	if _, err := gateway.Initialize(Charge{Reference: "REF-1", Amount: 5000, Currency: "NGN"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body, signature, err := gateway.Settle("REF-1", enum.PaymentSuccess)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event, err := gateway.ParseWebhook(body, signature)
+	if err != nil {
+		t.Fatalf("settled webhook was rejected: %v", err)
+	}
+	if event.Reference != "REF-1" || event.Amount != 5000 || event.Currency != "NGN" || event.Status != enum.PaymentSuccess {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+
+	transaction, err := gateway.Verify("REF-1")
+	if err != nil || transaction.Status != enum.PaymentSuccess {
+		t.Fatalf("expected the charge to be settled, got %+v (%v)", transaction, err)
+	}
+}
+
+func TestFakeRefundKey(t *testing.T) {
+
+	gateway := NewFakeGateway("sk_test_secret")
+	if _, err := gateway.Initialize(Charge{Reference: "REF-1", Amount: 5000, Currency: "NGN"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := gateway.Settle("REF-1", enum.PaymentSuccess); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := gateway.FindRefund("REF-1", "refund-1"); err != ErrRefundNotFound {
+		t.Fatalf("expected %v before the refund is issued, got %v", ErrRefundNotFound, err)
+	}
+
+	first, err := gateway.Refund("REF-1", 3000, "refund-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a retry under the same key must not pay out again (it would exceed the charge otherwise)
+	second, err := gateway.Refund("REF-1", 3000, "refund-1")
+	if err != nil || second.ID != first.ID {
+		t.Fatalf("expected the first refund back, got %+v (%v)", second, err)
+	}
+
+	found, err := gateway.FindRefund("REF-1", "refund-1")
+	if err != nil || found.ID != first.ID {
+		t.Fatalf("expected the refund issued under the key, got %+v (%v)", found, err)
+	}
+	if _, err := gateway.FindRefund("REF-2", "refund-1"); err != ErrRefundNotFound {
+		t.Fatalf("expected %v for another charge, got %v", ErrRefundNotFound, err)
+	}
+}
@@ -0,0 +1,284 @@
+package payment
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+const paystackURL = "https://api.paystack.co"
+
+// PaystackGateway talks to the paystack REST api
+type PaystackGateway struct {
+	secret  string
+	baseURL string
+	client  *http.Client
+}
+
+// paystackResponse is the envelope paystack wraps every response in
+type paystackResponse struct {
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// paystackTransaction is the subset of a paystack transaction we rely on
+type paystackTransaction struct {
+	Reference string `json:"reference"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Status    string `json:"status"`
+}
+
+// NewPaystackGateway creates a new gateway authenticated with the given secret key
+func NewPaystackGateway(secret string) *PaystackGateway {
+	return &PaystackGateway{
+		secret:  secret,
+		baseURL: paystackURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider behind the gateway
+func (p *PaystackGateway) Name() enum.PaymentProvider {
+	return enum.Paystack
+}
+
+// Initialize creates a paystack transaction and returns its checkout url
+func (p *PaystackGateway) Initialize(charge Charge) (*Authorization, error) {
+	var data struct {
+		AuthorizationURL string `json:"authorization_url"`
+		AccessCode       string `json:"access_code"`
+		Reference        string `json:"reference"`
+	}
+
+	if err := p.do(http.MethodPost, "/transaction/initialize", map[string]interface{}{
+		"email":        charge.Email,
+		"amount":       charge.Amount,
+		"currency":     charge.Currency,
+		"reference":    charge.Reference,
+		"callback_url": charge.CallbackURL,
+		"metadata":     charge.Metadata,
+	}, &data); err != nil {
+		return nil, err
+	}
+
+	return &Authorization{
+		Reference:  data.Reference,
+		URL:        data.AuthorizationURL,
+		AccessCode: data.AccessCode,
+	}, nil
+}
+
+// Verify fetches the paystack transaction with the given reference
+func (p *PaystackGateway) Verify(reference string) (*Transaction, error) {
+	var data paystackTransaction
+	if err := p.do(http.MethodGet, "/transaction/verify/"+reference, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		Reference: data.Reference,
+		Amount:    data.Amount,
+		Currency:  data.Currency,
+		Status:    paystackStatus(data.Status),
+	}, nil
+}
+
+// paystackRefund is the subset of a paystack refund we rely on
+type paystackRefund struct {
+	ID           int64  `json:"id"`
+	Amount       int64  `json:"amount"`
+	Status       string `json:"status"`
+	MerchantNote string `json:"merchant_note"`
+}
+
+/*
+Refund refunds the given amount of the paystack transaction with the given reference, sending the key as the merchant note of the refund
+so that FindRefund can tell it apart from the other refunds of the transaction
+
+Paystack does not honour idempotency keys, so a refund that may already have reached paystack must be looked up with FindRefund before it is sent again
+*/
+func (p *PaystackGateway) Refund(reference string, amount int64, key string) (*Refund, error) {
+	var data paystackRefund
+
+	if err := p.do(http.MethodPost, "/refund", map[string]interface{}{
+		"transaction":   reference,
+		"amount":        amount,
+		"merchant_note": key,
+	}, &data); err != nil {
+		return nil, err
+	}
+
+	return &Refund{
+		ID:        fmt.Sprintf("%d", data.ID),
+		Reference: reference,
+		Amount:    data.Amount,
+		Status:    paystackRefundStatus(data.Status),
+	}, nil
+}
+
+// FindRefund lists the refunds of the paystack transaction with the given reference and returns the one whose merchant note is the key
+func (p *PaystackGateway) FindRefund(reference string, key string) (*Refund, error) {
+	var data []paystackRefund
+	if err := p.do(http.MethodGet, "/refund?transaction="+url.QueryEscape(reference), nil, &data); err != nil {
+		return nil, err
+	}
+
+	for _, r := range data {
+		if r.MerchantNote == key {
+			return &Refund{
+				ID:        fmt.Sprintf("%d", r.ID),
+				Reference: reference,
+				Amount:    r.Amount,
+				Status:    paystackRefundStatus(r.Status),
+			}, nil
+		}
+	}
+
+	return nil, ErrRefundNotFound
+}
+
+// SignatureHeader returns the request header carrying the webhook signature
+func (p *PaystackGateway) SignatureHeader() string {
+	return "X-Paystack-Signature"
+}
+
+// paystackRefundEvent is the subset of the data of a paystack refund webhook we rely on, it refers to the refunded transaction by its reference
+type paystackRefundEvent struct {
+	TransactionReference string `json:"transaction_reference"`
+	Amount               int64  `json:"amount"`
+	Currency             string `json:"currency"`
+	Status               string `json:"status"`
+}
+
+// ParseWebhook verifies and normalizes a paystack webhook payload, refund events are parsed with their own shape
+func (p *PaystackGateway) ParseWebhook(body []byte, signature string) (*Event, error) {
+	if !verify(p.secret, body, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	var payload struct {
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(payload.Event, "refund.") {
+		var data paystackRefundEvent
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, err
+		}
+
+		return &Event{
+			Reference: data.TransactionReference,
+			Amount:    data.Amount,
+			Currency:  data.Currency,
+			Status:    paystackRefundStatus(data.Status),
+			Refund:    true,
+		}, nil
+	}
+
+	var data paystackTransaction
+	if err := json.Unmarshal(payload.Data, &data); err != nil {
+		return nil, err
+	}
+
+	status := enum.PaymentPending
+	switch payload.Event {
+	case "charge.success":
+		status = enum.PaymentSuccess
+	case "charge.failed":
+		status = enum.PaymentFailed
+	}
+
+	return &Event{
+		Reference: data.Reference,
+		Amount:    data.Amount,
+		Currency:  data.Currency,
+		Status:    status,
+	}, nil
+}
+
+// do sends a request to paystack and decodes the data of the response into v
+func (p *PaystackGateway) do(method, path string, body interface{}, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, p.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.secret)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return ErrTransactionNotFound
+	}
+
+	var envelope paystackResponse
+
+	// errors from in front of the api (e.g a 502 from a proxy) are not json, so the status is checked before the body is trusted
+	if res.StatusCode >= http.StatusBadRequest {
+		if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil || envelope.Message == "" {
+			return fmt.Errorf("paystack responded with %s", res.Status)
+		}
+		return errors.New(envelope.Message)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		return err
+	}
+
+	if !envelope.Status {
+		return errors.New(envelope.Message)
+	}
+
+	return json.Unmarshal(envelope.Data, v)
+}
+
+// paystackStatus maps a paystack transaction status to a payment status
+func paystackStatus(status string) enum.PaymentStatus {
+	switch status {
+	case "success":
+		return enum.PaymentSuccess
+	case "failed", "abandoned":
+		return enum.PaymentFailed
+	case "reversed":
+		return enum.PaymentReversed
+	}
+	return enum.PaymentPending
+}
+
+// paystackRefundStatus maps a paystack refund status to a payment status
+func paystackRefundStatus(status string) enum.PaymentStatus {
+	switch status {
+	case "processed":
+		return enum.PaymentReversed
+	case "failed":
+		return enum.PaymentFailed
+	}
+	return enum.PaymentPending
+}
@@ -1,8 +1,57 @@
 package primer
 
+import "time"
+
 const (
 	MinPassword = 7
 	ZeroValue   = 0
 	HashCost    = 13
 	PageLimit   = 10
+
+	// MaxPageLimit is the most products a page can hold for anyone but an admin (the catalogue is open to unauthenticated callers)
+	MaxPageLimit = 100
+
+	// DefaultCurrency is the ISO 4217 code products, coupons and fixed charges are priced in when no currency is given
+	DefaultCurrency = "NGN"
+
+	// SystemActor is recorded in order history for changes made by background workers
+	SystemActor = "system"
+
+	// DefaultPendingOrderTTL is how long an unpaid order holds stock when PENDING_ORDER_TTL is not set
+	DefaultPendingOrderTTL = 30 * time.Minute
+	// ExpiryInterval is how often stale pending orders are looked for
+	ExpiryInterval = time.Minute
+	// ExpiryBatch is the number of stale pending orders expired per transaction
+	ExpiryBatch = 100
+
+	// RefundRetryAge is how long a refund can stay PENDING without reaching the payment provider before it is handed to the provider again
+	RefundRetryAge = 10 * time.Minute
+	// RefundRetryInterval is how often refunds that never reached the payment provider are looked for
+	RefundRetryInterval = 5 * time.Minute
+	// RefundRetryBatch is the number of refunds handed back to the payment provider per transaction
+	RefundRetryBatch = 50
+
+	// IdempotencyHeader is the request header clients use to make retries safe
+	IdempotencyHeader = "Idempotency-Key"
+	// IdempotencyReplayHeader is set on responses replayed from a stored idempotency key
+	IdempotencyReplayHeader = "Idempotent-Replayed"
+	// MaxIdempotencyKey is the maximum length of an idempotency key
+	MaxIdempotencyKey = 255
+	// IdempotencyKeyTTL is how long a stored idempotency key is honoured
+	IdempotencyKeyTTL = 24 * time.Hour
+	// PurgeInterval is how often expired records are purged
+	PurgeInterval = time.Hour
+	// DeletedProductRetention is how long a deleted product can still be restored before it is purged
+	DeletedProductRetention = 30 * 24 * time.Hour
+
+	// MaxAddresses is the number of addresses a user can keep in their address book
+	MaxAddresses = 20
+
+	// CartHeader is the request header clients use to reach a cart started before logging in
+	CartHeader = "X-Cart-Token"
+	// MaxCartItems is the number of different products a cart can hold (the most an order can take)
+	MaxCartItems = 100
+
+	// MaxCommentLength is the number of characters a comment on an order can hold
+	MaxCommentLength = 2000
 )
@@ -0,0 +1,53 @@
+package receipt
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var page = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Receipt {{ if .Reference }}{{ .Reference }}{{ else }}{{ .OrderID }}{{ end }}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 720px; margin: 40px auto; padding: 0 16px; }
+h1 { font-size: 24px; margin-bottom: 4px; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 8px 4px; border-bottom: 1px solid #ddd; text-align: left; }
+.amount { text-align: right; white-space: nowrap; }
+.meta td { border: none; padding: 2px 4px; }
+tfoot td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Receipt</h1>
+<table class="meta">
+<tr><td>Order</td><td>{{ .OrderID }}</td></tr>
+{{ if .Reference }}<tr><td>Reference</td><td>{{ .Reference }}</td></tr>{{ end }}
+<tr><td>Billed to</td><td>{{ .Email }}</td></tr>
+<tr><td>Status</td><td>{{ .Status }}</td></tr>
+{{ if .CreatedAt }}<tr><td>Placed</td><td>{{ .CreatedAt }}</td></tr>{{ end }}
+{{ if .PaidAt }}<tr><td>Paid</td><td>{{ .PaidAt }}</td></tr>{{ end }}
+<tr><td>Issued</td><td>{{ .IssuedAt }}</td></tr>
+{{ if .ShipTo }}<tr><td>Ship to</td><td>{{ range $i, $part := .ShipTo }}{{ if $i }}<br>{{ end }}{{ $part }}{{ end }}</td></tr>{{ end }}
+</table>
+<table>
+<thead><tr><th>Item</th><th class="amount">Qty</th><th class="amount">Price</th><th class="amount">Total</th></tr></thead>
+<tbody>
+{{ range .Lines }}<tr><td>{{ .Name }}</td><td class="amount">{{ if .Quantity }}{{ .Quantity }}{{ end }}</td><td class="amount">{{ .Price }}</td><td class="amount">{{ .Total }}</td></tr>
+{{ end }}</tbody>
+<tfoot><tr><td colspan="3">Total</td><td class="amount">{{ .Total }}</td></tr></tfoot>
+</table>
+</body>
+</html>
+`))
+
+// HTML renders the receipt as a standalone web page
+func (d *Document) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := page.Execute(&b, d); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
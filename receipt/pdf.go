@@ -0,0 +1,231 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A4 portrait in points, the unit PDF positions are given in
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+	lineHeight = 16.0
+)
+
+// widths of the characters of Helvetica that show up in amounts and headings (in thousandths of the font size),
+// every other character is assumed to be as wide as a digit which is close enough to right align a column
+var widths = map[rune]float64{
+	' ': 278, '.': 278, ',': 278, '-': 333, ':': 278, '(': 333, ')': 333,
+	'A': 667, 'B': 667, 'C': 722, 'D': 722, 'E': 667, 'F': 611, 'G': 778, 'H': 722, 'I': 278,
+	'K': 667, 'L': 556, 'M': 833, 'N': 722, 'O': 778, 'P': 667, 'Q': 778, 'R': 722, 'S': 667,
+	'T': 611, 'U': 722, 'V': 667, 'W': 944, 'X': 667, 'Y': 667, 'Z': 611,
+	'i': 222, 'j': 222, 'l': 222, 'f': 278, 't': 278, 'r': 333, 'm': 833, 'w': 722,
+}
+
+// pdf lays text out on as many pages as it takes, starting a new page whenever the current one is full
+type pdf struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (p *pdf) page() *bytes.Buffer {
+	if len(p.pages) == 0 || p.y < margin {
+		p.pages = append(p.pages, &bytes.Buffer{})
+		p.y = pageHeight - margin
+	}
+	return p.pages[len(p.pages)-1]
+}
+
+// text writes s at x on the current line, right aligned to x when right is set
+func (p *pdf) text(x float64, s string, size float64, bold, right bool) {
+	s = latin1(s)
+	if right {
+		x -= width(s, size)
+	}
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.page(), "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(p.y), escape(s))
+}
+
+// rule draws a horizontal line across the page just below the current line
+func (p *pdf) rule() {
+	y := p.y - 4
+	fmt.Fprintf(p.page(), "0.8 G 0.5 w %s %s m %s %s l S 0 G\n", num(margin), num(y), num(pageWidth-margin), num(y))
+}
+
+// next moves down to the following line
+func (p *pdf) next(lines float64) {
+	p.y -= lineHeight * lines
+}
+
+// PDF renders the receipt as a PDF document using only the fonts every PDF reader ships with
+func (d *Document) PDF() ([]byte, error) {
+
+	p := pdf{}
+
+	p.text(margin, "Receipt", 22, true, false)
+	p.next(2)
+
+	meta := [][2]string{{"Order", d.OrderID}}
+	if d.Reference != "" {
+		meta = append(meta, [2]string{"Reference", d.Reference})
+	}
+	meta = append(meta, [2]string{"Billed to", d.Email}, [2]string{"Status", d.Status})
+	if d.CreatedAt != "" {
+		meta = append(meta, [2]string{"Placed", d.CreatedAt})
+	}
+	if d.PaidAt != "" {
+		meta = append(meta, [2]string{"Paid", d.PaidAt})
+	}
+	meta = append(meta, [2]string{"Issued", d.IssuedAt})
+	for i, part := range d.ShipTo {
+		label := ""
+		if i == 0 {
+			label = "Ship to"
+		}
+		meta = append(meta, [2]string{label, part})
+	}
+
+	for _, m := range meta {
+		p.text(margin, m[0], 10, true, false)
+		p.text(margin+80, m[1], 10, false, false)
+		p.next(1)
+	}
+	p.next(1)
+
+	// columns of the invoice table (amounts are right aligned to the end of their column)
+	qty, price, total := 360.0, 460.0, pageWidth-margin
+
+	header := func() {
+		p.text(margin, "Item", 10, true, false)
+		p.text(qty, "Qty", 10, true, true)
+		p.text(price, "Price", 10, true, true)
+		p.text(total, "Total", 10, true, true)
+		p.rule()
+		p.next(1.5)
+	}
+	header()
+
+	for _, line := range d.Lines {
+		// repeat the table header at the top of every new page
+		if p.y-lineHeight < margin {
+			p.y = 0
+			header()
+		}
+		p.text(margin, clip(line.Name, 260, 10), 10, false, false)
+		if line.Quantity != 0 {
+			p.text(qty, strconv.Itoa(line.Quantity), 10, false, true)
+		}
+		p.text(price, line.Price, 10, false, true)
+		p.text(total, line.Total, 10, false, true)
+		p.next(1.25)
+	}
+
+	p.rule()
+	p.next(1.5)
+	p.text(margin, "Total", 11, true, false)
+	p.text(total, d.Total, 11, true, true)
+
+	return p.bytes(), nil
+}
+
+// bytes assembles the pages into a PDF file
+func (p *pdf) bytes() []byte {
+
+	var b bytes.Buffer
+	offsets := []int{}
+
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	b.WriteString("%PDF-1.4\n")
+
+	// 1: catalog, 2: page tree, 3 and 4: fonts, then a page and its content for every page
+	kids := []string{}
+	for i := range p.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range p.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", num(pageWidth), num(pageHeight), 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return b.Bytes()
+}
+
+// width is how wide s is when set in Helvetica at the given size
+func width(s string, size float64) float64 {
+	w := 0.0
+	for _, r := range s {
+		if cw, ok := widths[r]; ok {
+			w += cw
+		} else {
+			w += 556
+		}
+	}
+	return w * size / 1000
+}
+
+// clip shortens s with an ellipsis so that it fits in the given width
+func clip(s string, max, size float64) string {
+	if width(s, size) <= max {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && width(string(r)+"...", size) > max {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// latin1 replaces the characters the standard fonts cannot show
+func latin1(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xff || (r < 0x20) {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// escape prepares s to be written as a PDF string, which is byte oriented and in the font's encoding
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			if r > 0x7e {
+				fmt.Fprintf(&b, "\\%03o", r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// num formats a number the way PDF operators expect it
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,101 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+)
+
+// Document is everything printed on the receipt of an order, with amounts already formatted in the order's currency
+type Document struct {
+	OrderID   string
+	Reference string
+	Status    string
+	Email     string
+	Currency  enum.Currency
+
+	Lines []Line
+	Total string
+
+	// where the order is delivered to, one line per part of the address
+	ShipTo []string
+
+	CreatedAt string
+	PaidAt    string
+	IssuedAt  string
+}
+
+// Line is a row of the receipt's invoice table
+type Line struct {
+	Name     string
+	Quantity int
+	Price    string
+	Total    string
+}
+
+const timeLayout = "02 Jan 2006, 15:04 MST"
+
+// New prepares the receipt of the order for the buyer with the given email
+func New(order *orderRepository.Order, email string) *Document {
+
+	doc := Document{
+		OrderID:   order.ID,
+		Reference: order.Reference,
+		Status:    order.Status.String(),
+		Email:     email,
+		Currency:  order.Currency,
+		Total:     order.Currency.Format(order.Amount),
+		IssuedAt:  time.Now().UTC().Format(timeLayout),
+	}
+
+	if !order.CreatedAt.IsZero() {
+		doc.CreatedAt = order.CreatedAt.Time.UTC().Format(timeLayout)
+	}
+	if order.Paid && !order.PaidAt.IsZero() {
+		doc.PaidAt = order.PaidAt.Time.UTC().Format(timeLayout)
+	}
+
+	for _, item := range order.Invoice {
+		line := Line{
+			Name:     item.Name,
+			Quantity: item.Quantity,
+			Price:    order.Currency.Format(item.Amount),
+			Total:    order.Currency.Format(item.Amount * int64(item.Quantity)),
+		}
+		// only products are bought in units
+		if !item.IsProduct() {
+			line.Quantity = 0
+			line.Price = ""
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+
+	if a := order.ShippingAddress; a != nil {
+		for _, part := range []string{
+			a.Recipient,
+			a.Phone,
+			a.Line1,
+			a.Line2,
+			strings.Trim(fmt.Sprintf("%s, %s %s", a.City, a.State, a.PostalCode), ", "),
+			a.Country,
+		} {
+			if part = strings.TrimSpace(part); part != "" {
+				doc.ShipTo = append(doc.ShipTo, part)
+			}
+		}
+	}
+
+	return &doc
+}
+
+// Filename is the name the receipt is downloaded as
+func (d *Document) Filename(format enum.ReceiptFormat) string {
+	name := d.Reference
+	if name == "" {
+		name = d.OrderID
+	}
+	return fmt.Sprintf("receipt-%s.%s", name, strings.ToLower(format.String()))
+}
@@ -0,0 +1,169 @@
+package address
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/reflection"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// Prepare trims the address and ensures every field needed to deliver to it is present
+func (a *Address) Prepare() error {
+	a.Label = strings.TrimSpace(a.Label)
+	a.Recipient = strings.TrimSpace(a.Recipient)
+	a.Phone = strings.TrimSpace(a.Phone)
+	a.Line1 = strings.TrimSpace(a.Line1)
+	a.Line2 = strings.TrimSpace(a.Line2)
+	a.City = strings.TrimSpace(a.City)
+	a.State = strings.TrimSpace(a.State)
+	a.PostalCode = strings.TrimSpace(a.PostalCode)
+	a.Country = strings.TrimSpace(a.Country)
+
+	if a.Recipient == "" {
+		return errors.New("address recipient is required")
+	}
+	if a.Phone == "" {
+		return errors.New("address phone number is required")
+	}
+	if a.Line1 == "" {
+		return errors.New("address line1 is required")
+	}
+	if a.City == "" {
+		return errors.New("address city is required")
+	}
+	if a.State == "" {
+		return errors.New("address state is required")
+	}
+	if a.Country == "" {
+		return errors.New("address country is required")
+	}
+
+	return nil
+}
+
+// Snapshot returns a copy of the address as stored on an order
+func (a *Address) Snapshot() *orderRepository.Address {
+	return &orderRepository.Address{
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+/* Fields returns the struct fields as a slice of interface{} values */
+func (a *Address) Fields() []interface{} {
+	return reflection.ReturnStructFields(a)
+}
+
+/*
+Date loads the created_at and updated_at fields of the address if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (a *Address) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if a.CreatedAt.IsZero() {
+			a.CreatedAt = schema.NullTime{Time: time.Now()}
+			a.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		a.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	a.CreatedAt = schema.NullTime{Time: time.Now()}
+	a.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new address into the database using the provided transaction
+
+It returns an error if any
+*/
+func (a *Address) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO addresses `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns an address matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (a *Address) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM addresses WHERE `+query, args...).Scan(context.Background(), a)
+}
+
+/*
+FUByMap finds and returns an address matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (a *Address) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM addresses WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), a)
+}
+
+/*
+UByMapTx updates an address or addresses matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (a *Address) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE addresses `+query, args...).Scan(context.Background(), a)
+	}
+	_, err := tx.NewRaw(`UPDATE addresses `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+DByMapTx deletes an address matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (a *Address) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	_, err := tx.NewRaw(`DELETE FROM addresses WHERE `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all addresses matching the key/value pairs provided in the map, the default address first
+
+It returns an error if any
+*/
+func (a *Addresses) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM addresses WHERE `+query+` ORDER BY addresses.is_default DESC, addresses.created_at ASC`, args...).Scan(context.Background(), a)
+}
+
+/*
+CByMap finds and counts all addresses matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (a *Addresses) CByMap(m types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(m)
+	err := database.PostgreSQLDB.NewRaw(`SELECT count(*) FROM addresses WHERE `+query, args...).Scan(context.Background(), &count)
+	return count, err
+}
@@ -0,0 +1,34 @@
+package address
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type Address struct {
+	bun.BaseModel `bun:"table:addresses" rsf:"false"`
+
+	ID     string `bun:"id,pk" json:"id"`
+	UserID string `bun:"user_id" json:"user_id"`
+
+	// a name the user gives the address e.g home, office
+	Label string `bun:"label" json:"label"`
+
+	// the person receiving the delivery and how to reach them
+	Recipient string `bun:"recipient" json:"recipient"`
+	Phone     string `bun:"phone" json:"phone"`
+
+	Line1      string `bun:"line1" json:"line1"`
+	Line2      string `bun:"line2" json:"line2"`
+	City       string `bun:"city" json:"city"`
+	State      string `bun:"state" json:"state"`
+	PostalCode string `bun:"postal_code" json:"postal_code"`
+	Country    string `bun:"country" json:"country"`
+
+	// the address used when an order does not name one
+	IsDefault bool `bun:"is_default" json:"is_default"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Addresses []Address
@@ -0,0 +1,113 @@
+package cart
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// Scan implements the Scanner interface.
+func (i *Item) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, i)
+	case string:
+		return json.Unmarshal([]byte(v), i)
+	case nil:
+		return nil
+	}
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (i Item) Value() (driver.Value, error) {
+	b, err := json.Marshal(i)
+	return string(b), err
+}
+
+/*
+Date loads the created_at and updated_at fields of the cart if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (c *Cart) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if c.CreatedAt.IsZero() {
+			c.CreatedAt = schema.NullTime{Time: time.Now()}
+			c.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		c.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	c.CreatedAt = schema.NullTime{Time: time.Now()}
+	c.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new cart into the database using the provided transaction unless the user already has one
+
+It returns false if the cart already exists and an error if any
+*/
+func (c *Cart) CreateTx(tx *bun.Tx, m types.SQLMaps) (bool, error) {
+	query, args := database.MapsToIQuery(m)
+	res, err := tx.NewRaw(`INSERT INTO carts `+query+` ON CONFLICT DO NOTHING`, args...).Exec(context.Background())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+/*
+FByMap finds and returns a cart matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (c *Cart) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM carts WHERE `+query, args...).Scan(context.Background(), c)
+}
+
+/*
+FUByMap finds and returns a cart matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (c *Cart) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM carts WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), c)
+}
+
+/*
+UByMapTx updates a cart matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (c *Cart) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE carts `+query, args...).Scan(context.Background(), c)
+	}
+	_, err := tx.NewRaw(`UPDATE carts `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+DByMapTx deletes a cart matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (c *Cart) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	_, err := tx.NewRaw(`DELETE FROM carts WHERE `+query, args...).Exec(context.Background())
+	return err
+}
@@ -0,0 +1,35 @@
+package cart
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type Cart struct {
+	bun.BaseModel `bun:"table:carts" rsf:"false"`
+
+	ID string `bun:"id,pk" json:"id"`
+
+	// the owner of the cart (empty for a cart started before logging in)
+	UserID string `bun:"user_id" json:"user_id"`
+
+	// the secret a client holds to reach its cart before logging in
+	Token string `bun:"token,unique" json:"token"`
+
+	Items []Item `bun:"items,type:jsonb" json:"items" rsfr:"false"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+// schematic representation of an item in a cart
+type Item struct {
+	ProductID string `json:"product_id"`
+	// the variant of the product (empty for a product not sold in variants)
+	VariantID string `json:"variant_id,omitempty"`
+	Quantity  int    `json:"quantity"`
+
+	// the price of the product when the customer last saw it (in the smallest unit of its currency)
+	Price int64 `json:"price"`
+}
+
+type Carts []Cart
@@ -0,0 +1,163 @@
+package category
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Subtree returns the sub query selecting the id of the given category and of every category below it. The id is written into
+the query as is, so it must be an id read back from the database or checked to be a uuid
+
+UNION (rather than UNION ALL) stops the walk at a category already visited, so a broken tree cannot loop forever
+*/
+func Subtree(categoryId string) string {
+	return fmt.Sprintf(`(WITH RECURSIVE subtree AS (SELECT categories.id FROM categories WHERE categories.id = '%s' UNION SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id) SELECT subtree.id FROM subtree)`, categoryId)
+}
+
+/*
+Date loads the created_at and updated_at fields of the category if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (c *Category) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if c.CreatedAt.IsZero() {
+			c.CreatedAt = schema.NullTime{Time: time.Now()}
+			c.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		c.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	c.CreatedAt = schema.NullTime{Time: time.Now()}
+	c.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new category into the database using the provided transaction
+
+It returns an error if any
+*/
+func (c *Category) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO categories `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns a category matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (c *Category) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM categories WHERE `+query, args...).Scan(context.Background(), c)
+}
+
+/*
+FUByMap finds and returns a category matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (c *Category) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM categories WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), c)
+}
+
+/*
+UByMapTx updates a category matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (c *Category) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE categories `+query, args...).Scan(context.Background(), c)
+	}
+	_, err := tx.NewRaw(`UPDATE categories `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+DByMapTx deletes the category matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (c *Category) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	_, err := tx.NewRaw(`DELETE FROM categories WHERE `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all categories matching the key/value pairs provided in the map ordered by name
+
+It returns an error if any
+*/
+func (c *Categories) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM categories WHERE ` + query + ` ORDER BY categories.name ASC`
+	} else {
+		query = `SELECT * FROM categories ORDER BY categories.name ASC`
+	}
+	return database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), c)
+}
+
+/*
+CByMapTx counts all categories matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (c *Categories) CByMapTx(tx *bun.Tx, m types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(m)
+	err := tx.NewRaw(`SELECT count(*) FROM categories WHERE `+query, args...).Scan(context.Background(), &count)
+	return count, err
+}
+
+/*
+CreateTx inserts new links into the database using the provided transaction, skipping the ones that already exist
+
+It returns an error if any
+*/
+func (l *Links) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO product_categories `+query+` ON CONFLICT (product_id, category_id) DO NOTHING`, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns all links matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (l *Links) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM product_categories WHERE `+query+` ORDER BY product_categories.created_at ASC`, args...).Scan(context.Background(), l)
+}
+
+/*
+DByMapTx deletes all links matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (l *Links) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	_, err := tx.NewRaw(`DELETE FROM product_categories WHERE `+query, args...).Exec(context.Background())
+	return err
+}
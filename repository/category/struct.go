@@ -0,0 +1,41 @@
+package category
+
+import (
+	"github.com/uptrace/bun"
+)
+
+// Category is a node of the product taxonomy
+type Category struct {
+	bun.BaseModel `bun:"table:categories" rsf:"false"`
+
+	ID string `bun:"id,pk" json:"id"`
+
+	// the category this one sits under (empty for a top level category)
+	ParentID string `bun:"parent_id" json:"parent_id"`
+
+	Name string `bun:"name" json:"name"`
+
+	// the url friendly name of the category (lower case letters, digits and hyphens)
+	Slug        string `bun:"slug,unique" json:"slug"`
+	Description string `bun:"description" json:"description"`
+
+	// the admin who created the category
+	CreatedBy string `bun:"created_by" json:"created_by"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Categories []Category
+
+// Link puts a product in a category
+type Link struct {
+	bun.BaseModel `bun:"table:product_categories" rsf:"false"`
+
+	ProductID  string `bun:"product_id,unique:product_categories_pair" json:"product_id"`
+	CategoryID string `bun:"category_id,unique:product_categories_pair" json:"category_id"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+}
+
+type Links []Link
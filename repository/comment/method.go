@@ -0,0 +1,53 @@
+package comment
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the comment if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (c *Comment) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if c.CreatedAt.IsZero() {
+			c.CreatedAt = schema.NullTime{Time: time.Now()}
+			c.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		c.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	c.CreatedAt = schema.NullTime{Time: time.Now()}
+	c.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+Create inserts a new comment into the database
+
+It returns an error if any
+*/
+func (c *Comment) Create(m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := database.PostgreSQLDB.NewRaw(`INSERT INTO order_comments `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns all comments matching the key/value pairs provided in the map, oldest first so that they read as a thread
+
+It returns an error if any
+*/
+func (c *Comments) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM order_comments WHERE `+query+` ORDER BY order_comments.created_at ASC`, args...).Scan(context.Background(), c)
+}
@@ -0,0 +1,27 @@
+package comment
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/uptrace/bun"
+)
+
+type Comment struct {
+	bun.BaseModel `bun:"table:order_comments" rsf:"false"`
+
+	ID      string `bun:"id,pk" json:"id"`
+	OrderID string `bun:"order_id" json:"order_id"`
+
+	// who wrote the comment and whether they wrote it as the customer or as an admin
+	AuthorID   string    `bun:"author_id" json:"author_id"`
+	AuthorRole enum.Role `bun:"author_role" json:"author_role"`
+
+	Body string `bun:"body" json:"body"`
+
+	// internal comments are only shown to admins
+	Visibility enum.CommentVisibility `bun:"visibility" json:"visibility"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Comments []Comment
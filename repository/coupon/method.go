@@ -0,0 +1,173 @@
+package coupon
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the coupon if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (c *Coupon) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if c.CreatedAt.IsZero() {
+			c.CreatedAt = schema.NullTime{Time: time.Now()}
+			c.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		c.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	c.CreatedAt = schema.NullTime{Time: time.Now()}
+	c.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+// Usable reports whether the coupon is active and within its start and end dates at the given time
+func (c *Coupon) Usable(at time.Time) bool {
+	if !c.Active {
+		return false
+	}
+	if !c.StartsAt.IsZero() && at.Before(c.StartsAt.Time) {
+		return false
+	}
+	if !c.EndsAt.IsZero() && !at.Before(c.EndsAt.Time) {
+		return false
+	}
+	return true
+}
+
+/*
+CreateTx inserts a new coupon into the database using the provided transaction
+
+It returns an error if any
+*/
+func (c *Coupon) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO coupons `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns a coupon matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (c *Coupon) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM coupons WHERE `+query, args...).Scan(context.Background(), c)
+}
+
+/*
+FUByMap finds and returns a coupon matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (c *Coupon) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM coupons WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), c)
+}
+
+/*
+UByMapTx updates a coupon matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (c *Coupon) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE coupons `+query, args...).Scan(context.Background(), c)
+	}
+	_, err := tx.NewRaw(`UPDATE coupons `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all coupons matching the key/value pairs provided in the map, newest first
+
+It returns an error if any
+*/
+func (c *Coupons) FByMap(m types.SQLMaps, limit, offset int) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM coupons WHERE ` + query + ` ORDER BY coupons.created_at DESC LIMIT ? OFFSET ?`
+	} else {
+		query = `SELECT * FROM coupons ORDER BY coupons.created_at DESC LIMIT ? OFFSET ?`
+	}
+	return database.PostgreSQLDB.NewRaw(query, append(args, limit, offset)...).Scan(context.Background(), c)
+}
+
+/*
+CByMap finds and counts all coupons matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (c *Coupons) CByMap(m types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT count(*) FROM coupons WHERE ` + query
+	} else {
+		query = `SELECT count(*) FROM coupons`
+	}
+	err := database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), &count)
+	return count, err
+}
+
+/*
+CreateTx inserts a new redemption into the database using the provided transaction
+
+It returns an error if any
+*/
+func (r *Redemption) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO coupon_redemptions `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+CByMapTx counts all redemptions matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (r *Redemption) CByMapTx(tx *bun.Tx, m types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(m)
+	err := tx.NewRaw(`SELECT count(*) FROM coupon_redemptions WHERE `+query, args...).Scan(context.Background(), &count)
+	return count, err
+}
+
+/*
+CByMap counts all redemptions matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (r *Redemption) CByMap(m types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(m)
+	err := database.PostgreSQLDB.NewRaw(`SELECT count(*) FROM coupon_redemptions WHERE `+query, args...).Scan(context.Background(), &count)
+	return count, err
+}
+
+/*
+DByMapTx deletes the redemption matching the key/value pairs provided in the map using the provided transaction and loads the deleted row
+
+It returns sql.ErrNoRows if nothing was deleted
+*/
+func (r *Redemption) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`DELETE FROM coupon_redemptions WHERE `+query+` RETURNING *`, args...).Scan(context.Background(), r)
+}
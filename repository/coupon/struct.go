@@ -0,0 +1,70 @@
+package coupon
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/uptrace/bun"
+)
+
+type Coupon struct {
+	bun.BaseModel `bun:"table:coupons" rsf:"false"`
+
+	ID string `bun:"id,pk" json:"id"`
+
+	// the code customers enter at checkout (stored in upper case)
+	Code string          `bun:"code,unique" json:"code"`
+	Type enum.CouponType `bun:"type" json:"type"`
+
+	// the product made free by a free item coupon
+	ProductID string `bun:"product_id" json:"product_id"`
+
+	// the order subtotal needed for the coupon to apply (in the smallest unit of the coupon's currency)
+	MinSpend int64 `bun:"min_spend" json:"min_spend"`
+
+	// how many times the coupon can be used in total and by a single user (zero means no limit)
+	MaxUses        int `bun:"max_uses" json:"max_uses"`
+	MaxUsesPerUser int `bun:"max_uses_per_user" json:"max_uses_per_user"`
+
+	// how many orders currently hold the coupon
+	Uses int `bun:"uses" json:"uses"`
+
+	// when the coupon can be used
+	StartsAt bun.NullTime `bun:"starts_at" json:"starts_at"`
+	EndsAt   bun.NullTime `bun:"ends_at" json:"ends_at"`
+
+	Active bool `bun:"active" json:"active"`
+
+	// the admin who created the coupon
+	CreatedBy string `bun:"created_by" json:"created_by"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+
+	// the currency of a fixed amount and of the minimum spend (the coupon only applies to orders in this currency)
+	Currency enum.Currency `bun:"currency" json:"currency"`
+
+	// the percentage taken off by a percentage coupon
+	Percent float64 `bun:"percent" json:"percent"`
+
+	// the amount taken off by a fixed amount coupon (in the smallest unit of the coupon's currency)
+	Amount int64 `bun:"amount" json:"amount"`
+
+	// the number of units given away by a free item coupon
+	Units int `bun:"units" json:"units"`
+}
+
+type Coupons []Coupon
+
+// Redemption is a use of a coupon by an order
+type Redemption struct {
+	bun.BaseModel `bun:"table:coupon_redemptions" rsf:"false"`
+
+	ID       string `bun:"id,pk" json:"id"`
+	CouponID string `bun:"coupon_id" json:"coupon_id"`
+	UserID   string `bun:"user_id" json:"user_id"`
+	OrderID  string `bun:"order_id,unique" json:"order_id"`
+
+	// the discount given on the order (in the smallest unit of the order's currency)
+	Amount int64 `bun:"amount" json:"amount"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+}
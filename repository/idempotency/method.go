@@ -0,0 +1,60 @@
+package idempotency
+
+import (
+	"context"
+	"strings"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+)
+
+/*
+Create inserts a new idempotency key into the database unless the key has already been used by the same user
+
+It returns false if the key already exists and an error if any
+*/
+func (k *Key) Create(m types.SQLMaps) (bool, error) {
+	query, args := database.MapsToIQuery(m)
+	res, err := database.PostgreSQLDB.NewRaw(`INSERT INTO idempotency_keys `+query+` ON CONFLICT DO NOTHING`, args...).Exec(context.Background())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+/*
+FByMap finds and returns an idempotency key matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (k *Key) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM idempotency_keys WHERE `+query, args...).Scan(context.Background(), k)
+}
+
+/*
+UByMap updates an idempotency key matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (k *Key) UByMap(m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return database.PostgreSQLDB.NewRaw(`UPDATE idempotency_keys `+query, args...).Scan(context.Background(), k)
+	}
+	_, err := database.PostgreSQLDB.NewRaw(`UPDATE idempotency_keys `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+DByMap deletes the idempotency keys matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (k *Key) DByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	_, err := database.PostgreSQLDB.NewRaw(`DELETE FROM idempotency_keys WHERE `+query, args...).Exec(context.Background())
+	return err
+}
@@ -0,0 +1,26 @@
+package idempotency
+
+import (
+	"github.com/uptrace/bun"
+)
+
+type Key struct {
+	bun.BaseModel `bun:"table:idempotency_keys" rsf:"false"`
+
+	// the value of the Idempotency-Key header
+	Key    string `bun:"key,pk" json:"key"`
+	UserID string `bun:"user_id,pk" json:"user_id"`
+
+	Method string `bun:"method" json:"method"`
+	Path   string `bun:"path" json:"path"`
+
+	// SHA256 of the method, path and body of the first request made with the key
+	RequestHash string `bun:"request_hash" json:"request_hash"`
+
+	// the stored response (a zero status code means the first request is still being processed)
+	StatusCode int    `bun:"status_code" json:"status_code"`
+	Response   string `bun:"response" json:"response"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
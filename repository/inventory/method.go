@@ -0,0 +1,65 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// Date loads the created_at field of the movement, movements are never updated so there is no updated_at field
+func (m *Movement) Date() {
+	m.CreatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new movement or movements into the database using the provided transaction
+
+It returns an error if any
+*/
+func (m *Movement) CreateTx(tx *bun.Tx, sm types.SQLMaps) error {
+	query, args := database.MapsToIQuery(sm)
+	if _, err := tx.NewRaw(`INSERT INTO inventory_movements `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns up to "limit" movements matching the key/value pairs provided in the map, newest first
+
+It returns an error if any
+*/
+func (m *Movements) FByMap(sm types.SQLMaps, limit, offset int) error {
+	query, args := database.MapsToWQuery(sm)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM inventory_movements WHERE `+query+` ORDER BY inventory_movements.created_at DESC, inventory_movements.id ASC LIMIT ? OFFSET ?`, append(args, limit, offset)...).Scan(context.Background(), m)
+}
+
+/*
+CByMap finds and counts all movements matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (m *Movements) CByMap(sm types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(sm)
+	err := database.PostgreSQLDB.NewRaw(`SELECT count(*) FROM inventory_movements WHERE `+query, args...).Scan(context.Background(), &count)
+	return count, err
+}
+
+/*
+SumByMapTx adds up the quantities of all movements matching the key/value pairs provided in the map using the provided transaction,
+which is the stock the movements account for
+
+It returns an error if any
+*/
+func (m *Movements) SumByMapTx(tx *bun.Tx, sm types.SQLMaps) (int64, error) {
+	var sum int64
+	query, args := database.MapsToWQuery(sm)
+	err := tx.NewRaw(`SELECT COALESCE(SUM(quantity), 0) FROM inventory_movements WHERE `+query, args...).Scan(context.Background(), &sum)
+	return sum, err
+}
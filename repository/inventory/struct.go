@@ -0,0 +1,36 @@
+package inventory
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/uptrace/bun"
+)
+
+// Movement is a change to the stock of a product or variant, movements are only ever added so that they read as the stock history of the product
+type Movement struct {
+	bun.BaseModel `bun:"table:inventory_movements" rsf:"false"`
+
+	ID        string `bun:"id,pk" json:"id"`
+	ProductID string `bun:"product_id" json:"product_id"`
+
+	// the variant whose stock changed (empty when the stock of the product itself changed)
+	VariantID string `bun:"variant_id" json:"variant_id"`
+
+	Kind enum.MovementKind `bun:"kind" json:"kind"`
+
+	// the number of units added to (positive) or taken from (negative) stock
+	Quantity int64 `bun:"quantity" json:"quantity"`
+
+	// the stock left after the movement
+	Balance int64 `bun:"balance" json:"balance"`
+
+	// the order that caused the movement (empty for movements not caused by an order)
+	OrderID string `bun:"order_id" json:"order_id"`
+
+	// the user (or payment gateway) that caused the movement and why
+	ActorID string `bun:"actor_id" json:"actor_id"`
+	Reason  string `bun:"reason" json:"reason"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+}
+
+type Movements []Movement
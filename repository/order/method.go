@@ -35,6 +35,73 @@ func (i Item) Value() (driver.Value, error) {
 	return string(b), err
 }
 
+// Line returns what the invoice line is for
+func (i Item) Line() enum.InvoiceLine {
+	if i.Metadata == "" {
+		return enum.ProductLine
+	}
+	var metadata LineMetadata
+	if err := json.Unmarshal([]byte(i.Metadata), &metadata); err != nil || metadata.Type == "" {
+		return enum.ProductLine
+	}
+	return metadata.Type
+}
+
+// IsProduct reports whether the invoice line is for units of a product
+func (i Item) IsProduct() bool {
+	return i.Line() == enum.ProductLine
+}
+
+// ProductID returns the id of the product a product line is for (the key of the line unless it is for a variant)
+func (i Item) ProductID() string {
+	if i.Metadata == "" {
+		return i.Key
+	}
+	var metadata LineMetadata
+	if err := json.Unmarshal([]byte(i.Metadata), &metadata); err != nil || metadata.ProductID == "" {
+		return i.Key
+	}
+	return metadata.ProductID
+}
+
+// VariantID returns the id of the variant a product line is for (empty when the line is for a product without variants)
+func (i Item) VariantID() string {
+	if i.ProductID() == i.Key {
+		return ""
+	}
+	return i.Key
+}
+
+// Scan implements the Scanner interface.
+func (a *Address) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case nil:
+		return nil
+	}
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (a Address) Value() (driver.Value, error) {
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// ProductLines returns the lines of the order's invoice that are for units of a product
+func (o *Order) ProductLines() []Item {
+	lines := []Item{}
+	for _, item := range o.Invoice {
+		if item.IsProduct() {
+			lines = append(lines, item)
+		}
+	}
+	return lines
+}
+
 /* Fields returns the struct fields as a slice of interface{} values */
 func (o *Order) Fields() []interface{} {
 	return reflection.ReturnStructFields(o)
@@ -215,3 +282,53 @@ func (o *Orders) CByMap(m types.SQLMaps) (int, error) {
 	err := database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), &count)
 	return count, err
 }
+
+/*
+FUByMap finds and returns up to "limit" orders matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching rows to be locked
+(rows already locked by other transactions are skipped so that concurrent workers never pick up the same orders)
+
+By default, only the id and user_id fields are loaded
+
+The	"preloadandjoin" parameter can be used to request that all the fields of the struct be loaded
+
+It returns an error if any
+*/
+func (o *Orders) FUByMap(tx *bun.Tx, m types.SQLMaps, limit int, preloadandjoin ...bool) error {
+	query, args := database.MapsToWQuery(m)
+	if len(preloadandjoin) > 0 && preloadandjoin[0] {
+		return tx.NewRaw(`SELECT * FROM orders WHERE `+query+` ORDER BY orders.created_at ASC LIMIT ? FOR UPDATE SKIP LOCKED`, append(args, limit)...).Scan(context.Background(), o)
+	}
+	return tx.NewRaw(`SELECT id, user_id FROM orders WHERE `+query+` ORDER BY orders.created_at ASC LIMIT ? FOR UPDATE SKIP LOCKED`, append(args, limit)...).Scan(context.Background(), o)
+}
+
+/*
+EachByMap finds all orders matching the key/value pairs provided in the map, oldest first, and hands them to fn one at a time as they are read
+so that any number of orders can be walked through without holding them all in memory
+
+It stops at and returns the first error returned by fn or the database
+*/
+func (o *Orders) EachByMap(ctx context.Context, m types.SQLMaps, fn func(order *Order) error) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM orders WHERE ` + query + ` ORDER BY orders.created_at ASC, orders.id ASC`
+	} else {
+		query = `SELECT * FROM orders ORDER BY orders.created_at ASC, orders.id ASC`
+	}
+
+	rows, err := database.PostgreSQLDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var order Order
+		// bun scans by column name and decodes the jsonb columns (history, invoice, shipping address) which plain rows.Scan cannot
+		if err := database.PostgreSQLDB.ScanRow(ctx, rows, &order); err != nil {
+			return err
+		}
+		if err := fn(&order); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -29,8 +29,8 @@ type Order struct {
 	// items that make up the total amount
 	Invoice []Item `bun:"invoice,type:jsonb" json:"invoice" rsfr:"false"`
 
-	// the total amount to be paid (including all possible fees)
-	Amount float64 `bun:"amount" json:"amount"`
+	// the total amount to be paid (including all possible fees) in the smallest unit of the order's currency
+	Amount int64 `bun:"amount" json:"amount"`
 
 	Remark string `bun:"remark" json:"remark"`
 
@@ -38,6 +38,22 @@ type Order struct {
 
 	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
 	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+
+	// true once the units on the invoice have been returned to stock
+	StockReleased bool `bun:"stock_released" json:"stock_released"`
+
+	// where the order is delivered to (a copy of the address at the time the order was placed)
+	ShippingAddress *Address `bun:"shipping_address,type:jsonb" json:"shipping_address" rsfr:"false"`
+
+	// the coupon applied to the order if any
+	CouponCode string `bun:"coupon_code" json:"coupon_code"`
+
+	// the currency every amount on the order is in
+	Currency enum.Currency `bun:"currency" json:"currency"`
+
+	// true once an admin has rejected the order, whatever its status is now
+	Rejected   bool         `bun:"rejected" json:"rejected"`
+	RejectedAt bun.NullTime `bun:"rejected_at" json:"rejected_at"`
 }
 
 // schematic representation of an item in a order's invoice
@@ -48,8 +64,8 @@ type Item struct {
 	// the name of the item
 	Name string `json:"name"`
 
-	// the amount of the item
-	Amount float64 `json:"amount"`
+	// the amount of the item (in the smallest unit of the order's currency)
+	Amount int64 `json:"amount"`
 
 	// the quantity of the item
 	Quantity int `json:"quantity"`
@@ -58,4 +74,41 @@ type Item struct {
 	Metadata string `json:"metadata"`
 }
 
+// schematic representation of the metadata of an invoice line that is not a product
+type LineMetadata struct {
+	// what the line is for
+	Type enum.InvoiceLine `json:"type"`
+
+	// the coupon the discount was given for
+	CouponID   string `json:"coupon_id,omitempty"`
+	CouponCode string `json:"coupon_code,omitempty"`
+
+	// the pricing rule a tax or fee was charged by, and for a percentage rule its rate and the amount it was charged on (the amount of
+	// a fixed rule is the amount of the line)
+	RuleID string         `json:"rule_id,omitempty"`
+	Basis  enum.RuleBasis `json:"basis,omitempty"`
+	Rate   float64        `json:"rate,omitempty"`
+	Base   int64          `json:"base,omitempty"`
+
+	// the price and currency of a product charged in another currency than it is priced in
+	Price    int64         `json:"price,omitempty"`
+	Currency enum.Currency `json:"currency,omitempty"`
+
+	// the product and sku of a line for a variant (the key of the line is then the id of the variant)
+	ProductID string `json:"product_id,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+}
+
+// schematic representation of the address an order is delivered to
+type Address struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
 type Orders []Order
@@ -0,0 +1,102 @@
+package orderitem
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the order item if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (i *Item) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if i.CreatedAt.IsZero() {
+			i.CreatedAt = schema.NullTime{Time: time.Now()}
+			i.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		i.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	i.CreatedAt = schema.NullTime{Time: time.Now()}
+	i.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new order item or order items into the database using the provided transaction
+
+It returns an error if any
+*/
+func (i *Item) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO order_items `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FUByMap finds and returns an order item matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (i *Item) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM order_items WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), i)
+}
+
+/*
+UByMapTx updates the order items matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (i *Item) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE order_items `+query, args...).Scan(context.Background(), i)
+	}
+	_, err := tx.NewRaw(`UPDATE order_items `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns up to "limit" order items matching the key/value pairs provided in the map, newest first
+
+It returns an error if any
+*/
+func (i *Items) FByMap(m types.SQLMaps, limit, offset int) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM order_items WHERE ` + query + ` ORDER BY order_items.created_at DESC, order_items.id ASC LIMIT ? OFFSET ?`
+	} else {
+		query = `SELECT * FROM order_items ORDER BY order_items.created_at DESC, order_items.id ASC LIMIT ? OFFSET ?`
+	}
+	return database.PostgreSQLDB.NewRaw(query, append(args, limit, offset)...).Scan(context.Background(), i)
+}
+
+/*
+CByMap finds and counts all order items matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (i *Items) CByMap(m types.SQLMaps) (int, error) {
+	var count int
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT count(*) FROM order_items WHERE ` + query
+	} else {
+		query = `SELECT count(*) FROM order_items`
+	}
+	err := database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), &count)
+	return count, err
+}
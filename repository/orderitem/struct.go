@@ -0,0 +1,33 @@
+package orderitem
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/uptrace/bun"
+)
+
+// Item is a product line of an order's invoice kept in its own row so that orders can be looked up and tracked by product
+type Item struct {
+	bun.BaseModel `bun:"table:order_items" rsf:"false"`
+
+	ID        string `bun:"id,pk" json:"id"`
+	OrderID   string `bun:"order_id" json:"order_id"`
+	ProductID string `bun:"product_id" json:"product_id"`
+
+	// the name of the product when the order was placed
+	Name string `bun:"name" json:"name"`
+
+	// the unit price charged in the smallest unit of the order's currency
+	Price    int64         `bun:"price" json:"price"`
+	Quantity int           `bun:"quantity" json:"quantity"`
+	Currency enum.Currency `bun:"currency" json:"currency"`
+
+	Status enum.ItemStatus `bun:"status" json:"status"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+
+	// the variant of the product the line is for (empty for a product not sold in variants)
+	VariantID string `bun:"variant_id" json:"variant_id"`
+}
+
+type Items []Item
@@ -0,0 +1,176 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the rule if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (r *Rule) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = schema.NullTime{Time: time.Now()}
+			r.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		r.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	r.CreatedAt = schema.NullTime{Time: time.Now()}
+	r.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+// Matches reports whether the rule applies to orders in the given currency delivered to the given country and state
+func (r *Rule) Matches(currency enum.Currency, country, state string) bool {
+	if r.Basis == enum.Flat && r.Currency != currency {
+		return false
+	}
+	if r.Country != "" && !strings.EqualFold(r.Country, strings.TrimSpace(country)) {
+		return false
+	}
+	if r.State != "" && !strings.EqualFold(r.State, strings.TrimSpace(state)) {
+		return false
+	}
+	return true
+}
+
+/*
+CreateTx inserts a new rule into the database using the provided transaction
+
+It returns an error if any
+*/
+func (r *Rule) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO pricing_rules `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FUByMap finds and returns a rule matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (r *Rule) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM pricing_rules WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), r)
+}
+
+/*
+UByMapTx updates a rule matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (r *Rule) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE pricing_rules `+query, args...).Scan(context.Background(), r)
+	}
+	_, err := tx.NewRaw(`UPDATE pricing_rules `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all rules matching the key/value pairs provided in the map in the order they were created
+
+It returns an error if any
+*/
+func (r *Rules) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM pricing_rules WHERE ` + query + ` ORDER BY pricing_rules.created_at ASC`
+	} else {
+		query = `SELECT * FROM pricing_rules ORDER BY pricing_rules.created_at ASC`
+	}
+	return database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), r)
+}
+
+/*
+Date loads the created_at and updated_at fields of the rate if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (r *Rate) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = schema.NullTime{Time: time.Now()}
+			r.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		r.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	r.CreatedAt = schema.NullTime{Time: time.Now()}
+	r.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+UpsertTx inserts a rate into the database using the provided transaction, replacing the rate already set for the currency pair
+
+It returns an error if any
+*/
+func (r *Rate) UpsertTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	return tx.NewRaw(`INSERT INTO exchange_rates `+query+` ON CONFLICT (base, quote) DO UPDATE SET rate = EXCLUDED.rate, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at RETURNING *`, args...).Scan(context.Background(), r)
+}
+
+/*
+DByMapTx deletes a rate matching the key/value pairs provided in the map using the provided transaction
+
+It returns sql.ErrNoRows if no rate matched and an error if any
+*/
+func (r *Rate) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`DELETE FROM exchange_rates WHERE `+query+` RETURNING *`, args...).Scan(context.Background(), r)
+}
+
+/*
+FByMap finds and returns all rates matching the key/value pairs provided in the map ordered by currency pair
+
+It returns an error if any
+*/
+func (r *Rates) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM exchange_rates WHERE ` + query + ` ORDER BY exchange_rates.base ASC, exchange_rates.quote ASC`
+	} else {
+		query = `SELECT * FROM exchange_rates ORDER BY exchange_rates.base ASC, exchange_rates.quote ASC`
+	}
+	return database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), r)
+}
+
+/*
+Convert returns an amount in the smallest unit of the "from" currency in the smallest unit of the "to" currency, using the
+rate set for the pair or the inverse of the rate set for the opposite pair
+
+It returns false if no rate connects the two currencies
+*/
+func (r Rates) Convert(amount int64, from, to enum.Currency) (int64, bool) {
+	if from == to {
+		return amount, true
+	}
+	for _, rate := range r {
+		if rate.Base == from && rate.Quote == to && rate.Rate > 0 {
+			return to.Minor(from.Major(amount) * rate.Rate), true
+		}
+	}
+	for _, rate := range r {
+		if rate.Base == to && rate.Quote == from && rate.Rate > 0 {
+			return to.Minor(from.Major(amount) / rate.Rate), true
+		}
+	}
+	return 0, false
+}
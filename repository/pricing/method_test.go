@@ -0,0 +1,63 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+func TestRatesConvert(t *testing.T) {
+
+	// one dollar costs 1500 naira, the opposite pair is found through the inverse of the rate
+	rates := Rates{{Base: enum.USD, Quote: enum.NGN, Rate: 1500}}
+
+	tests := []struct {
+		name   string
+		rates  Rates
+		amount int64
+		from   enum.Currency
+		to     enum.Currency
+		want   int64
+		ok     bool
+	}{
+		{"the same currency", nil, 4999, enum.NGN, enum.NGN, 4999, true},
+		{"along the pair", rates, 1000, enum.USD, enum.NGN, 1500000, true},
+		{"against the pair", rates, 1500000, enum.NGN, enum.USD, 1000, true},
+		{"against the pair rounded half away from zero", rates, 2250, enum.NGN, enum.USD, 2, true},
+		{"against the pair below the smallest unit", rates, 700, enum.NGN, enum.USD, 0, true},
+		{"without a rate", nil, 1000, enum.USD, enum.NGN, 0, false},
+		{"with a rate that was never set", Rates{{Base: enum.USD, Quote: enum.NGN}}, 1000, enum.USD, enum.NGN, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rates.Convert(tt.amount, tt.from, tt.to)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("expected %d (%t), got %d (%t)", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestRatesConvertInversePairs(t *testing.T) {
+
+	// a rate set for either pair gives the same conversions both ways
+	pairs := []Rates{
+		{{Base: enum.USD, Quote: enum.NGN, Rate: 1600}},
+		{{Base: enum.NGN, Quote: enum.USD, Rate: 1.0 / 1600}},
+	}
+
+	for _, rates := range pairs {
+		for _, amount := range []int64{100, 2599, 1000000} {
+			naira, ok := rates.Convert(amount, enum.USD, enum.NGN)
+			if !ok || naira != amount*1600 {
+				t.Fatalf("expected %d cents to be %d kobo, got %d (%t)", amount, amount*1600, naira, ok)
+			}
+
+			cents, ok := rates.Convert(naira, enum.NGN, enum.USD)
+			if !ok || cents != amount {
+				t.Fatalf("expected %d kobo to convert back to %d cents, got %d (%t)", naira, amount, cents, ok)
+			}
+		}
+	}
+}
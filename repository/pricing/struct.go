@@ -0,0 +1,62 @@
+package pricing
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/uptrace/bun"
+)
+
+type Rule struct {
+	bun.BaseModel `bun:"table:pricing_rules" rsf:"false"`
+
+	ID string `bun:"id,pk" json:"id"`
+
+	// the name shown on the invoice line e.g VAT, delivery fee
+	Name string           `bun:"name" json:"name"`
+	Kind enum.PricingRule `bun:"kind" json:"kind"`
+
+	// whether the rule charges a percentage of the subtotal or a fixed amount, and the percentage charged by a percentage rule
+	Basis enum.RuleBasis `bun:"basis" json:"basis"`
+	Rate  float64        `bun:"rate" json:"rate"`
+
+	// the region the rule applies to (empty means everywhere)
+	Country string `bun:"country" json:"country"`
+	State   string `bun:"state" json:"state"`
+
+	Active bool `bun:"active" json:"active"`
+
+	// the admin who created the rule
+	CreatedBy string `bun:"created_by" json:"created_by"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+
+	// the currency a fixed rule charges in (fixed rules only apply to orders in this currency)
+	Currency enum.Currency `bun:"currency" json:"currency"`
+
+	// the category the rule is limited to (empty means every product), a percentage rule then only charges on the products in the category or below it
+	CategoryID string `bun:"category_id" json:"category_id"`
+
+	// the amount charged by a fixed rule (in the smallest unit of the rule's currency)
+	Amount int64 `bun:"amount" json:"amount"`
+}
+
+type Rules []Rule
+
+// Rate is the price of one unit of the base currency in the quote currency, used to charge for products in a currency they are not priced in
+type Rate struct {
+	bun.BaseModel `bun:"table:exchange_rates" rsf:"false"`
+
+	ID string `bun:"id,pk" json:"id"`
+
+	Base  enum.Currency `bun:"base,unique:exchange_rates_pair" json:"base"`
+	Quote enum.Currency `bun:"quote,unique:exchange_rates_pair" json:"quote"`
+	Rate  float64       `bun:"rate" json:"rate"`
+
+	// the admin who last set the rate
+	UpdatedBy string `bun:"updated_by" json:"updated_by"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Rates []Rate
@@ -148,6 +148,21 @@ func (p *Product) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
 	return err
 }
 
+/*
+UByMapTx updates the products matching the key/value pairs provided in the map using the provided transaction, the updated
+products are loaded when a returning map is provided
+
+It returns an error if any
+*/
+func (p *Products) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE products `+query, args...).Scan(context.Background(), p)
+	}
+	_, err := tx.NewRaw(`UPDATE products `+query, args...).Exec(context.Background())
+	return err
+}
+
 /*
 CByMap finds and counts all products matching the key/value pairs provided in the map
 
@@ -253,3 +268,13 @@ func (p *Products) FByMap(m types.SQLMaps, limit, offset int, sort string, prelo
 	}
 	return database.PostgreSQLDB.NewRaw(query, append(args, limit, offset)...).Scan(context.Background(), p)
 }
+
+/*
+DByMapTx deletes the products matching the key/value pairs provided in the map using the provided transaction and loads the deleted products
+
+It returns an error if any
+*/
+func (p *Products) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`DELETE FROM products WHERE `+query+` RETURNING *`, args...).Scan(context.Background(), p)
+}
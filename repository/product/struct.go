@@ -2,6 +2,7 @@ package product
 
 import (
 	"github.com/funmi4194/ecommerce/enum"
+	variantRepository "github.com/funmi4194/ecommerce/repository/variant"
 	"github.com/uptrace/bun"
 )
 
@@ -9,13 +10,25 @@ type Product struct {
 	bun.BaseModel `bun:"table:products" rsf:"false"`
 	ID            string             `bun:"id,pk" json:"id"`
 	Name          string             `bun:"name" json:"name"`
-	Price         float64            `bun:"price" json:"price"`
+	Price         int64              `bun:"price" json:"price"`
 	Stock         int64              `bun:"stock" json:"stock"`
 	ProductUrl    string             `bun:"product_url" json:"product_url"`
 	Status        enum.ProductStatus `bun:"status" json:"status" rsfr:"false"`
 	Description   string             `bun:"description" json:"description"`
 	CreatedAt     bun.NullTime       `bun:"created_at" json:"created_at" rsfr:"false"`
 	UpdatedAt     bun.NullTime       `bun:"updated_at" json:"updated_at" rsfr:"false"`
+
+	// the currency the price is in
+	Currency enum.Currency `bun:"currency" json:"currency"`
+
+	// when the product was deleted (deleted products are ARCHIVED and kept for the orders that reference them until they are purged)
+	DeletedAt bun.NullTime `bun:"deleted_at" json:"deleted_at" rsfr:"false"`
+
+	// the ids of the categories the product is listed in (kept in product_categories)
+	CategoryIds []string `bun:"-" json:"category_ids" rsf:"false"`
+
+	// the versions of the product sold under their own sku, price and stock (kept in product_variants)
+	Variants variantRepository.Variants `bun:"-" json:"variants" rsf:"false"`
 }
 
 type Products []Product
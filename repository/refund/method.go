@@ -0,0 +1,105 @@
+package refund
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the refund if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (r *Refund) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = schema.NullTime{Time: time.Now()}
+			r.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		r.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	r.CreatedAt = schema.NullTime{Time: time.Now()}
+	r.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new refund into the database using the provided transaction
+
+It returns an error if any
+*/
+func (r *Refund) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO refunds `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+UByMap updates a refund matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (r *Refund) UByMap(m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return database.PostgreSQLDB.NewRaw(`UPDATE refunds `+query, args...).Scan(context.Background(), r)
+	}
+	_, err := database.PostgreSQLDB.NewRaw(`UPDATE refunds `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+UByMapTx updates a refund matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (r *Refund) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE refunds `+query, args...).Scan(context.Background(), r)
+	}
+	_, err := tx.NewRaw(`UPDATE refunds `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMapTx finds and returns all refunds matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (r *Refunds) FByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM refunds WHERE `+query+` ORDER BY refunds.created_at ASC`, args...).Scan(context.Background(), r)
+}
+
+/*
+FByMap finds and returns all refunds matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (r *Refunds) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM refunds WHERE `+query+` ORDER BY refunds.created_at ASC`, args...).Scan(context.Background(), r)
+}
+
+/*
+FUByMap finds and locks up to "limit" refunds matching the key/value pairs provided in the map using the provided transaction, oldest first,
+skipping the rows already locked by another transaction
+
+It returns an error if any
+*/
+func (r *Refunds) FUByMap(tx *bun.Tx, m types.SQLMaps, limit int) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM refunds WHERE `+query+` ORDER BY refunds.created_at ASC LIMIT ? FOR UPDATE SKIP LOCKED`, append(args, limit)...).Scan(context.Background(), r)
+}
@@ -0,0 +1,37 @@
+package refund
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/uptrace/bun"
+)
+
+type Refund struct {
+	bun.BaseModel `bun:"table:refunds" rsf:"false"`
+
+	ID      string `bun:"id,pk" json:"id"`
+	OrderID string `bun:"order_id" json:"order_id"`
+
+	// the amount returned to the customer (in the smallest unit of the order's currency)
+	Amount int64  `bun:"amount" json:"amount"`
+	Reason string `bun:"reason" json:"reason"`
+
+	// invoice items returned to stock as part of the refund
+	Items []orderRepository.Item `bun:"items,type:jsonb" json:"items" rsfr:"false"`
+
+	// the id and status of the refund at the payment provider, the status is PENDING until the provider has been asked to pay
+	// the refund out and FAILED if it declined to
+	ProviderID string `bun:"provider_id" json:"provider_id"`
+	Status     string `bun:"status" json:"status"`
+
+	// the admin who issued the refund
+	CreatedBy string `bun:"created_by" json:"created_by"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+
+	// the status the order had before the refund, it is moved back to it if the provider declines the refund
+	OrderStatus enum.OrderStatus `bun:"order_status" json:"order_status"`
+}
+
+type Refunds []Refund
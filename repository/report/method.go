@@ -0,0 +1,119 @@
+package report
+
+import (
+	"context"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+)
+
+// where prefixes the filter with WHERE when there is one
+func where(m types.SQLMaps, extra ...string) (string, []interface{}) {
+	query, args := database.MapsToWQuery(m)
+	for _, e := range extra {
+		if query != "" {
+			query = `(` + query + `) AND ` + e
+		} else {
+			query = e
+		}
+	}
+	if query != "" {
+		query = ` WHERE ` + query
+	}
+	return query, args
+}
+
+/*
+FByMap groups the orders matching the key/value pairs provided in the map by the period they were placed in and their currency, oldest period first
+(refunds are counted against the period of the order they were issued on, leaving out those declined by the payment provider)
+
+It returns an error if any
+*/
+func (s *Sales) FByMap(m types.SQLMaps, period enum.ReportPeriod) error {
+	query, args := where(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT date_trunc(?, orders.created_at) AS period, orders.currency,
+		COUNT(*) AS orders,
+		COUNT(*) FILTER (WHERE orders.paid) AS paid_orders,
+		COALESCE(SUM(orders.amount) FILTER (WHERE orders.paid), 0) AS revenue,
+		COALESCE(SUM(refunded.amount), 0) AS refunded,
+		COALESCE(SUM(orders.amount) FILTER (WHERE orders.paid), 0) - COALESCE(SUM(refunded.amount), 0) AS net_revenue
+		FROM orders
+		LEFT JOIN (SELECT order_id, SUM(amount) AS amount FROM refunds WHERE status <> 'FAILED' GROUP BY order_id) AS refunded ON refunded.order_id = orders.id`+query+`
+		GROUP BY 1, 2 ORDER BY 1 ASC, 2 ASC`, append([]interface{}{period.Trunc()}, args...)...).Scan(context.Background(), s)
+}
+
+/*
+FByMap ranks the products on the invoices of paid orders matching the key/value pairs provided in the map by the units sold, returning up to "limit" of them
+(a product sold in several currencies is ranked once per currency)
+
+It returns an error if any
+*/
+func (p *ProductSales) FByMap(m types.SQLMaps, limit int) error {
+	// lines without metadata are product lines, the CASE keeps the cast to jsonb away from them (lines for a variant name their product in the metadata)
+	query, args := where(m, `orders.paid = true`, `CASE WHEN COALESCE(line->>'metadata', '') = '' THEN 'PRODUCT' ELSE COALESCE((line->>'metadata')::jsonb->>'type', 'PRODUCT') END = 'PRODUCT'`)
+	return database.PostgreSQLDB.NewRaw(`SELECT CASE WHEN COALESCE(line->>'metadata', '') = '' THEN line->>'key' ELSE COALESCE(NULLIF((line->>'metadata')::jsonb->>'product_id', ''), line->>'key') END AS product_id,
+		MAX(line->>'name') AS name, orders.currency,
+		SUM((line->>'quantity')::bigint) AS quantity,
+		SUM((line->>'amount')::bigint * (line->>'quantity')::bigint) AS revenue,
+		COUNT(DISTINCT orders.id) AS orders
+		FROM orders CROSS JOIN LATERAL jsonb_array_elements(orders.invoice) AS line`+query+`
+		GROUP BY 1, 3 ORDER BY quantity DESC, revenue DESC, product_id ASC LIMIT ?`, append(args, limit)...).Scan(context.Background(), p)
+}
+
+/*
+FByMap groups the order items of paid orders matching the key/value pairs provided in the map by the period the order was placed in,
+product and currency, oldest period first (cancelled lines are left out)
+
+It returns an error if any
+*/
+func (p *ProductPeriods) FByMap(m types.SQLMaps, period enum.ReportPeriod) error {
+	query, args := where(m, `orders.paid = true`, `order_items.status <> 'CANCELLED'`)
+	return database.PostgreSQLDB.NewRaw(`SELECT date_trunc(?, orders.created_at) AS period, order_items.product_id, MAX(order_items.name) AS name, order_items.currency,
+		SUM(order_items.quantity) AS quantity,
+		SUM(order_items.price * order_items.quantity) AS revenue,
+		COUNT(DISTINCT orders.id) AS orders
+		FROM order_items JOIN orders ON orders.id = order_items.order_id`+query+`
+		GROUP BY 1, 2, 4 ORDER BY 1 ASC, quantity DESC, 2 ASC`, append([]interface{}{period.Trunc()}, args...)...).Scan(context.Background(), p)
+}
+
+/*
+FByMap counts the orders matching the key/value pairs provided in the map in each status
+
+It returns an error if any
+*/
+func (s *StatusCounts) FByMap(m types.SQLMaps) error {
+	query, args := where(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT orders.status, COUNT(*) AS orders FROM orders`+query+` GROUP BY 1 ORDER BY 2 DESC, 1 ASC`, args...).Scan(context.Background(), s)
+}
+
+/*
+FByMap totals the paid orders matching the key/value pairs provided in the map by currency, along with what was refunded on them
+(leaving out the refunds declined by the payment provider)
+
+It returns an error if any
+*/
+func (c *CurrencyTotals) FByMap(m types.SQLMaps) error {
+	query, args := where(m, `orders.paid = true`)
+	return database.PostgreSQLDB.NewRaw(`SELECT orders.currency, COUNT(*) AS paid_orders, COALESCE(SUM(orders.amount), 0) AS revenue,
+		COALESCE(SUM(refunded.amount), 0) AS refunded,
+		COALESCE(SUM(orders.amount), 0) - COALESCE(SUM(refunded.amount), 0) AS net_revenue,
+		ROUND(COALESCE(AVG(orders.amount), 0))::bigint AS average_order_value
+		FROM orders
+		LEFT JOIN (SELECT order_id, SUM(amount) AS amount FROM refunds WHERE status <> 'FAILED' GROUP BY order_id) AS refunded ON refunded.order_id = orders.id`+query+`
+		GROUP BY 1 ORDER BY 1 ASC`, args...).Scan(context.Background(), c)
+}
+
+/*
+FByMap counts the orders matching the key/value pairs provided in the map along with the ones that were cancelled (by the customer or an admin)
+or rejected by an admin, whatever their status is now
+
+It returns an error if any
+*/
+func (o *Outcome) FByMap(m types.SQLMaps) error {
+	query, args := where(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT COUNT(*) AS orders,
+		COUNT(*) FILTER (WHERE orders.cancelled) AS cancelled,
+		COUNT(*) FILTER (WHERE orders.rejected) AS rejected
+		FROM orders`+query, args...).Scan(context.Background(), o)
+}
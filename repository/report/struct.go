@@ -0,0 +1,80 @@
+package report
+
+import (
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+// Sale is what orders placed in a period brought in, in one currency (amounts are in the smallest unit of the currency)
+type Sale struct {
+	Period   time.Time     `bun:"period" json:"period"`
+	Currency enum.Currency `bun:"currency" json:"currency"`
+
+	// every order placed in the period and the ones among them that were paid for
+	Orders     int `bun:"orders" json:"orders"`
+	PaidOrders int `bun:"paid_orders" json:"paid_orders"`
+
+	// the amount of the paid orders, what has since been refunded on them and the difference
+	Revenue    int64 `bun:"revenue" json:"revenue"`
+	Refunded   int64 `bun:"refunded" json:"refunded"`
+	NetRevenue int64 `bun:"net_revenue" json:"net_revenue"`
+}
+
+type Sales []Sale
+
+// ProductSale is how much of a product was sold in one currency across paid orders
+type ProductSale struct {
+	ProductID string        `bun:"product_id" json:"product_id"`
+	Name      string        `bun:"name" json:"name"`
+	Currency  enum.Currency `bun:"currency" json:"currency"`
+	Quantity  int64         `bun:"quantity" json:"quantity"`
+	Revenue   int64         `bun:"revenue" json:"revenue"`
+	Orders    int           `bun:"orders" json:"orders"`
+}
+
+type ProductSales []ProductSale
+
+// ProductPeriod is how much of a product was sold in one currency in a period, read from the order items of paid orders
+type ProductPeriod struct {
+	Period    time.Time     `bun:"period" json:"period"`
+	ProductID string        `bun:"product_id" json:"product_id"`
+	Name      string        `bun:"name" json:"name"`
+	Currency  enum.Currency `bun:"currency" json:"currency"`
+	Quantity  int64         `bun:"quantity" json:"quantity"`
+	Revenue   int64         `bun:"revenue" json:"revenue"`
+	Orders    int           `bun:"orders" json:"orders"`
+}
+
+type ProductPeriods []ProductPeriod
+
+// StatusCount is how many orders are in a status
+type StatusCount struct {
+	Status enum.OrderStatus `bun:"status" json:"status"`
+	Orders int              `bun:"orders" json:"orders"`
+}
+
+type StatusCounts []StatusCount
+
+// CurrencyTotal is what paid orders in one currency add up to
+type CurrencyTotal struct {
+	Currency   enum.Currency `bun:"currency" json:"currency"`
+	PaidOrders int           `bun:"paid_orders" json:"paid_orders"`
+
+	// the amount of the paid orders (gross), what has since been refunded on them and the difference
+	Revenue    int64 `bun:"revenue" json:"revenue"`
+	Refunded   int64 `bun:"refunded" json:"refunded"`
+	NetRevenue int64 `bun:"net_revenue" json:"net_revenue"`
+
+	// Revenue / PaidOrders rounded to the nearest smallest unit
+	AverageOrderValue int64 `bun:"average_order_value" json:"average_order_value"`
+}
+
+type CurrencyTotals []CurrencyTotal
+
+// Outcome counts the orders that did not go through
+type Outcome struct {
+	Orders    int `bun:"orders" json:"orders"`
+	Cancelled int `bun:"cancelled" json:"cancelled"`
+	Rejected  int `bun:"rejected" json:"rejected"`
+}
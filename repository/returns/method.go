@@ -0,0 +1,95 @@
+package returns
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the return if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (r *Return) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = schema.NullTime{Time: time.Now()}
+			r.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		r.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	r.CreatedAt = schema.NullTime{Time: time.Now()}
+	r.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new return into the database using the provided transaction
+
+It returns an error if any
+*/
+func (r *Return) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO order_returns `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FUByMap finds and returns a return matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (r *Return) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM order_returns WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), r)
+}
+
+/*
+UByMapTx updates a return matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (r *Return) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE order_returns `+query, args...).Scan(context.Background(), r)
+	}
+	_, err := tx.NewRaw(`UPDATE order_returns `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all returns matching the key/value pairs provided in the map, newest first
+
+It returns an error if any
+*/
+func (r *Returns) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	if query != "" {
+		query = `SELECT * FROM order_returns WHERE ` + query + ` ORDER BY order_returns.created_at DESC`
+	} else {
+		query = `SELECT * FROM order_returns ORDER BY order_returns.created_at DESC`
+	}
+	return database.PostgreSQLDB.NewRaw(query, args...).Scan(context.Background(), r)
+}
+
+/*
+FByMapTx finds and returns all returns matching the key/value pairs provided in the map using the provided transaction, oldest first
+
+It returns an error if any
+*/
+func (r *Returns) FByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM order_returns WHERE `+query+` ORDER BY order_returns.created_at ASC`, args...).Scan(context.Background(), r)
+}
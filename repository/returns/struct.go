@@ -0,0 +1,37 @@
+package returns
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/uptrace/bun"
+)
+
+type Return struct {
+	bun.BaseModel `bun:"table:order_returns" rsf:"false"`
+
+	ID      string `bun:"id,pk" json:"id"`
+	OrderID string `bun:"order_id" json:"order_id"`
+	UserID  string `bun:"user_id" json:"user_id"`
+
+	// invoice items (and how many of each) the customer is sending back
+	Items []orderRepository.Item `bun:"items,type:jsonb" json:"items" rsfr:"false"`
+
+	// why the customer is sending the items back
+	Reason string            `bun:"reason" json:"reason"`
+	Status enum.ReturnStatus `bun:"status" json:"status"`
+
+	// the admin who approved or rejected the return, when and what they said
+	ReviewedBy string       `bun:"reviewed_by" json:"reviewed_by"`
+	ReviewedAt bun.NullTime `bun:"reviewed_at" json:"reviewed_at"`
+	Note       string       `bun:"note" json:"note"`
+
+	ReceivedAt bun.NullTime `bun:"received_at" json:"received_at"`
+
+	// the refund issued when the items were received if any
+	RefundID string `bun:"refund_id" json:"refund_id"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Returns []Return
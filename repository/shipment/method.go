@@ -0,0 +1,90 @@
+package shipment
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+/*
+Date loads the created_at and updated_at fields of the shipment if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (s *Shipment) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if s.CreatedAt.IsZero() {
+			s.CreatedAt = schema.NullTime{Time: time.Now()}
+			s.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		s.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	s.CreatedAt = schema.NullTime{Time: time.Now()}
+	s.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new shipment into the database using the provided transaction
+
+It returns an error if any
+*/
+func (s *Shipment) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO shipments `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FUByMap finds and returns a shipment matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (s *Shipment) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM shipments WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), s)
+}
+
+/*
+UByMapTx updates a shipment matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (s *Shipment) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE shipments `+query, args...).Scan(context.Background(), s)
+	}
+	_, err := tx.NewRaw(`UPDATE shipments `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all shipments matching the key/value pairs provided in the map, oldest first
+
+It returns an error if any
+*/
+func (s *Shipments) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM shipments WHERE `+query+` ORDER BY shipments.created_at ASC`, args...).Scan(context.Background(), s)
+}
+
+/*
+FByMapTx finds and returns all shipments matching the key/value pairs provided in the map using the provided transaction, oldest first
+
+It returns an error if any
+*/
+func (s *Shipments) FByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM shipments WHERE `+query+` ORDER BY shipments.created_at ASC`, args...).Scan(context.Background(), s)
+}
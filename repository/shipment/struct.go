@@ -0,0 +1,32 @@
+package shipment
+
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	orderRepository "github.com/funmi4194/ecommerce/repository/order"
+	"github.com/uptrace/bun"
+)
+
+type Shipment struct {
+	bun.BaseModel `bun:"table:shipments" rsf:"false"`
+
+	ID      string `bun:"id,pk" json:"id"`
+	OrderID string `bun:"order_id" json:"order_id"`
+
+	// invoice items (and how many of each) that left in the shipment
+	Items []orderRepository.Item `bun:"items,type:jsonb" json:"items" rsfr:"false"`
+
+	Carrier        string              `bun:"carrier" json:"carrier"`
+	TrackingNumber string              `bun:"tracking_number" json:"tracking_number"`
+	Status         enum.ShipmentStatus `bun:"status" json:"status"`
+
+	ShippedAt   bun.NullTime `bun:"shipped_at" json:"shipped_at"`
+	DeliveredAt bun.NullTime `bun:"delivered_at" json:"delivered_at"`
+
+	// the admin who dispatched the shipment
+	CreatedBy string `bun:"created_by" json:"created_by"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Shipments []Shipment
@@ -0,0 +1,168 @@
+package variant
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/funmi4194/ecommerce/database"
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// Scan implements the Scanner interface.
+func (o *Options) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case nil:
+		return nil
+	}
+	return nil
+}
+
+// Value implements the driver Valuer interface.
+func (o Options) Value() (driver.Value, error) {
+	if o == nil {
+		o = Options{}
+	}
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
+// Types returns the option types sorted by name
+func (o Options) Types() []string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Label returns the option values in the order of their types e.g "Red / M"
+func (o Options) Label() string {
+	values := []string{}
+	for _, name := range o.Types() {
+		values = append(values, o[name])
+	}
+	return strings.Join(values, " / ")
+}
+
+// Same reports whether the options take the same values, ignoring case
+func (o Options) Same(other Options) bool {
+	if len(o) != len(other) {
+		return false
+	}
+	for name, value := range o {
+		if !strings.EqualFold(other[name], value) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+Date loads the created_at and updated_at fields of the variant if not already present, otherwise, it loads the updated_at field only.
+
+If the "pessimistic" parameter is set to true, it loads both fields regardless
+*/
+func (v *Variant) Date(pessimistic ...bool) {
+	if len(pessimistic) > 0 && !pessimistic[0] {
+		if v.CreatedAt.IsZero() {
+			v.CreatedAt = schema.NullTime{Time: time.Now()}
+			v.UpdatedAt = schema.NullTime{Time: time.Now()}
+			return
+		}
+		v.UpdatedAt = schema.NullTime{Time: time.Now()}
+		return
+	}
+	v.CreatedAt = schema.NullTime{Time: time.Now()}
+	v.UpdatedAt = schema.NullTime{Time: time.Now()}
+}
+
+/*
+CreateTx inserts a new variant into the database using the provided transaction
+
+It returns an error if any
+*/
+func (v *Variant) CreateTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToIQuery(m)
+	if _, err := tx.NewRaw(`INSERT INTO product_variants `+query, args...).Exec(context.Background()); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+/*
+FByMap finds and returns a variant matching the key/value pairs provided in the map
+
+It returns an error if any
+*/
+func (v *Variant) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM product_variants WHERE `+query, args...).Scan(context.Background(), v)
+}
+
+/*
+FUByMap finds and returns a variant matching the key/value pairs provided in the map for the purpose of an update thereby causing the matching row to be locked
+
+It returns an error if any
+*/
+func (v *Variant) FUByMap(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM product_variants WHERE `+query+` FOR UPDATE`, args...).Scan(context.Background(), v)
+}
+
+/*
+UByMapTx updates a variant matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (v *Variant) UByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToSQuery(m)
+	if strings.Contains(query, string(enum.RETURNING)) {
+		return tx.NewRaw(`UPDATE product_variants `+query, args...).Scan(context.Background(), v)
+	}
+	_, err := tx.NewRaw(`UPDATE product_variants `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+DByMapTx deletes the variant matching the key/value pairs provided in the map using the provided transaction
+
+It returns an error if any
+*/
+func (v *Variant) DByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	_, err := tx.NewRaw(`DELETE FROM product_variants WHERE `+query, args...).Exec(context.Background())
+	return err
+}
+
+/*
+FByMap finds and returns all variants matching the key/value pairs provided in the map in the order they were created
+
+It returns an error if any
+*/
+func (v *Variants) FByMap(m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return database.PostgreSQLDB.NewRaw(`SELECT * FROM product_variants WHERE `+query+` ORDER BY product_variants.created_at ASC`, args...).Scan(context.Background(), v)
+}
+
+/*
+FByMapTx finds and returns all variants matching the key/value pairs provided in the map in the order they were created using the provided transaction
+
+It returns an error if any
+*/
+func (v *Variants) FByMapTx(tx *bun.Tx, m types.SQLMaps) error {
+	query, args := database.MapsToWQuery(m)
+	return tx.NewRaw(`SELECT * FROM product_variants WHERE `+query+` ORDER BY product_variants.created_at ASC`, args...).Scan(context.Background(), v)
+}
@@ -0,0 +1,33 @@
+package variant
+
+import (
+	"github.com/uptrace/bun"
+)
+
+// Variant is a version of a product (e.g a size and colour of a shirt) sold under its own sku, price and stock
+type Variant struct {
+	bun.BaseModel `bun:"table:product_variants" rsf:"false"`
+
+	ID        string `bun:"id,pk" json:"id"`
+	ProductID string `bun:"product_id" json:"product_id"`
+
+	// the stock keeping unit code of the variant (stored in upper case)
+	SKU string `bun:"sku,unique" json:"sku"`
+
+	// the value of each option type of the product e.g {"size": "M", "colour": "Red"}
+	Options Options `bun:"options,type:jsonb" json:"options" rsfr:"false"`
+
+	// in the smallest unit of the currency of its product
+	Price int64 `bun:"price" json:"price"`
+	Stock int64 `bun:"stock" json:"stock"`
+
+	ImageUrl string `bun:"image_url" json:"image_url"`
+
+	CreatedAt bun.NullTime `bun:"created_at" json:"created_at" rsfr:"false"`
+	UpdatedAt bun.NullTime `bun:"updated_at" json:"updated_at" rsfr:"false"`
+}
+
+type Variants []Variant
+
+// Options maps the option types of a product to the values a variant takes
+type Options map[string]string
@@ -0,0 +1,26 @@
+package cart
+
+import (
+	cartController "github.com/funmi4194/ecommerce/controller/cart"
+	"github.com/opensaucerer/barf"
+)
+
+// RegisterCartRoutes registers the routes open to guests, who reach their cart by token
+func RegisterCartRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/cart")
+
+	frame.Get("/view", cartController.Cart)
+	frame.Post("/items/add", cartController.AddItem)
+	frame.Patch("/items/update", cartController.UpdateItem)
+	frame.Delete("/items/remove", cartController.RemoveItem)
+}
+
+// RegisterCheckoutRoutes registers the cart routes that need a signed in user
+func RegisterCheckoutRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/cart")
+
+	frame.Post("/checkout", cartController.Checkout)
+	frame.Post("/merge", cartController.MergeCart)
+}
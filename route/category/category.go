@@ -0,0 +1,16 @@
+package category
+
+import (
+	categoryController "github.com/funmi4194/ecommerce/controller/category"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterCategoryRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/categories")
+
+	frame.Post("/create", categoryController.CreateCategory)
+	frame.Patch("/update", categoryController.UpdateCategory)
+	frame.Delete("/delete", categoryController.DeleteCategory)
+	frame.Get("/list", categoryController.Categories)
+}
@@ -0,0 +1,15 @@
+package coupon
+
+import (
+	couponController "github.com/funmi4194/ecommerce/controller/coupon"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterCouponRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/coupons")
+
+	frame.Post("/create", couponController.CreateCoupon)
+	frame.Patch("/update", couponController.UpdateCoupon)
+	frame.Post("/list", couponController.Coupons)
+}
@@ -0,0 +1,15 @@
+package inventory
+
+import (
+	inventoryController "github.com/funmi4194/ecommerce/controller/inventory"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterInventoryRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/inventory")
+
+	frame.Post("/adjust", inventoryController.Adjust)
+	frame.Post("/movements", inventoryController.Movements)
+	frame.Post("/reconcile", inventoryController.Reconcile)
+}
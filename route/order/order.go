@@ -10,7 +10,24 @@ func RegisterOrderRoutes(frame *barf.SubRoute) {
 	frame = frame.RetroFrame("/order")
 
 	frame.Post("/create", orderController.InitiateOrder)
+	frame.Post("/quote", orderController.PriceOrder)
+	frame.Post("/reorder", orderController.Reorder)
 	frame.Post("/list", orderController.Orders)
+	frame.Post("/export", orderController.ExportOrders)
 	frame.Patch("/update", orderController.UpdateOrder)
 	frame.Patch("/cancel", orderController.CancelOrder)
+	frame.Post("/pay", orderController.Pay)
+	frame.Post("/refund", orderController.RefundOrder)
+	frame.Post("/shipments/create", orderController.CreateShipment)
+	frame.Patch("/shipments/deliver", orderController.DeliverShipment)
+	frame.Get("/shipments", orderController.Shipments)
+	frame.Post("/returns/open", orderController.OpenReturn)
+	frame.Patch("/returns/review", orderController.ReviewReturn)
+	frame.Patch("/returns/receive", orderController.ReceiveReturn)
+	frame.Get("/returns", orderController.Returns)
+	frame.Get("/receipt", orderController.Receipt)
+	frame.Post("/comments/add", orderController.AddComment)
+	frame.Get("/comments", orderController.Comments)
+	frame.Post("/items/list", orderController.OrderItems)
+	frame.Patch("/items/update", orderController.UpdateOrderItem)
 }
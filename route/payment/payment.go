@@ -0,0 +1,13 @@
+package payment
+
+import (
+	paymentController "github.com/funmi4194/ecommerce/controller/payment"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterPaymentRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/payments")
+
+	frame.Post("/webhook", paymentController.Webhook)
+}
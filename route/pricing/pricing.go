@@ -0,0 +1,15 @@
+package pricing
+
+import (
+	pricingController "github.com/funmi4194/ecommerce/controller/pricing"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterPricingRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/pricing/rules")
+
+	frame.Post("/create", pricingController.CreateRule)
+	frame.Patch("/update", pricingController.UpdateRule)
+	frame.Get("/list", pricingController.Rules)
+}
@@ -0,0 +1,15 @@
+package pricing
+
+import (
+	pricingController "github.com/funmi4194/ecommerce/controller/pricing"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterRateRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/pricing/rates")
+
+	frame.Post("/set", pricingController.SetRate)
+	frame.Delete("/delete", pricingController.DeleteRate)
+	frame.Get("/list", pricingController.Rates)
+}
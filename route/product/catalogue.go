@@ -0,0 +1,16 @@
+package product
+
+import (
+	productController "github.com/funmi4194/ecommerce/controller/product"
+	"github.com/opensaucerer/barf"
+)
+
+// RegisterCatalogueRoutes registers the read only routes open to shoppers who have not signed in
+func RegisterCatalogueRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/catalogue")
+
+	frame.Post("/products", productController.Catalogue)
+	frame.Get("/product", productController.CatalogueProduct)
+	frame.Get("/categories", productController.CatalogueCategories)
+}
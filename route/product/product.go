@@ -13,5 +13,6 @@ func RegisterProductRoutes(frame *barf.SubRoute) {
 	frame.Patch("/update", productController.UpdateProduct)
 	frame.Get("/product", productController.Product)
 	frame.Delete("/delete", productController.DeleteProduct)
+	frame.Patch("/restore", productController.RestoreProducts)
 	frame.Post("/list", productController.Products)
 }
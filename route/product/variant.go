@@ -0,0 +1,15 @@
+package product
+
+import (
+	productController "github.com/funmi4194/ecommerce/controller/product"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterVariantRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/products/variants")
+
+	frame.Post("/create", productController.CreateVariant)
+	frame.Patch("/update", productController.UpdateVariant)
+	frame.Delete("/delete", productController.DeleteVariant)
+}
@@ -0,0 +1,16 @@
+package report
+
+import (
+	reportController "github.com/funmi4194/ecommerce/controller/report"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterReportRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/reports")
+
+	frame.Post("/sales", reportController.Sales)
+	frame.Post("/products", reportController.TopProducts)
+	frame.Post("/products/sales", reportController.ProductSales)
+	frame.Post("/summary", reportController.Summary)
+}
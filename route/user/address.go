@@ -0,0 +1,16 @@
+package user
+
+import (
+	userController "github.com/funmi4194/ecommerce/controller/user"
+	"github.com/opensaucerer/barf"
+)
+
+func RegisterAddressRoutes(frame *barf.SubRoute) {
+
+	frame = frame.RetroFrame("/accounts/addresses")
+
+	frame.Post("/create", userController.CreateAddress)
+	frame.Get("/list", userController.Addresses)
+	frame.Patch("/update", userController.UpdateAddress)
+	frame.Delete("/delete", userController.DeleteAddress)
+}
@@ -0,0 +1,36 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+	idempotencyRepository "github.com/funmi4194/ecommerce/repository/idempotency"
+	"github.com/funmi4194/ecommerce/types"
+	"github.com/opensaucerer/barf"
+)
+
+// PurgeIdempotencyKeys starts a background worker that deletes idempotency keys older than the given ttl every interval
+func PurgeIdempotencyKeys(ttl, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			var key idempotencyRepository.Key
+			if err := key.DByMap(types.SQLMaps{
+				WMaps: []types.SQLMap{
+					{
+						Map: map[string]interface{}{
+							"created_at": time.Now().Add(-ttl),
+						},
+						JoinOperator:       enum.And,
+						ComparisonOperator: enum.LessThan,
+					},
+				},
+				WJoinOperator: enum.And,
+			}); err != nil {
+				barf.Logger().Errorf(`[scheduler.PurgeIdempotencyKeys] [key.DByMap(types.SQLMaps{] %s`, err.Error())
+			}
+		}
+	}()
+}
@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"time"
+
+	orderLogic "github.com/funmi4194/ecommerce/logic/order"
+	"github.com/opensaucerer/barf"
+)
+
+// ExpirePendingOrders starts a background worker that cancels unpaid pending orders older than the given ttl every interval
+func ExpirePendingOrders(ttl, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			expired, err := orderLogic.ExpireOrders(ttl)
+			if err != nil {
+				barf.Logger().Errorf(`[scheduler.ExpirePendingOrders] [orderLogic.ExpireOrders(ttl)] %s`, err.Error())
+			}
+			if expired > 0 {
+				barf.Logger().Infof(`[scheduler.ExpirePendingOrders] expired %d pending order(s)`, expired)
+			}
+		}
+	}()
+}
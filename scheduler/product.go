@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"time"
+
+	productLogic "github.com/funmi4194/ecommerce/logic/product"
+	"github.com/opensaucerer/barf"
+)
+
+// PurgeDeletedProducts starts a background worker that permanently deletes products deleted more than the given retention ago every interval
+func PurgeDeletedProducts(retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			purged, err := productLogic.PurgeProducts(retention)
+			if err != nil {
+				barf.Logger().Errorf(`[scheduler.PurgeDeletedProducts] [productLogic.PurgeProducts(retention)] %s`, err.Error())
+			}
+			if purged > 0 {
+				barf.Logger().Infof(`[scheduler.PurgeDeletedProducts] purged %d deleted product(s)`, purged)
+			}
+		}
+	}()
+}
@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"time"
+
+	orderLogic "github.com/funmi4194/ecommerce/logic/order"
+	"github.com/opensaucerer/barf"
+)
+
+// RetryPendingRefunds starts a background worker that hands refunds left PENDING for longer than the given age back to the payment provider every interval
+func RetryPendingRefunds(age, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			retried, err := orderLogic.RetryRefunds(age)
+			if err != nil {
+				barf.Logger().Errorf(`[scheduler.RetryPendingRefunds] [orderLogic.RetryRefunds(age)] %s`, err.Error())
+			}
+			if retried > 0 {
+				barf.Logger().Infof(`[scheduler.RetryPendingRefunds] retried %d pending refund(s)`, retried)
+			}
+		}
+	}()
+}
@@ -0,0 +1,32 @@
+package types
+
+type Address struct {
+	Label      string `json:"label"`
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	IsDefault  bool   `json:"is_default"`
+}
+
+type UpdateAddress struct {
+	AddressId  string  `json:"address_id"`
+	Label      *string `json:"label"`
+	Recipient  *string `json:"recipient"`
+	Phone      *string `json:"phone"`
+	Line1      *string `json:"line1"`
+	Line2      *string `json:"line2"`
+	City       *string `json:"city"`
+	State      *string `json:"state"`
+	PostalCode *string `json:"postal_code"`
+	Country    *string `json:"country"`
+	IsDefault  *bool   `json:"is_default"`
+}
+
+type DeleteAddress struct {
+	AddressId string `json:"address_id"`
+}
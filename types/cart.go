@@ -0,0 +1,27 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type CartItem struct {
+	ProductId string `json:"product_id"`
+	// the variant of the product (required for products sold in variants)
+	VariantId string `json:"variant_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type RemoveCartItem struct {
+	ProductId string `json:"product_id"`
+	VariantId string `json:"variant_id"`
+}
+
+type CartCheckout struct {
+	// the saved address to deliver to (defaults to the user's default address)
+	AddressId string `json:"address_id"`
+	// an address to deliver to without saving it to the address book
+	Address *Address `json:"address"`
+
+	// the coupon to apply to the order
+	CouponCode string `json:"coupon_code"`
+	// the currency to pay in (needed when the cart holds products priced in different currencies)
+	Currency enum.Currency `json:"currency"`
+}
@@ -0,0 +1,23 @@
+package types
+
+type CreateCategory struct {
+	Name string `json:"name"`
+	// generated from the name when not provided
+	Slug string `json:"slug"`
+	// the category it sits under (empty for a top level category)
+	ParentId    string `json:"parent_id"`
+	Description string `json:"description"`
+}
+
+type UpdateCategory struct {
+	CategoryId string  `json:"category_id"`
+	Name       *string `json:"name"`
+	Slug       *string `json:"slug"`
+	// an empty parent moves the category to the top level
+	ParentId    *string `json:"parent_id"`
+	Description *string `json:"description"`
+}
+
+type DeleteCategory struct {
+	CategoryId string `json:"category_id"`
+}
@@ -0,0 +1,14 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type AddComment struct {
+	OrderId string `json:"order_id"`
+	Body    string `json:"body"`
+	// only admins can choose, INTERNAL (default for admins) or CUSTOMER (always used for customers)
+	Visibility enum.CommentVisibility `json:"visibility"`
+}
+
+type ListComments struct {
+	OrderId string `json:"order_id"`
+}
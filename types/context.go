@@ -1,3 +1,6 @@
 package types
 
 type AuthCtxKey struct{}
+
+// StreamCtxKey holds the connection's own http.ResponseWriter for handlers that stream (barf buffers whatever is written through it)
+type StreamCtxKey struct{}
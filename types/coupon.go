@@ -0,0 +1,53 @@
+package types
+
+import (
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+type CreateCoupon struct {
+	Code string          `json:"code"`
+	Type enum.CouponType `json:"type"`
+	// the percentage taken off by a percentage coupon
+	Percent float64 `json:"percent"`
+	// the amount taken off by a fixed amount coupon (in the smallest unit of the currency)
+	Amount int64 `json:"amount"`
+	// the number of units given away by a free item coupon (defaults to 1)
+	Units int `json:"units"`
+	// the product made free by a free item coupon
+	ProductId string `json:"product_id"`
+	// in the smallest unit of the currency (kobo, cents)
+	MinSpend int64 `json:"min_spend"`
+	// the currency of a fixed amount and of the minimum spend (defaults to NGN)
+	Currency       enum.Currency `json:"currency"`
+	MaxUses        int           `json:"max_uses"`
+	MaxUsesPerUser int           `json:"max_uses_per_user"`
+	StartsAt       *time.Time    `json:"starts_at"`
+	EndsAt         *time.Time    `json:"ends_at"`
+	// defaults to true
+	Active *bool `json:"active"`
+}
+
+type UpdateCoupon struct {
+	CouponId       string         `json:"coupon_id"`
+	Percent        *float64       `json:"percent"`
+	Amount         *int64         `json:"amount"`
+	Units          *int           `json:"units"`
+	MinSpend       *int64         `json:"min_spend"`
+	Currency       *enum.Currency `json:"currency"`
+	MaxUses        *int           `json:"max_uses"`
+	MaxUsesPerUser *int           `json:"max_uses_per_user"`
+	StartsAt       *time.Time     `json:"starts_at"`
+	EndsAt         *time.Time     `json:"ends_at"`
+	Active         *bool          `json:"active"`
+}
+
+type CouponFilter struct {
+	Code   string `json:"code"`
+	Active *bool  `json:"active"`
+
+	// pagination
+	Page  *int `json:"page"`
+	Limit *int `json:"limit"`
+}
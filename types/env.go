@@ -1,6 +1,9 @@
 package types
 
-import "github.com/funmi4194/ecommerce/primitive"
+import (
+	"github.com/funmi4194/ecommerce/enum"
+	"github.com/funmi4194/ecommerce/primitive"
+)
 
 type Env struct {
 	// Port for the server to listen on
@@ -21,4 +24,12 @@ type Env struct {
 	GoogleApplicationCredentials string `barfenv:"key=GOOGLE_APPLICATION_CREDENTIALS;required=true"`
 	// OriginalBucket is the bucket for the cloud storage
 	OriginalBucket string `barfenv:"key=ORIGINAL_BUCKET;required=true"`
+	// PaymentProvider is the provider orders are charged through (paystack)
+	PaymentProvider enum.PaymentProvider `barfenv:"key=PAYMENT_PROVIDER;required=true"`
+	// PaymentSecretKey authenticates api calls to the payment provider and signs its webhooks
+	PaymentSecretKey string `barfenv:"key=PAYMENT_SECRET_KEY;required=true"`
+	// PaymentCallbackURL is where customers are sent after completing checkout
+	PaymentCallbackURL string `barfenv:"key=PAYMENT_CALLBACK_URL;required=false"`
+	// PendingOrderTTL is how long an unpaid order may stay pending before it is expired (eg. 30m, 2h)
+	PendingOrderTTL string `barfenv:"key=PENDING_ORDER_TTL;required=false"`
 }
@@ -0,0 +1,35 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type AdjustStock struct {
+	ProductId string `json:"product_id"`
+	// the variant whose stock is adjusted (the product's own stock is adjusted when empty)
+	VariantId string `json:"variant_id"`
+	// the number of units to add (positive) or take away (negative)
+	Quantity int64 `json:"quantity"`
+	// RESTOCK for units received or ADJUSTMENT (default) for a correction e.g after a stock count
+	Kind   enum.MovementKind `json:"kind"`
+	Reason string            `json:"reason"`
+}
+
+type MovementFilter struct {
+	ProductId string `json:"product_id"`
+	// only the movements of this variant (all movements of the product and its variants when empty)
+	VariantId string            `json:"variant_id"`
+	Kind      enum.MovementKind `json:"kind"`
+	OrderId   string            `json:"order_id"`
+
+	// pagination
+	Page  *int `json:"page"`
+	Limit *int `json:"limit"`
+	// when true, the response will contain the pagination metadata
+	Paginate bool `json:"paginate"`
+}
+
+type ReconcileStock struct {
+	ProductId string `json:"product_id"`
+	// when true, an ADJUSTMENT is recorded for any stock the movements do not account for so that they add up to the stock again
+	Apply  bool   `json:"apply"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type UpdateOrderItem struct {
+	ItemId string          `json:"item_id"`
+	Status enum.ItemStatus `json:"status"`
+	// why the line is being moved, recorded in the order's history
+	Note string `json:"note"`
+}
+
+type OrderItemFilter struct {
+	OrderId   string          `json:"order_id"`
+	ProductId string          `json:"product_id"`
+	Status    enum.ItemStatus `json:"status"`
+
+	// pagination
+	Page  *int `json:"page"`
+	Limit *int `json:"limit"`
+	// when true, the response will contain the pagination metadata
+	Paginate bool `json:"paginate"`
+}
@@ -8,13 +8,41 @@ import (
 
 type InitiateOrder struct {
 	Items []Item `json:"items"`
+
+	// the saved address to deliver to (defaults to the user's default address)
+	AddressId string `json:"address_id"`
+	// an address to deliver to without saving it to the address book
+	Address *Address `json:"address"`
+
+	// the coupon to apply to the order
+	CouponCode string `json:"coupon_code"`
+
+	// the currency to pay in (defaults to the currency the products are priced in)
+	Currency enum.Currency `json:"currency"`
 }
 
 type Item struct {
 	ProductId string `json:"product_id"`
+	// the variant of the product to order (required for products sold in variants)
+	VariantId string `json:"variant_id"`
 	Quantity  int    `json:"quantity"`
 }
 
+type Reorder struct {
+	// the previous order to order again
+	OrderId string `json:"order_id"`
+
+	// where to deliver the new order (defaults to where the previous order was delivered)
+	AddressId string   `json:"address_id"`
+	Address   *Address `json:"address"`
+
+	// the coupon to apply to the new order
+	CouponCode string `json:"coupon_code"`
+
+	// the currency to pay in (defaults to the currency of the previous order)
+	Currency enum.Currency `json:"currency"`
+}
+
 type ListOrder struct {
 	UserId string           `json:"userId"`
 	Status enum.OrderStatus `json:"status"`
@@ -33,15 +61,19 @@ type CancelOrder struct {
 type OrderFilter struct {
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
-	MinAmount *float64  `json:"min_amount"`
-	MaxAmount *float64  `json:"max_amount"`
+	// in the smallest unit of the currency (kobo, cents)
+	MinAmount *int64 `json:"min_amount"`
+	MaxAmount *int64 `json:"max_amount"`
 
-	OrderId   string `json:"order_id"`
-	Reference string `json:"reference"`
-	Paid      *bool  `json:"paid"`
-	Failed    *bool  `json:"failed"`
-	Cancelled *bool  `json:"cancelled"`
-	UserId    string `json:"user_id"`
+	OrderId   string        `json:"order_id"`
+	Reference string        `json:"reference"`
+	Paid      *bool         `json:"paid"`
+	Failed    *bool         `json:"failed"`
+	Cancelled *bool         `json:"cancelled"`
+	UserId    string        `json:"user_id"`
+	Currency  enum.Currency `json:"currency"`
+	// orders with the product on them
+	ProductId string `json:"product_id"`
 
 	// pagination
 	Page  *int `json:"page"`
@@ -49,3 +81,26 @@ type OrderFilter struct {
 	// when true, the response will contain the pagination metadata
 	Paginate bool `json:"paginate"`
 }
+
+// ExportOrders takes the same filters as listing orders (pagination is ignored as every matching order is exported)
+type ExportOrders struct {
+	OrderFilter
+	// CSV (default) or NDJSON
+	Format enum.ExportFormat `json:"format"`
+	// ORDER (default) for a row per order or LINE for a row per invoice line
+	Rows enum.ExportRow `json:"rows"`
+}
+
+type RefundOrder struct {
+	OrderId string `json:"order_id"`
+	// the amount to refund in the smallest unit of the order's currency (defaults to the refundable balance)
+	Amount *int64 `json:"amount"`
+	Reason string `json:"reason"`
+	// invoice items to return to stock
+	Restock []RestockItem `json:"restock"`
+}
+
+type RestockItem struct {
+	Key      string `json:"key"`
+	Quantity int    `json:"quantity"`
+}
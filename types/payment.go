@@ -0,0 +1,5 @@
+package types
+
+type InitializePayment struct {
+	OrderId string `json:"order_id"`
+}
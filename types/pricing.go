@@ -0,0 +1,47 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type CreatePricingRule struct {
+	Name  string           `json:"name"`
+	Kind  enum.PricingRule `json:"kind"`
+	Basis enum.RuleBasis   `json:"basis"`
+	// the percentage charged by a percentage rule
+	Rate float64 `json:"rate"`
+	// the amount charged by a fixed rule (in the smallest unit of the currency)
+	Amount int64 `json:"amount"`
+	// the currency of a fixed rule (defaults to NGN)
+	Currency enum.Currency `json:"currency"`
+	Country  string        `json:"country"`
+	State    string        `json:"state"`
+	// limits the rule to the products in a category and its subcategories
+	CategoryId string `json:"category_id"`
+	// defaults to true
+	Active *bool `json:"active"`
+}
+
+type UpdatePricingRule struct {
+	RuleId string  `json:"rule_id"`
+	Name   *string `json:"name"`
+	// a rule moved to another basis must be given the rate or amount it now charges
+	Basis    *enum.RuleBasis `json:"basis"`
+	Rate     *float64        `json:"rate"`
+	Amount   *int64          `json:"amount"`
+	Currency *enum.Currency  `json:"currency"`
+	Country  *string         `json:"country"`
+	State    *string         `json:"state"`
+	// an empty category applies the rule to every product again
+	CategoryId *string `json:"category_id"`
+	Active     *bool   `json:"active"`
+}
+
+type SetExchangeRate struct {
+	// one unit of the base currency costs "rate" units of the quote currency
+	Base  enum.Currency `json:"base"`
+	Quote enum.Currency `json:"quote"`
+	Rate  float64       `json:"rate"`
+}
+
+type DeleteExchangeRate struct {
+	RateId string `json:"rate_id"`
+}
@@ -14,31 +14,51 @@ type Delete struct {
 	Products []Product `json:"products"`
 }
 
+type Restore struct {
+	Products []Product `json:"products"`
+}
+
 type Product struct {
-	ProductId   string             `json:"product_id"`
-	Name        string             `json:"name"`
-	Price       float64            `json:"price"`
+	ProductId string `json:"product_id"`
+	Name      string `json:"name"`
+	// in the smallest unit of the currency (kobo, cents)
+	Price int64 `json:"price"`
+	// the currency the price is in (defaults to NGN)
+	Currency    enum.Currency      `json:"currency"`
 	Stock       int64              `json:"stock"`
 	ProductUrl  string             `json:"product_url"`
 	Status      enum.ProductStatus `json:"status"`
 	Description string             `json:"description"`
+	// the ids of the categories the product is listed in
+	CategoryIds []string `json:"category_ids"`
 }
 
 type UpdateProduct struct {
 	ProductId   string              `json:"product_id"`
 	Name        *string             `json:"name"`
-	Price       *float64            `json:"price"`
+	Price       *int64              `json:"price"`
+	Currency    *enum.Currency      `json:"currency"`
 	Stock       *int64              `json:"stock"`
 	ProductUrl  *string             `json:"product_url"`
 	Status      *enum.ProductStatus `json:"status"`
 	Description *string             `json:"description"`
+	// why the stock was changed (kept in the product's stock history)
+	StockReason string `json:"stock_reason"`
+	// replaces the categories the product is listed in (an empty list removes it from all of them)
+	CategoryIds *[]string `json:"category_ids"`
 }
 
 type ProductFilter struct {
-	ProductId string             `json:"product_id"`
+	ProductId string `json:"product_id"`
+	// in the smallest unit of the currency (kobo, cents), so a filter of 5000 NGN means 50.00 NGN
 	MinAmount *int64             `json:"min_amount"`
 	MaxAmount *int64             `json:"max_amount"`
 	Status    enum.ProductStatus `json:"status"`
+	Currency  enum.Currency      `json:"currency"`
+	// a category id or slug (products in its subcategories are included)
+	Category string `json:"category"`
+	// when true, only deleted products are listed (admins only)
+	Deleted bool `json:"deleted"`
 
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
@@ -53,3 +73,23 @@ type ProductFilter struct {
 	// when true, the response will contain the pagination metadata
 	Paginate bool `json:"paginate"`
 }
+
+// CatalogueFilter is the filter shoppers can apply to the public catalogue, which only ever lists published products
+type CatalogueFilter struct {
+	// in the smallest unit of the currency (kobo, cents), so a filter of 5000 NGN means 50.00 NGN
+	MinAmount *int64        `json:"min_amount"`
+	MaxAmount *int64        `json:"max_amount"`
+	Currency  enum.Currency `json:"currency"`
+	// a category id or slug (products in its subcategories are included)
+	Category string `json:"category"`
+
+	// searches on name, description
+	Search string `json:"search"`
+
+	// pagination
+	Page  *int `json:"page"`
+	Limit *int `json:"limit"`
+
+	// when true, the response will contain the pagination metadata
+	Paginate bool `json:"paginate"`
+}
@@ -0,0 +1,9 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type Receipt struct {
+	OrderId string `json:"order_id"`
+	// PDF (default) or HTML
+	Format enum.ReceiptFormat `json:"format"`
+}
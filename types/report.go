@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"github.com/funmi4194/ecommerce/enum"
+)
+
+// ReportFilter narrows reports down to orders placed between StartDate and EndDate (inclusive, either can be left out) like OrderFilter
+type ReportFilter struct {
+	StartDate time.Time     `json:"start_date"`
+	EndDate   time.Time     `json:"end_date"`
+	Currency  enum.Currency `json:"currency"`
+
+	// DAY (default), WEEK or MONTH
+	Period enum.ReportPeriod `json:"period"`
+
+	// the product to report product sales on (all products when left out)
+	ProductId string `json:"product_id"`
+
+	// how many products to rank (defaults to 10)
+	Limit *int `json:"limit"`
+}
@@ -0,0 +1,35 @@
+package types
+
+import "github.com/funmi4194/ecommerce/enum"
+
+type OpenReturn struct {
+	OrderId string `json:"order_id"`
+	Reason  string `json:"reason"`
+	// invoice items being sent back
+	Items []ReturnItem `json:"items"`
+}
+
+type ReturnItem struct {
+	Key      string `json:"key"`
+	Quantity int    `json:"quantity"`
+}
+
+type ReviewReturn struct {
+	ReturnId string `json:"return_id"`
+	Approve  bool   `json:"approve"`
+	Note     string `json:"note"`
+}
+
+type ReceiveReturn struct {
+	ReturnId string `json:"return_id"`
+	// when true the customer is refunded for the returned items
+	Refund bool `json:"refund"`
+	// the amount to refund in the smallest unit of the order's currency (defaults to the value of the returned items)
+	Amount *int64 `json:"amount"`
+}
+
+type ListReturns struct {
+	// admins may leave this out to see the returns of every order
+	OrderId string            `json:"order_id"`
+	Status  enum.ReturnStatus `json:"status"`
+}
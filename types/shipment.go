@@ -0,0 +1,31 @@
+package types
+
+type CreateShipment struct {
+	OrderId        string `json:"order_id"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+	// invoice items in the shipment (defaults to everything not yet shipped)
+	Items []ShipmentItem `json:"items"`
+}
+
+type ShipmentItem struct {
+	Key      string `json:"key"`
+	Quantity int    `json:"quantity"`
+}
+
+type DeliverShipment struct {
+	ShipmentId string `json:"shipment_id"`
+}
+
+type ListShipments struct {
+	OrderId string `json:"order_id"`
+}
+
+// ShipmentProgress summarises how much of an invoice item has left the warehouse and reached the customer
+type ShipmentProgress struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Ordered   int    `json:"ordered"`
+	Shipped   int    `json:"shipped"`
+	Delivered int    `json:"delivered"`
+}
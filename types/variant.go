@@ -0,0 +1,27 @@
+package types
+
+type CreateVariant struct {
+	ProductId string `json:"product_id"`
+	Sku       string `json:"sku"`
+	// the value of each option type of the product e.g {"size": "M", "colour": "Red"}
+	Options map[string]string `json:"options"`
+	// in the smallest unit of the product's currency (defaults to the price of the product)
+	Price    *int64 `json:"price"`
+	Stock    int64  `json:"stock"`
+	ImageUrl string `json:"image_url"`
+}
+
+type UpdateVariant struct {
+	VariantId string             `json:"variant_id"`
+	Sku       *string            `json:"sku"`
+	Options   *map[string]string `json:"options"`
+	Price     *int64             `json:"price"`
+	Stock     *int64             `json:"stock"`
+	ImageUrl  *string            `json:"image_url"`
+	// why the stock was changed (kept in the product's stock history)
+	StockReason string `json:"stock_reason"`
+}
+
+type DeleteVariant struct {
+	VariantId string `json:"variant_id"`
+}
@@ -2,8 +2,15 @@ package version
 
 import (
 	"github.com/funmi4194/ecommerce/middleware"
+	"github.com/funmi4194/ecommerce/route/cart"
+	"github.com/funmi4194/ecommerce/route/category"
+	"github.com/funmi4194/ecommerce/route/coupon"
+	"github.com/funmi4194/ecommerce/route/inventory"
 	"github.com/funmi4194/ecommerce/route/order"
+	"github.com/funmi4194/ecommerce/route/payment"
+	"github.com/funmi4194/ecommerce/route/pricing"
 	"github.com/funmi4194/ecommerce/route/product"
+	"github.com/funmi4194/ecommerce/route/report"
 	"github.com/funmi4194/ecommerce/route/user"
 	"github.com/opensaucerer/barf"
 )
@@ -12,15 +19,33 @@ func V1() {
 	unauthenticedFrame := barf.RetroFrame("/v1")
 	barf.Hippocampus(unauthenticedFrame).Hijack(middleware.OptionalAuth)
 
-	// access to some parts of the api is only allowed with a valid token
+	// access to some parts of the api is only allowed with a valid token and mutating requests there honour Idempotency-Key
 	authenticatedFrame := barf.RetroFrame("/v1")
-	barf.Hippocampus(authenticatedFrame).Hijack(middleware.Auth)
+	barf.Hippocampus(authenticatedFrame).Hijack(middleware.Auth, middleware.Idempotency)
 
 	user.RegisterAuthRoutes(unauthenticedFrame)
 	user.RegisterAdminRoutes(authenticatedFrame)
+	user.RegisterAddressRoutes(authenticatedFrame)
 
 	product.RegisterProductRoutes(authenticatedFrame)
 	product.RegisterStorageRoutes(authenticatedFrame)
+	product.RegisterVariantRoutes(authenticatedFrame)
+	category.RegisterCategoryRoutes(authenticatedFrame)
+	inventory.RegisterInventoryRoutes(authenticatedFrame)
+
+	// shoppers can browse published products without signing in
+	product.RegisterCatalogueRoutes(unauthenticedFrame)
+
+	// guests keep a cart by token and only need to sign in to check out
+	cart.RegisterCartRoutes(unauthenticedFrame)
+	cart.RegisterCheckoutRoutes(authenticatedFrame)
 
 	order.RegisterOrderRoutes(authenticatedFrame)
+	coupon.RegisterCouponRoutes(authenticatedFrame)
+	pricing.RegisterPricingRoutes(authenticatedFrame)
+	pricing.RegisterRateRoutes(authenticatedFrame)
+	report.RegisterReportRoutes(authenticatedFrame)
+
+	// payment providers call in without a token - their requests are verified by signature
+	payment.RegisterPaymentRoutes(unauthenticedFrame)
 }